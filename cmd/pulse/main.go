@@ -1,21 +1,40 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"os"
 
 	tea "github.com/charmbracelet/bubbletea"
+	"github.com/docker/docker/client"
 
+	"pulse/internal/commands"
 	"pulse/internal/config"
 	"pulse/internal/docker"
 	"pulse/internal/ui"
 )
 
 func main() {
-	cfg := config.ParseFlags()
+	cfg, err := config.ParseFlags()
+	if err != nil {
+		log.Fatalf("Unable to load configuration: %v", err)
+	}
 
-	cli, err := docker.NewClient()
+	// Like the Docker CLI, connect to the current context unless told otherwise
+	useCurrent := cfg.Context == "" && cfg.DockerHost == ""
+	if useCurrent {
+		cfg.Context = docker.CurrentContext()
+	}
+
+	cli, err := newClient(cfg)
+	warning := ""
+	if err != nil && useCurrent && cfg.Context != "" {
+		// A current context that can't be used doesn't keep pulse from starting
+		warning = fmt.Sprintf("Unable to use the current Docker context %s, using the default one: %v", cfg.Context, err)
+		cfg.Context = ""
+		cli, err = newClient(cfg)
+	}
 	if err != nil {
 		log.Fatalf("Unable to create Docker client: %v", err)
 	}
@@ -23,9 +42,23 @@ func main() {
 		_ = cli.Close()
 	}()
 
+	// Run headless subcommands without starting the TUI
+	if cfg.Command != "" {
+		if warning != "" {
+			fmt.Fprintln(os.Stderr, warning)
+		}
+		code := commands.Run(context.Background(), cli, cfg, os.Stdout, os.Stderr)
+		_ = cli.Close()
+		os.Exit(code)
+	}
+
 	// Use WithAltScreen to enable full-screen mode with proper window size events
+	model := ui.NewModel(cli, cfg)
+	if warning != "" {
+		model = model.WithStatus(warning)
+	}
 	p := tea.NewProgram(
-		ui.NewModel(cli, cfg.Debug),
+		model,
 		tea.WithAltScreen(),
 		tea.WithMouseCellMotion(), // Optional: add mouse support for future enhancements
 	)
@@ -35,3 +68,20 @@ func main() {
 		os.Exit(1)
 	}
 }
+
+// newClient connects to the configured context, or to the configured host otherwise
+func newClient(cfg config.Config) (*client.Client, error) {
+	if cfg.Context == "" {
+		return docker.NewClient(cfg.DockerHost)
+	}
+
+	contexts, err := docker.ListContexts(cfg.Hosts)
+	if err != nil {
+		return nil, err
+	}
+	dockerContext, err := docker.FindContext(contexts, cfg.Context)
+	if err != nil {
+		return nil, err
+	}
+	return docker.NewContextClient(dockerContext)
+}
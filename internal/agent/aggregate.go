@@ -0,0 +1,53 @@
+package agent
+
+import (
+	"context"
+	"sync"
+
+	"github.com/docker/docker/api/types"
+)
+
+// NodeContainer is a stack container along with the node and agent reporting it
+type NodeContainer struct {
+	types.Container
+	Node  string
+	Agent *Client
+}
+
+// CollectContainers queries every agent concurrently and merges the containers they
+// report. Agents that cannot be reached are skipped and their errors returned.
+func CollectContainers(ctx context.Context, clients []*Client) ([]NodeContainer, []error) {
+	var (
+		mu         sync.Mutex
+		wg         sync.WaitGroup
+		containers []NodeContainer
+		errs       []error
+		seen       = make(map[string]bool)
+	)
+
+	for _, c := range clients {
+		wg.Add(1)
+		go func(c *Client) {
+			defer wg.Done()
+
+			list, err := c.Containers(ctx)
+
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				errs = append(errs, err)
+				return
+			}
+			for _, container := range list.Containers {
+				if seen[container.ID] {
+					continue
+				}
+				seen[container.ID] = true
+				containers = append(containers, NodeContainer{Container: container, Node: list.Node, Agent: c})
+			}
+		}(c)
+	}
+
+	wg.Wait()
+	return containers, errs
+}
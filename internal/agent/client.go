@@ -0,0 +1,182 @@
+package agent
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"pulse/internal/docker"
+)
+
+// Client talks to the agent running on one swarm node
+type Client struct {
+	Endpoint string
+	baseURL  string
+	token    string
+	http     *http.Client
+}
+
+// NewClient creates a client for the agent at endpoint ("host:port" or a full URL).
+// With a TLS config the agent is reached over https; the token may then be empty
+// when the config carries a client certificate.
+func NewClient(endpoint, token string, tlsConfig *tls.Config) *Client {
+	baseURL := endpoint
+	if !strings.Contains(baseURL, "://") {
+		if tlsConfig != nil {
+			baseURL = "https://" + baseURL
+		} else {
+			baseURL = "http://" + baseURL
+		}
+	}
+
+	httpClient := &http.Client{Timeout: 10 * time.Second}
+	if tlsConfig != nil {
+		httpClient.Transport = &http.Transport{TLSClientConfig: tlsConfig}
+	}
+
+	return &Client{
+		Endpoint: endpoint,
+		baseURL:  strings.TrimSuffix(baseURL, "/"),
+		token:    token,
+		http:     httpClient,
+	}
+}
+
+// Close releases the idle connections to the agent. Clients without TLS share the
+// default transport, which is left alone.
+func (c *Client) Close() {
+	if c.http.Transport != nil {
+		c.http.CloseIdleConnections()
+	}
+}
+
+// Info returns the node the agent runs on
+func (c *Client) Info(ctx context.Context) (Info, error) {
+	var info Info
+	err := c.getJSON(ctx, "/v1/info", &info)
+	return info, err
+}
+
+// Containers returns the stack containers on the agent's node
+func (c *Client) Containers(ctx context.Context) (ContainerList, error) {
+	var list ContainerList
+	err := c.getJSON(ctx, "/v1/containers", &list)
+	return list, err
+}
+
+// Stats samples the resource usage of a container on the agent's node
+func (c *Client) Stats(ctx context.Context, containerID string) (docker.ResourceStats, error) {
+	var stats docker.ResourceStats
+	err := c.getJSON(ctx, "/v1/containers/"+url.PathEscape(containerID)+"/stats", &stats)
+	return stats, err
+}
+
+// Logs returns the last tail log lines of a container on the agent's node
+func (c *Client) Logs(ctx context.Context, containerID string, tail int) (string, error) {
+	path := "/v1/containers/" + url.PathEscape(containerID) + "/logs?tail=" + strconv.Itoa(tail)
+	resp, err := c.do(ctx, http.MethodGet, path, nil)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	logs, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", fmt.Errorf("error reading logs from agent %s: %v", c.Endpoint, err)
+	}
+	return string(logs), nil
+}
+
+// Exec runs a command in a container on the agent's node
+func (c *Client) Exec(ctx context.Context, containerID string, cmd []string) (docker.ExecResult, error) {
+	body, err := json.Marshal(ExecRequest{Cmd: cmd})
+	if err != nil {
+		return docker.ExecResult{}, err
+	}
+
+	resp, err := c.do(ctx, http.MethodPost, "/v1/containers/"+url.PathEscape(containerID)+"/exec", bytes.NewReader(body))
+	if err != nil {
+		return docker.ExecResult{}, err
+	}
+	defer resp.Body.Close()
+
+	var result docker.ExecResult
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return docker.ExecResult{}, fmt.Errorf("error decoding response from agent %s: %v", c.Endpoint, err)
+	}
+	return result, nil
+}
+
+// Top lists the processes of a container on the agent's node
+func (c *Client) Top(ctx context.Context, containerID string) ([]docker.Process, error) {
+	var processes []docker.Process
+	err := c.getJSON(ctx, "/v1/containers/"+url.PathEscape(containerID)+"/top", &processes)
+	return processes, err
+}
+
+// Signal sends a signal to a container on the agent's node
+func (c *Client) Signal(ctx context.Context, containerID, signal string) error {
+	body, err := json.Marshal(SignalRequest{Signal: signal})
+	if err != nil {
+		return err
+	}
+
+	resp, err := c.do(ctx, http.MethodPost, "/v1/containers/"+url.PathEscape(containerID)+"/signal", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// getJSON performs a GET request and decodes the JSON response into v
+func (c *Client) getJSON(ctx context.Context, path string, v any) error {
+	resp, err := c.do(ctx, http.MethodGet, path, nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return fmt.Errorf("error decoding response from agent %s: %v", c.Endpoint, err)
+	}
+	return nil
+}
+
+// do sends an authenticated request and turns non-2xx responses into errors
+func (c *Client) do(ctx context.Context, method, path string, body io.Reader) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, body)
+	if err != nil {
+		return nil, err
+	}
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("error contacting agent %s: %v", c.Endpoint, err)
+	}
+
+	if resp.StatusCode/100 != 2 {
+		defer resp.Body.Close()
+		var apiErr errorResponse
+		if err := json.NewDecoder(resp.Body).Decode(&apiErr); err != nil || apiErr.Error == "" {
+			apiErr.Error = resp.Status
+		}
+		return nil, fmt.Errorf("agent %s: %s", c.Endpoint, apiErr.Error)
+	}
+
+	return resp, nil
+}
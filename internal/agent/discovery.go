@@ -0,0 +1,70 @@
+package agent
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"slices"
+	"strconv"
+	"sync"
+)
+
+// Discover returns the endpoints of all known agents: the statically configured
+// addresses plus one per task of the agent's swarm service. Swarm publishes the
+// task IPs of a service under the DNS name "tasks.<service>" on its overlay network.
+func Discover(ctx context.Context, addrs []string, service string, port int) []string {
+	endpoints := slices.Clone(addrs)
+
+	if service != "" {
+		ips, err := net.DefaultResolver.LookupHost(ctx, "tasks."+service)
+		if err == nil {
+			for _, ip := range ips {
+				endpoints = append(endpoints, net.JoinHostPort(ip, strconv.Itoa(port)))
+			}
+		}
+	}
+
+	slices.Sort(endpoints)
+	return slices.Compact(endpoints)
+}
+
+// Pool keeps one client per agent endpoint, so repeated discoveries reuse the clients
+// and their connections instead of opening new ones every time
+type Pool struct {
+	token     string
+	tlsConfig *tls.Config
+
+	mu      sync.Mutex
+	clients map[string]*Client
+}
+
+// NewPool creates a pool of clients sharing a token and TLS config
+func NewPool(token string, tlsConfig *tls.Config) *Pool {
+	return &Pool{token: token, tlsConfig: tlsConfig, clients: make(map[string]*Client)}
+}
+
+// Clients returns the clients of endpoints, creating the missing ones. Clients of
+// endpoints that are gone are closed.
+func (p *Pool) Clients(endpoints []string) []*Client {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	clients := make([]*Client, 0, len(endpoints))
+	current := make(map[string]*Client, len(endpoints))
+	for _, endpoint := range endpoints {
+		c, ok := p.clients[endpoint]
+		if !ok {
+			c = NewClient(endpoint, p.token, p.tlsConfig)
+		}
+		current[endpoint] = c
+		clients = append(clients, c)
+	}
+
+	for endpoint, c := range p.clients {
+		if _, ok := current[endpoint]; !ok {
+			c.Close()
+		}
+	}
+	p.clients = current
+	return clients
+}
@@ -0,0 +1,221 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/client"
+
+	"pulse/internal/auth"
+	"pulse/internal/docker"
+)
+
+// APIVersion is the version of the agent API, used as the path prefix
+const APIVersion = "v1"
+
+// stackLabel marks containers that belong to a swarm stack
+const stackLabel = "com.docker.stack.namespace"
+
+// Info describes the node an agent runs on
+type Info struct {
+	Node       string `json:"node"`
+	APIVersion string `json:"apiVersion"`
+}
+
+// ContainerList is the set of stack containers running on an agent's node
+type ContainerList struct {
+	Node       string            `json:"node"`
+	Containers []types.Container `json:"containers"`
+}
+
+// ExecRequest is the body of an exec call
+type ExecRequest struct {
+	Cmd []string `json:"cmd"`
+}
+
+// SignalRequest is the body of a signal call
+type SignalRequest struct {
+	Signal string `json:"signal"`
+}
+
+// errorResponse is the body returned with every non-2xx status
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+// Server exposes the stack containers of the local node to Pulse instances on other nodes
+type Server struct {
+	cli     *client.Client
+	node    string
+	auth    *auth.Authenticator
+	logTail int
+}
+
+// NewServer creates an agent server; callers authenticate with one of tokens or,
+// when the server is behind mutual TLS, with a client certificate
+func NewServer(cli *client.Client, node string, tokens []auth.Token, logTail int) *Server {
+	return &Server{cli: cli, node: node, auth: auth.New(tokens, writeError), logTail: logTail}
+}
+
+// Handler returns the HTTP handler serving the agent API
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /v1/info", s.auth.Require(auth.ScopeRead, s.handleInfo))
+	mux.HandleFunc("GET /v1/containers", s.auth.Require(auth.ScopeRead, s.handleContainers))
+	mux.HandleFunc("GET /v1/containers/{id}/stats", s.auth.Require(auth.ScopeRead, s.handleStats))
+	mux.HandleFunc("GET /v1/containers/{id}/logs", s.auth.Require(auth.ScopeRead, s.handleLogs))
+	mux.HandleFunc("GET /v1/containers/{id}/top", s.auth.Require(auth.ScopeRead, s.handleTop))
+	mux.HandleFunc("POST /v1/containers/{id}/exec", s.auth.Require(auth.ScopeOperate, s.handleExec))
+	mux.HandleFunc("POST /v1/containers/{id}/signal", s.auth.Require(auth.ScopeOperate, s.handleSignal))
+	return s.auth.Middleware(mux)
+}
+
+func (s *Server) handleInfo(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, Info{Node: s.node, APIVersion: APIVersion})
+}
+
+func (s *Server) handleContainers(w http.ResponseWriter, r *http.Request) {
+	var containers []types.Container
+	var err error
+	if stack := r.URL.Query().Get("stack"); stack != "" {
+		containers, err = docker.ListContainers(r.Context(), s.cli, stack)
+	} else {
+		containers, err = docker.ListAllStackContainers(r.Context(), s.cli, false)
+	}
+	if err != nil {
+		writeError(w, http.StatusBadGateway, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, ContainerList{Node: s.node, Containers: containers})
+}
+
+func (s *Server) handleStats(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	if !s.checkStackContainer(w, r.Context(), id) {
+		return
+	}
+
+	stats, err := docker.GetResourceStats(r.Context(), s.cli, id)
+	if err != nil {
+		writeError(w, http.StatusBadGateway, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, stats)
+}
+
+func (s *Server) handleLogs(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	if !s.checkStackContainer(w, r.Context(), id) {
+		return
+	}
+
+	tail := s.logTail
+	if value := r.URL.Query().Get("tail"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 0 {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("invalid tail %q", value))
+			return
+		}
+		tail = parsed
+	}
+
+	logs, err := docker.ViewContainerLogs(r.Context(), s.cli, id, tail)
+	if err != nil {
+		writeError(w, http.StatusBadGateway, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	_, _ = w.Write([]byte(logs))
+}
+
+func (s *Server) handleExec(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	if !s.checkStackContainer(w, r.Context(), id) {
+		return
+	}
+
+	var req ExecRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || len(req.Cmd) == 0 {
+		writeError(w, http.StatusBadRequest, errors.New(`body must be {"cmd": ["command", "args"...]}`))
+		return
+	}
+
+	result, err := docker.ExecInContainer(r.Context(), s.cli, id, req.Cmd)
+	if err != nil {
+		writeError(w, http.StatusBadGateway, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, result)
+}
+
+func (s *Server) handleTop(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	if !s.checkStackContainer(w, r.Context(), id) {
+		return
+	}
+
+	processes, err := docker.ListProcesses(r.Context(), s.cli, id)
+	if err != nil {
+		writeError(w, http.StatusBadGateway, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, processes)
+}
+
+func (s *Server) handleSignal(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	if !s.checkStackContainer(w, r.Context(), id) {
+		return
+	}
+
+	var req SignalRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Signal == "" {
+		writeError(w, http.StatusBadRequest, errors.New(`body must be {"signal": "SIGHUP"}`))
+		return
+	}
+
+	if err := docker.SignalContainer(r.Context(), s.cli, id, req.Signal); err != nil {
+		writeError(w, http.StatusBadGateway, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// checkStackContainer only lets the agent act on containers that belong to a stack
+func (s *Server) checkStackContainer(w http.ResponseWriter, ctx context.Context, id string) bool {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	details, err := s.cli.ContainerInspect(ctx, id)
+	if err != nil {
+		if client.IsErrNotFound(err) {
+			writeError(w, http.StatusNotFound, fmt.Errorf("container %s not found", id))
+		} else {
+			writeError(w, http.StatusBadGateway, err)
+		}
+		return false
+	}
+	if _, ok := details.Config.Labels[stackLabel]; !ok {
+		writeError(w, http.StatusForbidden, fmt.Errorf("container %s is not part of a stack", id))
+		return false
+	}
+	return true
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, errorResponse{Error: err.Error()})
+}
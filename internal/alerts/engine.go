@@ -0,0 +1,199 @@
+package alerts
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/docker/docker/client"
+)
+
+// State is the lifecycle stage of an alert
+type State string
+
+// An alert fires once its condition has held for the rule's For duration, and
+// resolves when the condition clears
+const (
+	StateFiring   State = "firing"
+	StateResolved State = "resolved"
+)
+
+// Alert is a rule broken by one subject: a service or a container
+type Alert struct {
+	Rule       string     `json:"rule"`
+	Condition  Condition  `json:"condition"`
+	State      State      `json:"state"`
+	Stack      string     `json:"stack"`
+	Service    string     `json:"service"`
+	Subject    string     `json:"subject"`
+	Message    string     `json:"message"`
+	Context    []string   `json:"context,omitempty"`
+	Since      time.Time  `json:"since"`
+	ResolvedAt *time.Time `json:"resolvedAt,omitempty"`
+}
+
+// Engine evaluates rules against snapshots and remembers which alerts are pending
+// or firing between evaluations
+type Engine struct {
+	rules     []Rule
+	notifiers []Notifier
+
+	mu      sync.Mutex
+	pending map[string]time.Time
+	firing  map[string]Alert
+}
+
+// NewEngine creates an engine sending alert transitions to notifiers
+func NewEngine(rules []Rule, notifiers []Notifier) *Engine {
+	return &Engine{
+		rules:     rules,
+		notifiers: notifiers,
+		pending:   make(map[string]time.Time),
+		firing:    make(map[string]Alert),
+	}
+}
+
+// Check collects a snapshot, evaluates it and notifies about every transition. It
+// returns the transitions; a collection failure is returned with whatever could be
+// evaluated from the partial snapshot, leaving the alerts of the missing parts alone.
+func (e *Engine) Check(ctx context.Context, cli *client.Client) ([]Alert, error) {
+	snapshot, collectErr := Collect(ctx, cli, e.rules)
+	transitions := e.Evaluate(snapshot)
+	notifyErr := e.Notify(ctx, transitions)
+	return transitions, errors.Join(collectErr, notifyErr)
+}
+
+// Evaluate applies the rules to a snapshot and returns the alerts that started
+// firing or resolved since the previous evaluation. Alerts whose data is missing
+// from the snapshot stay pending or firing.
+func (e *Engine) Evaluate(s Snapshot) []Alert {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	var transitions []Alert
+	current := make(map[string]bool)
+
+	for _, rule := range e.rules {
+		for _, v := range rule.check(s) {
+			key := rule.Name + "\x00" + v.subject
+			current[key] = true
+
+			if alert, ok := e.firing[key]; ok {
+				// Keep the message current, e.g. the latest CPU reading
+				alert.Message = v.message
+				e.firing[key] = alert
+				continue
+			}
+
+			since, ok := e.pending[key]
+			if !ok {
+				since = s.Time
+				e.pending[key] = since
+			}
+			if s.Time.Sub(since) < rule.For {
+				continue
+			}
+
+			alert := Alert{
+				Rule:      rule.Name,
+				Condition: rule.Condition,
+				State:     StateFiring,
+				Stack:     v.stack,
+				Service:   v.service,
+				Subject:   v.subject,
+				Message:   v.message,
+				Since:     since,
+			}
+			delete(e.pending, key)
+			e.firing[key] = alert
+			transitions = append(transitions, alert)
+		}
+	}
+
+	for key := range e.pending {
+		if !current[key] && !e.unknown(s, key) {
+			delete(e.pending, key)
+		}
+	}
+	for key, alert := range e.firing {
+		if current[key] || e.unknown(s, key) {
+			continue
+		}
+		resolved := s.Time
+		alert.State = StateResolved
+		alert.ResolvedAt = &resolved
+		delete(e.firing, key)
+		transitions = append(transitions, alert)
+	}
+
+	sortAlerts(transitions)
+	return transitions
+}
+
+// unknown reports whether the snapshot lacks the data to tell if the alert of key
+// still holds
+func (e *Engine) unknown(s Snapshot, key string) bool {
+	name, subject, _ := strings.Cut(key, "\x00")
+	for _, rule := range e.rules {
+		if rule.Name == name {
+			return rule.unknown(s, subject)
+		}
+	}
+	return false
+}
+
+// Reset forgets pending and firing alerts without notifying, e.g. after switching
+// to another cluster
+func (e *Engine) Reset() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.pending = make(map[string]time.Time)
+	e.firing = make(map[string]Alert)
+}
+
+// Active returns the alerts currently firing, oldest first
+func (e *Engine) Active() []Alert {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	active := make([]Alert, 0, len(e.firing))
+	for _, alert := range e.firing {
+		active = append(active, alert)
+	}
+	sortAlerts(active)
+	return active
+}
+
+// Notify sends each alert to the engine's notifiers
+func (e *Engine) Notify(ctx context.Context, alerts []Alert) error {
+	return Notify(ctx, e.notifiers, alerts)
+}
+
+// Notify sends each alert to every notifier
+func Notify(ctx context.Context, notifiers []Notifier, alerts []Alert) error {
+	var errs []error
+	for _, alert := range alerts {
+		for _, n := range notifiers {
+			if err := n.Notify(ctx, alert); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func sortAlerts(alerts []Alert) {
+	sort.Slice(alerts, func(i, j int) bool {
+		if !alerts[i].Since.Equal(alerts[j].Since) {
+			return alerts[i].Since.Before(alerts[j].Since)
+		}
+		if alerts[i].Rule != alerts[j].Rule {
+			return alerts[i].Rule < alerts[j].Rule
+		}
+		return alerts[i].Subject < alerts[j].Subject
+	})
+}
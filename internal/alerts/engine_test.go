@@ -0,0 +1,384 @@
+package alerts
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/docker/docker/api/types"
+
+	"pulse/internal/config"
+	"pulse/internal/docker"
+)
+
+var testStart = time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+// stackContainer is a running container of a stack service for tests
+func stackContainer(id, stack, service, status string) types.Container {
+	return types.Container{
+		ID:     id,
+		Names:  []string{"/" + service + ".1." + id},
+		State:  "running",
+		Status: status,
+		Labels: map[string]string{
+			"com.docker.stack.namespace":    stack,
+			"com.docker.swarm.service.name": service,
+		},
+	}
+}
+
+func TestNewRules(t *testing.T) {
+	tests := []struct {
+		name       string
+		configured []config.AlertRule
+		wantErr    string
+		want       []Rule
+	}{
+		{
+			name:       "unnamed rule is named after its condition",
+			configured: []config.AlertRule{{Condition: "unhealthy"}},
+			want:       []Rule{{Name: "unhealthy-0", Condition: Unhealthy}},
+		},
+		{
+			name:       "restart loop defaults",
+			configured: []config.AlertRule{{Name: "loop", Condition: "restart_loop"}},
+			want:       []Rule{{Name: "loop", Condition: RestartLoop, Threshold: DefaultRestartThreshold, Window: DefaultWindow}},
+		},
+		{
+			name:       "oom window default",
+			configured: []config.AlertRule{{Name: "oom", Condition: "oom_killed", Window: time.Minute}, {Name: "oom2", Condition: "oom_killed"}},
+			want: []Rule{
+				{Name: "oom", Condition: OOMKilled, Window: time.Minute},
+				{Name: "oom2", Condition: OOMKilled, Window: DefaultWindow},
+			},
+		},
+		{
+			name:       "duplicate names",
+			configured: []config.AlertRule{{Name: "web", Condition: "unhealthy"}, {Name: "web", Condition: "replicas_below_desired"}},
+			wantErr:    "alert rule web: another rule has the same name",
+		},
+		{
+			name:       "default name taken by a named rule",
+			configured: []config.AlertRule{{Name: "unhealthy-1", Condition: "oom_killed"}, {Condition: "unhealthy"}},
+			wantErr:    "alert rule unhealthy-1: another rule has the same name",
+		},
+		{
+			name:       "cpu needs a threshold",
+			configured: []config.AlertRule{{Name: "cpu", Condition: "cpu_above"}},
+			wantErr:    "alert rule cpu: cpu_above needs a threshold in percent",
+		},
+		{
+			name:       "unknown condition",
+			configured: []config.AlertRule{{Name: "disk", Condition: "disk_full"}},
+			wantErr:    `alert rule disk: unknown condition "disk_full"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rules, err := NewRules(tt.configured)
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Fatalf("NewRules() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("NewRules() error = %v", err)
+			}
+			if len(rules) != len(tt.want) {
+				t.Fatalf("NewRules() = %+v, want %+v", rules, tt.want)
+			}
+			for i := range rules {
+				if rules[i] != tt.want[i] {
+					t.Errorf("rule %d = %+v, want %+v", i, rules[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestRuleMatches(t *testing.T) {
+	tests := []struct {
+		name           string
+		rule           Rule
+		stack, service string
+		want           bool
+	}{
+		{name: "no filters", rule: Rule{}, stack: "shop", service: "shop_web", want: true},
+		{name: "other stack", rule: Rule{Stack: "blog"}, stack: "shop", service: "shop_web", want: false},
+		{name: "full service name", rule: Rule{Service: "shop_web"}, stack: "shop", service: "shop_web", want: true},
+		{name: "service without stack prefix", rule: Rule{Service: "web"}, stack: "shop", service: "shop_web", want: true},
+		{name: "other service", rule: Rule{Service: "db"}, stack: "shop", service: "shop_web", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.rule.matches(tt.stack, tt.service); got != tt.want {
+				t.Errorf("matches(%q, %q) = %v, want %v", tt.stack, tt.service, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRuleCheck(t *testing.T) {
+	tests := []struct {
+		name     string
+		rule     Rule
+		snapshot Snapshot
+		want     []string // subject: message
+	}{
+		{
+			name: "replicas below desired",
+			rule: Rule{Condition: ReplicasBelowDesired},
+			snapshot: Snapshot{Services: []docker.ServiceStatus{
+				{Stack: "shop", Service: "shop_web", Desired: 3, Running: 1},
+				{Stack: "shop", Service: "shop_db", Desired: 1, Running: 1},
+			}},
+			want: []string{"shop_web: running 1 of 3 replicas"},
+		},
+		{
+			name: "restart loop counts failures within the window",
+			rule: Rule{Condition: RestartLoop, Threshold: 2, Window: 10 * time.Minute},
+			snapshot: Snapshot{Time: testStart, Failures: []docker.TaskFailure{
+				{Stack: "shop", Service: "shop_web", Time: testStart.Add(-20 * time.Minute), Message: "too old"},
+				{Stack: "shop", Service: "shop_web", Time: testStart.Add(-5 * time.Minute), Message: "exit 1"},
+				{Stack: "shop", Service: "shop_web", Time: testStart.Add(-time.Minute), Message: "exit 2"},
+				{Stack: "shop", Service: "shop_db", Time: testStart.Add(-time.Minute), Message: "exit 1"},
+			}},
+			want: []string{"shop_web: 2 tasks failed in 10m0s, last: exit 2"},
+		},
+		{
+			name: "unhealthy containers",
+			rule: Rule{Condition: Unhealthy},
+			snapshot: Snapshot{Containers: []types.Container{
+				stackContainer("a1", "shop", "shop_web", "Up 5 minutes (unhealthy)"),
+				stackContainer("b2", "shop", "shop_db", "Up 5 minutes (healthy)"),
+			}},
+			want: []string{"shop_web.1.a1: healthcheck failing"},
+		},
+		{
+			name: "cpu above threshold",
+			rule: Rule{Condition: CPUAbove, Threshold: 80},
+			snapshot: Snapshot{
+				Containers: []types.Container{
+					stackContainer("a1", "shop", "shop_web", "Up"),
+					stackContainer("b2", "shop", "shop_db", "Up"),
+					stackContainer("c3", "shop", "shop_cache", "Up"),
+				},
+				Stats: map[string]docker.ResourceStats{"a1": {CPUPercent: 95.5}, "b2": {CPUPercent: 10}},
+			},
+			want: []string{"shop_web.1.a1: CPU at 95.5% (threshold 80%)"},
+		},
+		{
+			name: "memory above threshold",
+			rule: Rule{Condition: MemoryAbove, Threshold: 50},
+			snapshot: Snapshot{
+				Containers: []types.Container{stackContainer("a1", "shop", "shop_web", "Up")},
+				Stats:      map[string]docker.ResourceStats{"a1": {MemoryUsage: 75, MemoryLimit: 100}},
+			},
+			want: []string{"shop_web.1.a1: memory at 75.0% (threshold 50%)"},
+		},
+		{
+			name: "oom kills within the window",
+			rule: Rule{Condition: OOMKilled, Window: 10 * time.Minute},
+			snapshot: Snapshot{Time: testStart, Exits: []docker.ContainerExit{
+				{Name: "shop_web.1.a1", Stack: "shop", Service: "shop_web", OOMKilled: true, FinishedAt: testStart.Add(-time.Minute)},
+				{Name: "shop_web.1.b2", Stack: "shop", Service: "shop_web", OOMKilled: true, FinishedAt: testStart.Add(-time.Hour)},
+				{Name: "shop_db.1.c3", Stack: "shop", Service: "shop_db", FinishedAt: testStart.Add(-time.Minute)},
+			}},
+			want: []string{"shop_web.1.a1: killed for running out of memory at " + testStart.Add(-time.Minute).Local().Format("15:04:05")},
+		},
+		{
+			name: "filtered by service",
+			rule: Rule{Condition: ReplicasBelowDesired, Service: "db"},
+			snapshot: Snapshot{Services: []docker.ServiceStatus{
+				{Stack: "shop", Service: "shop_web", Desired: 3, Running: 1},
+			}},
+			want: nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, v := range tt.rule.check(tt.snapshot) {
+				got = append(got, v.subject+": "+v.message)
+			}
+			if strings.Join(got, "\n") != strings.Join(tt.want, "\n") {
+				t.Errorf("check() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEngineEvaluate(t *testing.T) {
+	below := func(running uint64) []docker.ServiceStatus {
+		return []docker.ServiceStatus{{Stack: "shop", Service: "shop_web", Desired: 2, Running: running}}
+	}
+
+	// Each step is evaluated at testStart plus offset and returns its transitions
+	type step struct {
+		offset   time.Duration
+		services []docker.ServiceStatus
+		want     []string // state subject
+		active   int
+	}
+	tests := []struct {
+		name  string
+		rule  Rule
+		steps []step
+	}{
+		{
+			name: "fires at once without for",
+			rule: Rule{Name: "replicas", Condition: ReplicasBelowDesired},
+			steps: []step{
+				{offset: 0, services: below(1), want: []string{"firing shop_web"}, active: 1},
+				{offset: time.Minute, services: below(1), active: 1},
+				{offset: 2 * time.Minute, services: below(2), want: []string{"resolved shop_web"}},
+			},
+		},
+		{
+			name: "waits for the for duration",
+			rule: Rule{Name: "replicas", Condition: ReplicasBelowDesired, For: 2 * time.Minute},
+			steps: []step{
+				{offset: 0, services: below(1)},
+				{offset: time.Minute, services: below(1)},
+				{offset: 2 * time.Minute, services: below(1), want: []string{"firing shop_web"}, active: 1},
+			},
+		},
+		{
+			name: "pending alert is forgotten when the condition clears",
+			rule: Rule{Name: "replicas", Condition: ReplicasBelowDesired, For: 2 * time.Minute},
+			steps: []step{
+				{offset: 0, services: below(1)},
+				{offset: time.Minute, services: below(2)},
+				{offset: 2 * time.Minute, services: below(1)},
+				{offset: 3 * time.Minute, services: below(1)},
+				{offset: 4 * time.Minute, services: below(1), want: []string{"firing shop_web"}, active: 1},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			engine := NewEngine([]Rule{tt.rule}, nil)
+			for i, step := range tt.steps {
+				transitions := engine.Evaluate(Snapshot{Time: testStart.Add(step.offset), Services: step.services})
+				var got []string
+				for _, alert := range transitions {
+					got = append(got, string(alert.State)+" "+alert.Subject)
+				}
+				if strings.Join(got, "\n") != strings.Join(step.want, "\n") {
+					t.Errorf("step %d: Evaluate() = %q, want %q", i, got, step.want)
+				}
+				if active := len(engine.Active()); active != step.active {
+					t.Errorf("step %d: %d active alerts, want %d", i, active, step.active)
+				}
+			}
+		})
+	}
+}
+
+func TestEngineEvaluateKeepsSinceAndMessage(t *testing.T) {
+	engine := NewEngine([]Rule{{Name: "replicas", Condition: ReplicasBelowDesired}}, nil)
+	snapshot := func(offset time.Duration, running uint64) Snapshot {
+		return Snapshot{
+			Time:     testStart.Add(offset),
+			Services: []docker.ServiceStatus{{Stack: "shop", Service: "shop_web", Desired: 3, Running: running}},
+		}
+	}
+
+	engine.Evaluate(snapshot(0, 1))
+	engine.Evaluate(snapshot(time.Minute, 2))
+
+	active := engine.Active()
+	if len(active) != 1 {
+		t.Fatalf("Active() = %+v, want one alert", active)
+	}
+	if !active[0].Since.Equal(testStart) {
+		t.Errorf("Since = %v, want %v", active[0].Since, testStart)
+	}
+	if active[0].Message != "running 2 of 3 replicas" {
+		t.Errorf("Message = %q, want the latest reading", active[0].Message)
+	}
+
+	transitions := engine.Evaluate(snapshot(2*time.Minute, 3))
+	if len(transitions) != 1 || transitions[0].ResolvedAt == nil || !transitions[0].ResolvedAt.Equal(testStart.Add(2*time.Minute)) {
+		t.Errorf("Evaluate() = %+v, want the alert resolved at the snapshot time", transitions)
+	}
+}
+
+func TestEngineEvaluateMissingData(t *testing.T) {
+	web := stackContainer("a1", "shop", "shop_web", "Up")
+	services := []docker.ServiceStatus{{Stack: "shop", Service: "shop_web", Desired: 2, Running: 1}}
+	busy := map[string]docker.ResourceStats{"a1": {CPUPercent: 95}}
+
+	tests := []struct {
+		name  string
+		rule  Rule
+		first Snapshot // breaks the rule
+		gap   Snapshot // lacks the data of the rule
+	}{
+		{
+			name:  "collect error does not resolve",
+			rule:  Rule{Name: "replicas", Condition: ReplicasBelowDesired},
+			first: Snapshot{Services: services},
+			gap:   Snapshot{ServicesFailed: true},
+		},
+		{
+			name:  "failed container listing",
+			rule:  Rule{Name: "cpu", Condition: CPUAbove, Threshold: 80},
+			first: Snapshot{Containers: []types.Container{web}, Stats: busy},
+			gap:   Snapshot{ContainersFailed: true},
+		},
+		{
+			name:  "failed stats of the container",
+			rule:  Rule{Name: "cpu", Condition: CPUAbove, Threshold: 80},
+			first: Snapshot{Containers: []types.Container{web}, Stats: busy},
+			gap:   Snapshot{Containers: []types.Container{web}, StatsFailed: map[string]bool{"shop_web.1.a1": true}},
+		},
+		{
+			name:  "failed exits",
+			rule:  Rule{Name: "oom", Condition: OOMKilled, Window: time.Hour},
+			first: Snapshot{Exits: []docker.ContainerExit{{Name: "shop_web.1.a1", OOMKilled: true, FinishedAt: testStart}}},
+			gap:   Snapshot{ExitsFailed: true},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			engine := NewEngine([]Rule{tt.rule}, nil)
+			tt.first.Time = testStart
+			if transitions := engine.Evaluate(tt.first); len(transitions) != 1 || transitions[0].State != StateFiring {
+				t.Fatalf("first Evaluate() = %+v, want the alert firing", transitions)
+			}
+
+			tt.gap.Time = testStart.Add(time.Minute)
+			if transitions := engine.Evaluate(tt.gap); len(transitions) != 0 {
+				t.Errorf("Evaluate() without the data = %+v, want no transitions", transitions)
+			}
+			if active := engine.Active(); len(active) != 1 {
+				t.Errorf("Active() = %+v, want the alert still firing", active)
+			}
+
+			// Once the data is back and the condition cleared, the alert resolves
+			transitions := engine.Evaluate(Snapshot{Time: testStart.Add(2 * time.Hour)})
+			if len(transitions) != 1 || transitions[0].State != StateResolved {
+				t.Errorf("Evaluate() after recovery = %+v, want the alert resolved", transitions)
+			}
+		})
+	}
+}
+
+func TestEngineEvaluateKeepsPendingWithoutData(t *testing.T) {
+	engine := NewEngine([]Rule{{Name: "replicas", Condition: ReplicasBelowDesired, For: 2 * time.Minute}}, nil)
+	services := []docker.ServiceStatus{{Stack: "shop", Service: "shop_web", Desired: 2, Running: 1}}
+
+	engine.Evaluate(Snapshot{Time: testStart, Services: services})
+	engine.Evaluate(Snapshot{Time: testStart.Add(time.Minute), ServicesFailed: true})
+	transitions := engine.Evaluate(Snapshot{Time: testStart.Add(2 * time.Minute), Services: services})
+	if len(transitions) != 1 || !transitions[0].Since.Equal(testStart) {
+		t.Errorf("Evaluate() = %+v, want the alert firing since the first violation", transitions)
+	}
+}
@@ -0,0 +1,314 @@
+package alerts
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/docker/docker/api/types/swarm"
+	"github.com/docker/docker/client"
+
+	"pulse/internal/config"
+	"pulse/internal/docker"
+)
+
+// LogMatch is the condition of alerts raised by log triggers
+const LogMatch Condition = "log_match"
+
+// Defaults for log triggers that leave Context or Cooldown unset
+const (
+	DefaultLogContext  = 3
+	DefaultLogCooldown = time.Minute
+)
+
+// logRescanInterval is how often the watcher looks for newly deployed services
+const logRescanInterval = 30 * time.Second
+
+// logContextWait bounds how long a hit waits for the lines that follow it
+const logContextWait = 5 * time.Second
+
+// LogTrigger is a validated log trigger
+type LogTrigger struct {
+	Name     string
+	Pattern  *regexp.Regexp
+	Stack    string
+	Service  string
+	Context  int
+	Cooldown time.Duration
+}
+
+// LogHit is a log line that matched a trigger, with the lines around it
+type LogHit struct {
+	Trigger string
+	Stack   string
+	Service string
+	Line    string
+	Before  []string
+	After   []string
+	Time    time.Time
+	// Throttled hits came within the trigger's cooldown and were not notified
+	Throttled bool
+}
+
+// Alert converts the hit to an alert for the notifiers
+func (h LogHit) Alert() Alert {
+	context := slices.Concat(h.Before, []string{h.Line}, h.After)
+	return Alert{
+		Rule:      h.Trigger,
+		Condition: LogMatch,
+		State:     StateFiring,
+		Stack:     h.Stack,
+		Service:   h.Service,
+		Subject:   h.Service,
+		Message:   h.Line,
+		Context:   context,
+		Since:     h.Time,
+	}
+}
+
+// NewLogTriggers validates the configured triggers and fills in defaults. Hits and
+// their cooldowns are tracked by trigger name, so names must be unique.
+func NewLogTriggers(configured []config.LogTrigger) ([]LogTrigger, error) {
+	triggers := make([]LogTrigger, 0, len(configured))
+	names := make(map[string]bool)
+	for i, c := range configured {
+		name := c.Name
+		if name == "" {
+			name = fmt.Sprintf("log-%d", i)
+		}
+		if names[name] {
+			return nil, fmt.Errorf("log trigger %s: another trigger has the same name", name)
+		}
+		names[name] = true
+
+		pattern, err := regexp.Compile(c.Pattern)
+		if err != nil || c.Pattern == "" {
+			return nil, fmt.Errorf("log trigger %s: invalid pattern %q: %v", name, c.Pattern, err)
+		}
+
+		trigger := LogTrigger{
+			Name:     name,
+			Pattern:  pattern,
+			Stack:    c.Stack,
+			Service:  c.Service,
+			Context:  DefaultLogContext,
+			Cooldown: c.Cooldown,
+		}
+		if c.Context != nil {
+			trigger.Context = max(*c.Context, 0)
+		}
+		if trigger.Cooldown == 0 {
+			trigger.Cooldown = DefaultLogCooldown
+		}
+		triggers = append(triggers, trigger)
+	}
+	return triggers, nil
+}
+
+// matches reports whether a trigger applies to a service of a stack. Services may be
+// named with or without their stack prefix.
+func (t LogTrigger) matches(stack, service string) bool {
+	if t.Stack != "" && t.Stack != stack {
+		return false
+	}
+	if t.Service != "" && t.Service != service && stack+"_"+t.Service != service {
+		return false
+	}
+	return true
+}
+
+// LogWatcher follows the logs of the services its triggers cover and reports every
+// line that matches
+type LogWatcher struct {
+	cli      *client.Client
+	cfg      config.Config
+	triggers []LogTrigger
+}
+
+// NewLogWatcher creates a watcher; triggers without a stack cover every stack that
+// is not hidden
+func NewLogWatcher(cli *client.Client, cfg config.Config, triggers []LogTrigger) *LogWatcher {
+	return &LogWatcher{cli: cli, cfg: cfg, triggers: triggers}
+}
+
+// Run follows logs until ctx is cancelled, sending hits to the channel. Services
+// deployed later, including new services of a stack already followed, are picked up,
+// and followers that end are restarted.
+func (w *LogWatcher) Run(ctx context.Context, hits chan<- LogHit) {
+	var mu sync.Mutex
+	following := make(map[string]bool)
+	lastNotified := make(map[string]time.Time)
+
+	ticker := time.NewTicker(logRescanInterval)
+	defer ticker.Stop()
+
+	for {
+		for _, service := range w.services(ctx) {
+			mu.Lock()
+			if following[service.ID] {
+				mu.Unlock()
+				continue
+			}
+			following[service.ID] = true
+			mu.Unlock()
+
+			go func(service swarm.Service) {
+				w.follow(ctx, service, hits, &mu, lastNotified)
+				mu.Lock()
+				delete(following, service.ID)
+				mu.Unlock()
+			}(service)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// services returns the stack services covered by at least one trigger
+func (w *LogWatcher) services(ctx context.Context) []swarm.Service {
+	services, err := docker.ListStackServices(ctx, w.cli)
+	if err != nil {
+		return nil
+	}
+
+	var covered []swarm.Service
+	for _, service := range services {
+		stack := service.Spec.Labels["com.docker.stack.namespace"]
+		for _, t := range w.triggers {
+			if t.matches(stack, service.Spec.Name) && (t.Stack != "" || !w.cfg.IsHidden(stack)) {
+				covered = append(covered, service)
+				break
+			}
+		}
+	}
+	return covered
+}
+
+// follow matches the new log lines of one service until the stream ends
+func (w *LogWatcher) follow(ctx context.Context, service swarm.Service, hits chan<- LogHit, mu *sync.Mutex, lastNotified map[string]time.Time) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	stack := service.Spec.Labels["com.docker.stack.namespace"]
+	lines := make(chan string, 64)
+	done := make(chan struct{})
+	go func() {
+		_ = docker.FollowServiceLogs(ctx, w.cli, service, 0, lines)
+		close(done)
+	}()
+	maxContext := 0
+	for _, t := range w.triggers {
+		maxContext = max(maxContext, t.Context)
+	}
+
+	recent := make(map[string][]string)
+	var pending []*LogHit
+	flush := func(all bool) {
+		kept := pending[:0]
+		for _, hit := range pending {
+			if all || len(hit.After) >= w.trigger(hit.Trigger).Context || time.Since(hit.Time) > logContextWait {
+				select {
+				case hits <- *hit:
+				case <-ctx.Done():
+				}
+			} else {
+				kept = append(kept, hit)
+			}
+		}
+		pending = kept
+	}
+
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-done:
+			// Drain what was read before the stream ended
+			for len(lines) > 0 {
+				w.match(stack, <-lines, recent, &pending, maxContext, mu, lastNotified)
+			}
+			flush(true)
+			return
+		case <-ticker.C:
+			flush(false)
+		case line := <-lines:
+			w.match(stack, line, recent, &pending, maxContext, mu, lastNotified)
+			flush(false)
+		}
+	}
+}
+
+// match checks a line against the triggers, records it as context for pending hits
+// and remembers it as context for later hits
+func (w *LogWatcher) match(stack, line string, recent map[string][]string, pending *[]*LogHit, maxContext int, mu *sync.Mutex, lastNotified map[string]time.Time) {
+	service, text, ok := strings.Cut(line, " | ")
+	if !ok {
+		return
+	}
+
+	for _, hit := range *pending {
+		if hit.Service == service && len(hit.After) < w.trigger(hit.Trigger).Context {
+			hit.After = append(hit.After, text)
+		}
+	}
+
+	now := time.Now()
+	for _, t := range w.triggers {
+		if !t.matches(stack, service) || !t.Pattern.MatchString(text) {
+			continue
+		}
+
+		before := recent[service]
+		if len(before) > t.Context {
+			before = before[len(before)-t.Context:]
+		}
+		hit := &LogHit{
+			Trigger: t.Name,
+			Stack:   stack,
+			Service: service,
+			Line:    text,
+			Before:  slices.Clone(before),
+			Time:    now,
+		}
+
+		key := t.Name + "\x00" + service
+		mu.Lock()
+		if now.Sub(lastNotified[key]) < t.Cooldown {
+			hit.Throttled = true
+		} else {
+			lastNotified[key] = now
+		}
+		mu.Unlock()
+
+		*pending = append(*pending, hit)
+	}
+
+	if maxContext > 0 {
+		buffer := append(recent[service], text)
+		if len(buffer) > maxContext {
+			buffer = buffer[len(buffer)-maxContext:]
+		}
+		recent[service] = buffer
+	}
+}
+
+// trigger returns a trigger by name
+func (w *LogWatcher) trigger(name string) LogTrigger {
+	for _, t := range w.triggers {
+		if t.Name == name {
+			return t
+		}
+	}
+	return LogTrigger{}
+}
@@ -0,0 +1,147 @@
+package alerts
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"pulse/internal/config"
+)
+
+// Notifier delivers alert transitions somewhere outside Pulse
+type Notifier interface {
+	Notify(ctx context.Context, alert Alert) error
+}
+
+// Notifier types accepted in the alert_notifiers config
+const (
+	NotifierWebhook = "webhook"
+	NotifierSlack   = "slack"
+	NotifierCommand = "command"
+)
+
+// NewNotifiers creates the configured notifiers
+func NewNotifiers(configured []config.AlertNotifier) ([]Notifier, error) {
+	httpClient := &http.Client{Timeout: 10 * time.Second}
+
+	notifiers := make([]Notifier, 0, len(configured))
+	for i, c := range configured {
+		switch c.Type {
+		case NotifierWebhook, NotifierSlack:
+			if c.URL == "" {
+				return nil, fmt.Errorf("alert_notifiers[%d]: %s needs a url", i, c.Type)
+			}
+			if c.Type == NotifierWebhook {
+				notifiers = append(notifiers, &Webhook{URL: c.URL, client: httpClient})
+			} else {
+				notifiers = append(notifiers, &Slack{URL: c.URL, client: httpClient})
+			}
+		case NotifierCommand:
+			if len(c.Command) == 0 {
+				return nil, fmt.Errorf("alert_notifiers[%d]: command needs a command", i)
+			}
+			notifiers = append(notifiers, &Command{Args: c.Command})
+		default:
+			return nil, fmt.Errorf("alert_notifiers[%d]: unknown type %q (use webhook, slack or command)", i, c.Type)
+		}
+	}
+	return notifiers, nil
+}
+
+// Webhook posts each alert as JSON
+type Webhook struct {
+	URL    string
+	client *http.Client
+}
+
+// Notify posts the alert to the webhook
+func (w *Webhook) Notify(ctx context.Context, alert Alert) error {
+	return postJSON(ctx, w.client, w.URL, alert)
+}
+
+// Slack posts each alert as a message to a Slack-compatible incoming webhook
+type Slack struct {
+	URL    string
+	client *http.Client
+}
+
+// Notify posts a one-line summary of the alert, followed by its log context if any
+func (s *Slack) Notify(ctx context.Context, alert Alert) error {
+	text := Summary(alert)
+	if len(alert.Context) > 0 {
+		text += "\n```\n" + strings.Join(alert.Context, "\n") + "\n```"
+	}
+	return postJSON(ctx, s.client, s.URL, map[string]string{"text": text})
+}
+
+// Command runs a program for each alert with the alert as JSON on stdin and in
+// PULSE_ALERT_* environment variables, e.g. notify-send for desktop notifications
+type Command struct {
+	Args []string
+}
+
+// Notify runs the command and waits for it to finish
+func (c *Command) Notify(ctx context.Context, alert Alert) error {
+	body, err := json.Marshal(alert)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, c.Args[0], c.Args[1:]...)
+	cmd.Stdin = bytes.NewReader(body)
+	cmd.Env = append(os.Environ(),
+		"PULSE_ALERT_RULE="+alert.Rule,
+		"PULSE_ALERT_STATE="+string(alert.State),
+		"PULSE_ALERT_STACK="+alert.Stack,
+		"PULSE_ALERT_SERVICE="+alert.Service,
+		"PULSE_ALERT_SUBJECT="+alert.Subject,
+		"PULSE_ALERT_MESSAGE="+alert.Message,
+		"PULSE_ALERT_SUMMARY="+Summary(alert),
+	)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("error running alert command %s: %v: %s", c.Args[0], err, bytes.TrimSpace(output))
+	}
+	return nil
+}
+
+// Summary renders an alert as one line of text
+func Summary(alert Alert) string {
+	state := "FIRING"
+	if alert.State == StateResolved {
+		state = "RESOLVED"
+	}
+	return fmt.Sprintf("[%s] %s: %s %s", state, alert.Rule, alert.Subject, alert.Message)
+}
+
+func postJSON(ctx context.Context, client *http.Client, url string, v any) error {
+	body, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("error sending alert to %s: %v", req.URL.Host, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("error sending alert to %s: %s", req.URL.Host, resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,232 @@
+package alerts
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"pulse/internal/config"
+	"pulse/internal/docker"
+)
+
+// Condition is the kind of problem a rule watches for
+type Condition string
+
+// Supported conditions
+const (
+	// ReplicasBelowDesired fires when a service runs fewer tasks than it should
+	ReplicasBelowDesired Condition = "replicas_below_desired"
+	// RestartLoop fires when Threshold tasks of a service failed within Window
+	RestartLoop Condition = "restart_loop"
+	// Unhealthy fires when a container's healthcheck reports unhealthy
+	Unhealthy Condition = "unhealthy"
+	// CPUAbove fires when a container uses more than Threshold percent of a CPU
+	CPUAbove Condition = "cpu_above"
+	// MemoryAbove fires when a container uses more than Threshold percent of its memory limit
+	MemoryAbove Condition = "memory_above"
+	// OOMKilled fires when a container was killed for running out of memory within Window
+	OOMKilled Condition = "oom_killed"
+)
+
+// Defaults for rules that leave Threshold or Window unset
+const (
+	DefaultRestartThreshold = 3
+	DefaultWindow           = 10 * time.Minute
+)
+
+// Rule is a validated alert rule
+type Rule struct {
+	Name      string
+	Condition Condition
+	Stack     string
+	Service   string
+	For       time.Duration
+	Threshold float64
+	Window    time.Duration
+}
+
+// violation is one subject currently breaking a rule
+type violation struct {
+	stack   string
+	service string
+	subject string
+	message string
+}
+
+// NewRules validates the configured rules and fills in defaults
+func NewRules(configured []config.AlertRule) ([]Rule, error) {
+	rules := make([]Rule, 0, len(configured))
+	names := make(map[string]bool)
+	for i, c := range configured {
+		rule := Rule{
+			Name:      c.Name,
+			Condition: Condition(c.Condition),
+			Stack:     c.Stack,
+			Service:   c.Service,
+			For:       c.For,
+			Threshold: c.Threshold,
+			Window:    c.Window,
+		}
+		if rule.Name == "" {
+			rule.Name = fmt.Sprintf("%s-%d", rule.Condition, i)
+		}
+		if names[rule.Name] {
+			return nil, fmt.Errorf("alert rule %s: another rule has the same name", rule.Name)
+		}
+		names[rule.Name] = true
+
+		switch rule.Condition {
+		case ReplicasBelowDesired, Unhealthy:
+		case RestartLoop:
+			if rule.Threshold == 0 {
+				rule.Threshold = DefaultRestartThreshold
+			}
+			if rule.Window == 0 {
+				rule.Window = DefaultWindow
+			}
+		case CPUAbove, MemoryAbove:
+			if rule.Threshold <= 0 {
+				return nil, fmt.Errorf("alert rule %s: %s needs a threshold in percent", rule.Name, rule.Condition)
+			}
+		case OOMKilled:
+			if rule.Window == 0 {
+				rule.Window = DefaultWindow
+			}
+		default:
+			return nil, fmt.Errorf("alert rule %s: unknown condition %q", rule.Name, c.Condition)
+		}
+
+		rules = append(rules, rule)
+	}
+	return rules, nil
+}
+
+// matches reports whether a stack and service fall under the rule's filters. Services
+// may be named with or without their stack prefix.
+func (r Rule) matches(stack, service string) bool {
+	if r.Stack != "" && r.Stack != stack {
+		return false
+	}
+	if r.Service != "" && r.Service != service && stack+"_"+r.Service != service {
+		return false
+	}
+	return true
+}
+
+// needsStats reports whether the rule looks at resource usage, which is slow to sample
+func (r Rule) needsStats() bool {
+	return r.Condition == CPUAbove || r.Condition == MemoryAbove
+}
+
+// unknown reports whether the snapshot lacks the data to tell if subject still
+// breaks the rule
+func (r Rule) unknown(s Snapshot, subject string) bool {
+	switch r.Condition {
+	case ReplicasBelowDesired:
+		return s.ServicesFailed
+	case RestartLoop:
+		return s.FailuresFailed
+	case Unhealthy:
+		return s.ContainersFailed
+	case CPUAbove, MemoryAbove:
+		return s.ContainersFailed || s.StatsFailed[subject]
+	case OOMKilled:
+		return s.ExitsFailed
+	}
+	return false
+}
+
+// check returns the subjects currently breaking the rule
+func (r Rule) check(s Snapshot) []violation {
+	var violations []violation
+
+	switch r.Condition {
+	case ReplicasBelowDesired:
+		for _, service := range s.Services {
+			if r.matches(service.Stack, service.Service) && service.Running < service.Desired {
+				violations = append(violations, violation{
+					stack:   service.Stack,
+					service: service.Service,
+					subject: service.Service,
+					message: fmt.Sprintf("running %d of %d replicas", service.Running, service.Desired),
+				})
+			}
+		}
+
+	case RestartLoop:
+		counts := make(map[string]int)
+		last := make(map[string]docker.TaskFailure)
+		since := s.Time.Add(-r.Window)
+		for _, failure := range s.Failures {
+			if r.matches(failure.Stack, failure.Service) && failure.Time.After(since) {
+				counts[failure.Service]++
+				if failure.Time.After(last[failure.Service].Time) {
+					last[failure.Service] = failure
+				}
+			}
+		}
+		for service, count := range counts {
+			if float64(count) >= r.Threshold {
+				violations = append(violations, violation{
+					stack:   last[service].Stack,
+					service: service,
+					subject: service,
+					message: fmt.Sprintf("%d tasks failed in %s, last: %s", count, r.Window, last[service].Message),
+				})
+			}
+		}
+
+	case Unhealthy:
+		for _, c := range s.Containers {
+			stack, service, name := containerLabels(c.Labels, c.Names)
+			if r.matches(stack, service) && docker.ContainerHealth(c) == "unhealthy" {
+				violations = append(violations, violation{stack: stack, service: service, subject: name, message: "healthcheck failing"})
+			}
+		}
+
+	case CPUAbove, MemoryAbove:
+		for _, c := range s.Containers {
+			stack, service, name := containerLabels(c.Labels, c.Names)
+			stats, ok := s.Stats[c.ID]
+			if !ok || !r.matches(stack, service) {
+				continue
+			}
+
+			value, what := stats.CPUPercent, "CPU"
+			if r.Condition == MemoryAbove {
+				value, what = stats.MemoryPercent(), "memory"
+			}
+			if value > r.Threshold {
+				violations = append(violations, violation{
+					stack:   stack,
+					service: service,
+					subject: name,
+					message: fmt.Sprintf("%s at %.1f%% (threshold %.0f%%)", what, value, r.Threshold),
+				})
+			}
+		}
+
+	case OOMKilled:
+		since := s.Time.Add(-r.Window)
+		for _, exit := range s.Exits {
+			if exit.OOMKilled && exit.FinishedAt.After(since) && r.matches(exit.Stack, exit.Service) {
+				violations = append(violations, violation{
+					stack:   exit.Stack,
+					service: exit.Service,
+					subject: exit.Name,
+					message: fmt.Sprintf("killed for running out of memory at %s", exit.FinishedAt.Local().Format("15:04:05")),
+				})
+			}
+		}
+	}
+
+	return violations
+}
+
+// containerLabels returns the stack, service and name of a container
+func containerLabels(labels map[string]string, names []string) (stack, service, name string) {
+	if len(names) > 0 {
+		name = strings.TrimPrefix(names[0], "/")
+	}
+	return labels["com.docker.stack.namespace"], labels["com.docker.swarm.service.name"], name
+}
@@ -0,0 +1,95 @@
+package alerts
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/client"
+
+	"pulse/internal/docker"
+)
+
+// statsConcurrency bounds the stats requests in flight while collecting a snapshot
+const statsConcurrency = 8
+
+// Snapshot is the cluster state the rules are evaluated against
+type Snapshot struct {
+	Time       time.Time
+	Services   []docker.ServiceStatus
+	Failures   []docker.TaskFailure
+	Containers []types.Container
+	Exits      []docker.ContainerExit
+	Stats      map[string]docker.ResourceStats
+
+	// Parts that could not be collected; alerts depending on them are kept as they
+	// are instead of resolving, so a failing API call doesn't make them flap
+	ServicesFailed, FailuresFailed, ContainersFailed, ExitsFailed bool
+	// StatsFailed holds the names of containers whose usage could not be sampled
+	StatsFailed map[string]bool
+}
+
+// Collect gathers the state needed by rules; resource usage is only sampled when a
+// rule needs it. Parts that fail are left empty and reported in the returned error.
+func Collect(ctx context.Context, cli *client.Client, rules []Rule) (Snapshot, error) {
+	s := Snapshot{Time: time.Now(), Stats: make(map[string]docker.ResourceStats), StatsFailed: make(map[string]bool)}
+
+	window := time.Duration(0)
+	needStats := false
+	for _, rule := range rules {
+		window = max(window, rule.Window)
+		needStats = needStats || rule.needsStats()
+	}
+
+	var errs []error
+	var err error
+	if s.Services, err = docker.ListServiceStatus(ctx, cli); err != nil {
+		s.ServicesFailed = true
+		errs = append(errs, err)
+	}
+	if s.Failures, err = docker.ListTaskFailures(ctx, cli, s.Time.Add(-window)); err != nil {
+		s.FailuresFailed = true
+		errs = append(errs, err)
+	}
+	if s.Containers, err = docker.ListAllStackContainers(ctx, cli, false); err != nil {
+		s.ContainersFailed = true
+		errs = append(errs, err)
+	}
+	if s.Exits, err = docker.ListContainerExits(ctx, cli, s.Time.Add(-window)); err != nil {
+		s.ExitsFailed = true
+		errs = append(errs, err)
+	}
+
+	if needStats {
+		var mu sync.Mutex
+		var wg sync.WaitGroup
+		sem := make(chan struct{}, statsConcurrency)
+		for _, c := range s.Containers {
+			if c.State != "running" {
+				continue
+			}
+			_, _, name := containerLabels(c.Labels, c.Names)
+			wg.Add(1)
+			go func(id, name string) {
+				defer wg.Done()
+				sem <- struct{}{}
+				defer func() { <-sem }()
+
+				stats, err := docker.GetResourceStats(ctx, cli, id)
+				mu.Lock()
+				defer mu.Unlock()
+				if err != nil {
+					s.StatsFailed[name] = true
+					errs = append(errs, err)
+					return
+				}
+				s.Stats[id] = stats
+			}(c.ID, name)
+		}
+		wg.Wait()
+	}
+
+	return s, errors.Join(errs...)
+}
@@ -0,0 +1,388 @@
+package api
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"sort"
+	"strconv"
+
+	"github.com/docker/docker/api/types/swarm"
+	"github.com/docker/docker/client"
+
+	"pulse/internal/auth"
+	"pulse/internal/config"
+	"pulse/internal/docker"
+)
+
+//go:embed openapi.yaml
+var openAPI []byte
+
+// Error codes returned in ErrorResponse
+const (
+	CodeBadRequest   = "bad_request"
+	CodeUnauthorized = "unauthorized"
+	CodeForbidden    = "forbidden"
+	CodeNotFound     = "not_found"
+	CodeConflict     = "conflict"
+	CodeDocker       = "docker_error"
+)
+
+// ErrorResponse is the body returned with every non-2xx status
+type ErrorResponse struct {
+	Error ErrorDetail `json:"error"`
+}
+
+// ErrorDetail describes a failed request; Code is stable, Message is for humans
+type ErrorDetail struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// Document is the envelope wrapped around every list, the same one the headless
+// commands print with -o json
+type Document struct {
+	SchemaVersion string `json:"schemaVersion"`
+	Kind          string `json:"kind"`
+	Items         any    `json:"items"`
+}
+
+// ActionResult is returned by operations that change a stack
+type ActionResult struct {
+	Message string `json:"message"`
+}
+
+// ScaleRequest is the body of a scale call
+type ScaleRequest struct {
+	Replicas *uint64 `json:"replicas"`
+}
+
+// DeployRequest is the body of a deploy call
+type DeployRequest struct {
+	Image string `json:"image"`
+}
+
+// Server exposes stack operations as a JSON REST API
+type Server struct {
+	cli  *client.Client
+	cfg  config.Config
+	auth *auth.Authenticator
+}
+
+// NewServer creates an API server; callers authenticate with one of tokens
+func NewServer(cli *client.Client, cfg config.Config, tokens []auth.Token) *Server {
+	return &Server{cli: cli, cfg: cfg, auth: auth.New(tokens, writeError)}
+}
+
+// Handler returns the HTTP handler serving the API under /api/v1
+func (s *Server) Handler() http.Handler {
+	read := func(h http.HandlerFunc) http.HandlerFunc { return s.auth.Require(auth.ScopeRead, h) }
+	operate := func(h http.HandlerFunc) http.HandlerFunc { return s.auth.Require(auth.ScopeOperate, h) }
+
+	api := http.NewServeMux()
+	api.HandleFunc("GET /api/v1/stacks", read(s.handleStacks))
+	api.HandleFunc("GET /api/v1/stacks/{stack}/containers", read(s.handleContainers))
+	api.HandleFunc("GET /api/v1/stacks/{stack}/services", read(s.handleServices))
+	api.HandleFunc("GET /api/v1/stacks/{stack}/logs", read(s.handleStackLogs))
+	api.HandleFunc("GET /api/v1/containers/{id}/logs", read(s.handleContainerLogs))
+	api.HandleFunc("POST /api/v1/stacks/{stack}/restart", operate(s.handleRestart))
+	api.HandleFunc("POST /api/v1/stacks/{stack}/kill", operate(s.handleKill))
+	api.HandleFunc("POST /api/v1/stacks/{stack}/services/{service}/scale", operate(s.handleScale))
+	api.HandleFunc("POST /api/v1/stacks/{stack}/services/{service}/deploy", operate(s.handleDeploy))
+	api.HandleFunc("/api/v1/", func(w http.ResponseWriter, r *http.Request) {
+		writeError(w, http.StatusNotFound, fmt.Errorf("no endpoint %s %s", r.Method, r.URL.Path))
+	})
+
+	// The OpenAPI document is public so clients can be generated without a token
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/v1/openapi.yaml", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/yaml")
+		_, _ = w.Write(openAPI)
+	})
+	mux.Handle("/api/v1/", s.auth.Middleware(api))
+	return mux
+}
+
+func (s *Server) handleStacks(w http.ResponseWriter, r *http.Request) {
+	stacks, err := s.stacks(r.Context())
+	if err != nil {
+		writeError(w, http.StatusBadGateway, err)
+		return
+	}
+
+	items := make([]docker.StackStats, 0, len(stacks))
+	for _, stack := range stacks {
+		stats, err := docker.GetStackStats(r.Context(), s.cli, stack)
+		if err != nil {
+			writeError(w, http.StatusBadGateway, err)
+			return
+		}
+		items = append(items, stats)
+	}
+	writeList(w, "StackList", items)
+}
+
+func (s *Server) handleContainers(w http.ResponseWriter, r *http.Request) {
+	stack, ok := s.requireStack(w, r)
+	if !ok {
+		return
+	}
+
+	containers, err := docker.ListContainers(r.Context(), s.cli, stack)
+	if err != nil {
+		writeError(w, http.StatusBadGateway, err)
+		return
+	}
+
+	items := make([]docker.ContainerSummary, 0, len(containers))
+	for _, c := range containers {
+		items = append(items, docker.SummarizeContainer(c))
+	}
+	sort.Slice(items, func(i, j int) bool { return items[i].Name < items[j].Name })
+	writeList(w, "ContainerList", items)
+}
+
+func (s *Server) handleServices(w http.ResponseWriter, r *http.Request) {
+	stack, ok := s.requireStack(w, r)
+	if !ok {
+		return
+	}
+
+	services, err := docker.ListServiceStatus(r.Context(), s.cli)
+	if err != nil {
+		writeError(w, http.StatusBadGateway, err)
+		return
+	}
+
+	items := make([]docker.ServiceStatus, 0)
+	for _, service := range services {
+		if service.Stack == stack {
+			items = append(items, service)
+		}
+	}
+	writeList(w, "ServiceList", items)
+}
+
+func (s *Server) handleStackLogs(w http.ResponseWriter, r *http.Request) {
+	stack, ok := s.requireStack(w, r)
+	if !ok {
+		return
+	}
+	opts, err := s.logOptions(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	logs, err := docker.StackLogs(r.Context(), s.cli, stack, opts)
+	if err != nil {
+		writeError(w, http.StatusBadGateway, err)
+		return
+	}
+	writeText(w, logs)
+}
+
+func (s *Server) handleContainerLogs(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	details, err := docker.InspectContainer(r.Context(), s.cli, id)
+	if err != nil {
+		if client.IsErrNotFound(err) {
+			writeError(w, http.StatusNotFound, fmt.Errorf("container %s not found", id))
+		} else {
+			writeError(w, http.StatusBadGateway, err)
+		}
+		return
+	}
+
+	// Only containers of visible stacks are reachable, like in the TUI
+	stack := details.Config.Labels["com.docker.stack.namespace"]
+	if stack == "" || s.cfg.IsHidden(stack) {
+		writeError(w, http.StatusNotFound, fmt.Errorf("container %s is not part of a stack", id))
+		return
+	}
+
+	opts, err := s.logOptions(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	logs, err := docker.ContainerLogs(r.Context(), s.cli, id, opts)
+	if err != nil {
+		writeError(w, http.StatusBadGateway, err)
+		return
+	}
+	writeText(w, logs)
+}
+
+func (s *Server) handleRestart(w http.ResponseWriter, r *http.Request) {
+	stack, ok := s.requireStack(w, r)
+	if !ok {
+		return
+	}
+
+	if err := docker.RestartStack(r.Context(), s.cli, stack); err != nil {
+		writeError(w, http.StatusBadGateway, err)
+		return
+	}
+	writeJSON(w, http.StatusAccepted, ActionResult{Message: fmt.Sprintf("Restarting stack %s", stack)})
+}
+
+func (s *Server) handleKill(w http.ResponseWriter, r *http.Request) {
+	stack, ok := s.requireStack(w, r)
+	if !ok {
+		return
+	}
+
+	if err := docker.KillStack(r.Context(), s.cli, stack); err != nil {
+		writeError(w, http.StatusBadGateway, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, ActionResult{Message: fmt.Sprintf("Killed stack %s", stack)})
+}
+
+func (s *Server) handleScale(w http.ResponseWriter, r *http.Request) {
+	var req ScaleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Replicas == nil {
+		writeError(w, http.StatusBadRequest, errors.New(`body must be {"replicas": <count>}`))
+		return
+	}
+
+	service, ok := s.requireService(w, r)
+	if !ok {
+		return
+	}
+
+	if err := docker.ScaleService(r.Context(), s.cli, service, *req.Replicas); err != nil {
+		if errors.Is(err, docker.ErrGlobalService) {
+			writeError(w, http.StatusConflict, err)
+		} else {
+			writeError(w, http.StatusBadGateway, err)
+		}
+		return
+	}
+	writeJSON(w, http.StatusAccepted, ActionResult{Message: fmt.Sprintf("Scaling service %s to %d replicas", service.Spec.Name, *req.Replicas)})
+}
+
+func (s *Server) handleDeploy(w http.ResponseWriter, r *http.Request) {
+	var req DeployRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Image == "" {
+		writeError(w, http.StatusBadRequest, errors.New(`body must be {"image": "<image>"}`))
+		return
+	}
+
+	service, ok := s.requireService(w, r)
+	if !ok {
+		return
+	}
+
+	if err := docker.DeployImage(r.Context(), s.cli, service, req.Image); err != nil {
+		writeError(w, http.StatusBadGateway, err)
+		return
+	}
+	writeJSON(w, http.StatusAccepted, ActionResult{Message: fmt.Sprintf("Deploying %s to service %s", req.Image, service.Spec.Name)})
+}
+
+// logOptions reads the tail, since and timestamps query parameters
+func (s *Server) logOptions(r *http.Request) (docker.LogOptions, error) {
+	query := r.URL.Query()
+	opts := docker.LogOptions{Tail: s.cfg.LogTail, Since: query.Get("since")}
+
+	if value := query.Get("tail"); value != "" {
+		tail, err := strconv.Atoi(value)
+		if err != nil || tail < 0 {
+			return opts, fmt.Errorf("invalid tail %q", value)
+		}
+		opts.Tail = tail
+	}
+	if value := query.Get("timestamps"); value != "" {
+		timestamps, err := strconv.ParseBool(value)
+		if err != nil {
+			return opts, fmt.Errorf("invalid timestamps %q", value)
+		}
+		opts.Timestamps = timestamps
+	}
+	return opts, nil
+}
+
+// stacks lists the stacks the API exposes: the same set the TUI shows
+func (s *Server) stacks(ctx context.Context) ([]string, error) {
+	stacks, err := docker.ListStacks(ctx, s.cli)
+	if err != nil {
+		return nil, err
+	}
+	stacks = s.cfg.VisibleStacks(stacks)
+	sort.Strings(stacks)
+	return stacks, nil
+}
+
+// requireStack returns the stack named in the path, answering 404 if it is not exposed
+func (s *Server) requireStack(w http.ResponseWriter, r *http.Request) (string, bool) {
+	stack := r.PathValue("stack")
+	stacks, err := s.stacks(r.Context())
+	if err != nil {
+		writeError(w, http.StatusBadGateway, err)
+		return "", false
+	}
+	if !slices.Contains(stacks, stack) {
+		writeError(w, http.StatusNotFound, fmt.Errorf("stack %s not found", stack))
+		return "", false
+	}
+	return stack, true
+}
+
+// requireService returns the service named in the path within its stack
+func (s *Server) requireService(w http.ResponseWriter, r *http.Request) (swarm.Service, bool) {
+	stack, ok := s.requireStack(w, r)
+	if !ok {
+		return swarm.Service{}, false
+	}
+
+	service, err := docker.FindStackService(r.Context(), s.cli, stack, r.PathValue("service"))
+	if err != nil {
+		if errors.Is(err, docker.ErrServiceNotFound) {
+			writeError(w, http.StatusNotFound, err)
+		} else {
+			writeError(w, http.StatusBadGateway, err)
+		}
+		return swarm.Service{}, false
+	}
+	return service, true
+}
+
+func writeList(w http.ResponseWriter, kind string, items any) {
+	writeJSON(w, http.StatusOK, Document{SchemaVersion: docker.SchemaVersion, Kind: kind, Items: items})
+}
+
+func writeText(w http.ResponseWriter, text string) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	_, _ = w.Write([]byte(text))
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+// writeError sends a structured error; the code follows from the status
+func writeError(w http.ResponseWriter, status int, err error) {
+	code := CodeDocker
+	switch status {
+	case http.StatusBadRequest:
+		code = CodeBadRequest
+	case http.StatusUnauthorized:
+		code = CodeUnauthorized
+	case http.StatusForbidden:
+		code = CodeForbidden
+	case http.StatusNotFound:
+		code = CodeNotFound
+	case http.StatusConflict:
+		code = CodeConflict
+	}
+	writeJSON(w, status, ErrorResponse{Error: ErrorDetail{Code: code, Message: err.Error()}})
+}
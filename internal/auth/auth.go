@@ -0,0 +1,125 @@
+package auth
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// Scope limits what an authenticated caller may do
+type Scope string
+
+// ScopeRead allows reading state such as containers, stats and logs; ScopeOperate
+// also allows changing it, such as exec, restart or scale
+const (
+	ScopeRead    Scope = "read"
+	ScopeOperate Scope = "operate"
+)
+
+// ParseScope validates a scope name from the config file or a certificate
+func ParseScope(name string) (Scope, error) {
+	switch Scope(name) {
+	case ScopeRead, ScopeOperate:
+		return Scope(name), nil
+	default:
+		return "", fmt.Errorf("unknown scope %q (use %s or %s)", name, ScopeRead, ScopeOperate)
+	}
+}
+
+// Allows reports whether s grants the required scope
+func (s Scope) Allows(required Scope) bool {
+	return s == ScopeOperate || s == required
+}
+
+// Token is an accepted bearer token
+type Token struct {
+	Name  string
+	Value string
+	Scope Scope
+}
+
+// Identity is the authenticated caller of a request
+type Identity struct {
+	Name  string
+	Scope Scope
+}
+
+// ErrorWriter renders an authentication failure in the format of the calling API
+type ErrorWriter func(w http.ResponseWriter, status int, err error)
+
+// Authenticator identifies callers by bearer token or, failing that, by a client
+// certificate verified during the TLS handshake; the certificate's organizational
+// unit carries its scope
+type Authenticator struct {
+	tokens     []Token
+	writeError ErrorWriter
+}
+
+type identityKey struct{}
+
+// New creates an authenticator accepting tokens; failures are written with writeError
+func New(tokens []Token, writeError ErrorWriter) *Authenticator {
+	return &Authenticator{tokens: tokens, writeError: writeError}
+}
+
+// FromContext returns the caller stored by Middleware
+func FromContext(ctx context.Context) Identity {
+	identity, _ := ctx.Value(identityKey{}).(Identity)
+	return identity
+}
+
+// Middleware rejects unauthenticated requests and stores the caller in the request context
+func (a *Authenticator) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		identity, err := a.identify(r)
+		if err != nil {
+			a.writeError(w, http.StatusUnauthorized, err)
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), identityKey{}, identity)))
+	})
+}
+
+// Require wraps a handler so it only runs for callers granted scope
+func (a *Authenticator) Require(scope Scope, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		identity := FromContext(r.Context())
+		if !identity.Scope.Allows(scope) {
+			a.writeError(w, http.StatusForbidden, fmt.Errorf("%s requires the %s scope; %s has %s", r.URL.Path, scope, identity.Name, identity.Scope))
+			return
+		}
+		next(w, r)
+	}
+}
+
+func (a *Authenticator) identify(r *http.Request) (Identity, error) {
+	if header := r.Header.Get("Authorization"); header != "" {
+		value, ok := strings.CutPrefix(header, "Bearer ")
+		if !ok {
+			return Identity{}, errors.New("unsupported authorization scheme")
+		}
+		for _, token := range a.tokens {
+			if subtle.ConstantTimeCompare([]byte(value), []byte(token.Value)) == 1 {
+				return Identity{Name: token.Name, Scope: token.Scope}, nil
+			}
+		}
+		return Identity{}, errors.New("invalid bearer token")
+	}
+
+	if r.TLS != nil && len(r.TLS.VerifiedChains) > 0 {
+		cert := r.TLS.VerifiedChains[0][0]
+		// Certificates without a recognised scope get the least privilege
+		scope := ScopeRead
+		if len(cert.Subject.OrganizationalUnit) > 0 {
+			if parsed, err := ParseScope(cert.Subject.OrganizationalUnit[0]); err == nil {
+				scope = parsed
+			}
+		}
+		return Identity{Name: cert.Subject.CommonName, Scope: scope}, nil
+	}
+
+	return Identity{}, errors.New("missing bearer token or client certificate")
+}
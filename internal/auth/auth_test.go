@@ -0,0 +1,176 @@
+package auth
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+var testTokens = []Token{
+	{Name: "viewer", Value: "read-token", Scope: ScopeRead},
+	{Name: "admin", Value: "operate-token", Scope: ScopeOperate},
+}
+
+// withCert marks a request as made with a verified client certificate
+func withCert(r *http.Request, commonName string, units ...string) {
+	cert := &x509.Certificate{Subject: pkix.Name{CommonName: commonName, OrganizationalUnit: units}}
+	r.TLS = &tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{cert}}}
+}
+
+func testError(w http.ResponseWriter, status int, err error) {
+	http.Error(w, err.Error(), status)
+}
+
+func TestParseScope(t *testing.T) {
+	tests := []struct {
+		name    string
+		want    Scope
+		wantErr bool
+	}{
+		{name: "read", want: ScopeRead},
+		{name: "operate", want: ScopeOperate},
+		{name: "admin", wantErr: true},
+		{name: "", wantErr: true},
+		{name: "Read", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseScope(tt.name)
+			if (err != nil) != tt.wantErr || got != tt.want {
+				t.Errorf("ParseScope(%q) = %q, %v, want %q, error %v", tt.name, got, err, tt.want, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestScopeAllows(t *testing.T) {
+	tests := []struct {
+		scope, required Scope
+		want            bool
+	}{
+		{scope: ScopeRead, required: ScopeRead, want: true},
+		{scope: ScopeRead, required: ScopeOperate, want: false},
+		{scope: ScopeOperate, required: ScopeRead, want: true},
+		{scope: ScopeOperate, required: ScopeOperate, want: true},
+		{scope: "", required: ScopeRead, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.scope)+" "+string(tt.required), func(t *testing.T) {
+			if got := tt.scope.Allows(tt.required); got != tt.want {
+				t.Errorf("%q.Allows(%q) = %v, want %v", tt.scope, tt.required, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIdentify(t *testing.T) {
+	tests := []struct {
+		name    string
+		header  string
+		cert    []string // common name followed by organizational units; nil for none
+		want    Identity
+		wantErr string
+	}{
+		{name: "read token", header: "Bearer read-token", want: Identity{Name: "viewer", Scope: ScopeRead}},
+		{name: "operate token", header: "Bearer operate-token", want: Identity{Name: "admin", Scope: ScopeOperate}},
+		{name: "unknown token", header: "Bearer guess", wantErr: "invalid bearer token"},
+		{name: "empty token", header: "Bearer ", wantErr: "invalid bearer token"},
+		{name: "other scheme", header: "Basic YWRtaW46YWRtaW4=", wantErr: "unsupported authorization scheme"},
+		{name: "nothing", wantErr: "missing bearer token or client certificate"},
+		{name: "certificate with operate unit", cert: []string{"ci", "operate"}, want: Identity{Name: "ci", Scope: ScopeOperate}},
+		{name: "certificate with read unit", cert: []string{"grafana", "read"}, want: Identity{Name: "grafana", Scope: ScopeRead}},
+		{name: "certificate with unknown unit gets read", cert: []string{"laptop", "admin"}, want: Identity{Name: "laptop", Scope: ScopeRead}},
+		{name: "certificate without unit gets read", cert: []string{"laptop"}, want: Identity{Name: "laptop", Scope: ScopeRead}},
+		{name: "only the first unit counts", cert: []string{"ci", "read", "operate"}, want: Identity{Name: "ci", Scope: ScopeRead}},
+		{
+			name:   "token wins over the certificate",
+			header: "Bearer read-token",
+			cert:   []string{"ci", "operate"},
+			want:   Identity{Name: "viewer", Scope: ScopeRead},
+		},
+		{
+			name:    "bad token is not rescued by the certificate",
+			header:  "Bearer guess",
+			cert:    []string{"ci", "operate"},
+			wantErr: "invalid bearer token",
+		},
+	}
+
+	a := New(testTokens, testError)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.header != "" {
+				r.Header.Set("Authorization", tt.header)
+			}
+			if tt.cert != nil {
+				withCert(r, tt.cert[0], tt.cert[1:]...)
+			}
+
+			got, err := a.identify(r)
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Fatalf("identify() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("identify() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("identify() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRequire(t *testing.T) {
+	tests := []struct {
+		name     string
+		required Scope
+		header   string
+		cert     []string
+		want     int
+	}{
+		{name: "read token reads", required: ScopeRead, header: "Bearer read-token", want: http.StatusOK},
+		{name: "operate token reads", required: ScopeRead, header: "Bearer operate-token", want: http.StatusOK},
+		{name: "read token cannot exec", required: ScopeOperate, header: "Bearer read-token", want: http.StatusForbidden},
+		{name: "operate token execs", required: ScopeOperate, header: "Bearer operate-token", want: http.StatusOK},
+		{name: "unknown unit cannot signal", required: ScopeOperate, cert: []string{"laptop", "admin"}, want: http.StatusForbidden},
+		{name: "operate certificate signals", required: ScopeOperate, cert: []string{"ci", "operate"}, want: http.StatusOK},
+		{name: "anonymous", required: ScopeRead, want: http.StatusUnauthorized},
+		{name: "invalid token", required: ScopeRead, header: "Bearer guess", want: http.StatusUnauthorized},
+	}
+
+	a := New(testTokens, testError)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var caller Identity
+			handler := a.Middleware(a.Require(tt.required, func(w http.ResponseWriter, r *http.Request) {
+				caller = FromContext(r.Context())
+			}))
+
+			r := httptest.NewRequest(http.MethodPost, "/containers/abc/exec", nil)
+			if tt.header != "" {
+				r.Header.Set("Authorization", tt.header)
+			}
+			if tt.cert != nil {
+				withCert(r, tt.cert[0], tt.cert[1:]...)
+			}
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, r)
+
+			if w.Code != tt.want {
+				t.Errorf("status = %d, want %d: %s", w.Code, tt.want, w.Body.String())
+			}
+			if tt.want == http.StatusOK && caller.Name == "" {
+				t.Error("handler ran without the caller in its context")
+			}
+		})
+	}
+}
@@ -0,0 +1,231 @@
+package certs
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// AgentServerName is included in every node certificate so clients can verify agents
+// reached through discovered task IPs, which change whenever a task is rescheduled
+const AgentServerName = "pulse-agent"
+
+// Validity periods of generated certificates
+const (
+	CAValidity   = 10 * 365 * 24 * time.Hour
+	CertValidity = 2 * 365 * 24 * time.Hour
+)
+
+// File names inside the certificate directory
+const (
+	CAFile    = "ca.pem"
+	CAKeyFile = "ca-key.pem"
+)
+
+// CertFile returns the certificate and key paths for a named node or client
+func CertFile(dir, name string) (cert, key string) {
+	return filepath.Join(dir, name+".pem"), filepath.Join(dir, name+"-key.pem")
+}
+
+// GenerateCA creates a new certificate authority in dir; an existing CA is never overwritten
+func GenerateCA(dir string) error {
+	if _, err := os.Stat(filepath.Join(dir, CAFile)); err == nil {
+		return fmt.Errorf("a CA already exists in %s", dir)
+	}
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return fmt.Errorf("error creating %s: %v", dir, err)
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return fmt.Errorf("error generating CA key: %v", err)
+	}
+
+	template, err := newTemplate("Pulse CA", CAValidity)
+	if err != nil {
+		return err
+	}
+	template.IsCA = true
+	template.BasicConstraintsValid = true
+	template.KeyUsage = x509.KeyUsageCertSign | x509.KeyUsageCRLSign
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		return fmt.Errorf("error creating CA certificate: %v", err)
+	}
+
+	return writePair(filepath.Join(dir, CAFile), filepath.Join(dir, CAKeyFile), der, key)
+}
+
+// IssueNode issues a server certificate for an agent node, valid for the given
+// host names and IPs as well as AgentServerName
+func IssueNode(dir, name string, hosts []string) error {
+	template, err := newTemplate(name, CertValidity)
+	if err != nil {
+		return err
+	}
+	template.ExtKeyUsage = []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth}
+	template.DNSNames = []string{AgentServerName}
+	for _, host := range hosts {
+		if ip := net.ParseIP(host); ip != nil {
+			template.IPAddresses = append(template.IPAddresses, ip)
+		} else {
+			template.DNSNames = append(template.DNSNames, host)
+		}
+	}
+
+	return issue(dir, name, template)
+}
+
+// IssueClient issues a client certificate; scope is recorded in the certificate's
+// organizational unit and decides what the client may do on agents
+func IssueClient(dir, name, scope string) error {
+	template, err := newTemplate(name, CertValidity)
+	if err != nil {
+		return err
+	}
+	template.ExtKeyUsage = []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth}
+	template.Subject.OrganizationalUnit = []string{scope}
+
+	return issue(dir, name, template)
+}
+
+// ServerTLSConfig loads a node certificate and, when caFile is set, requires clients
+// to present a certificate signed by that CA
+func ServerTLSConfig(certFile, keyFile, caFile string) (*tls.Config, error) {
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return nil, fmt.Errorf("error loading certificate: %v", err)
+	}
+
+	config := &tls.Config{Certificates: []tls.Certificate{cert}, MinVersion: tls.VersionTLS12}
+	if caFile != "" {
+		pool, err := loadPool(caFile)
+		if err != nil {
+			return nil, err
+		}
+		config.ClientCAs = pool
+		// Clients may still authenticate with a bearer token instead of a certificate
+		config.ClientAuth = tls.VerifyClientCertIfGiven
+	}
+	return config, nil
+}
+
+// ClientTLSConfig trusts agents signed by the CA in caFile and presents the client
+// certificate when one is given
+func ClientTLSConfig(caFile, certFile, keyFile string) (*tls.Config, error) {
+	pool, err := loadPool(caFile)
+	if err != nil {
+		return nil, err
+	}
+
+	config := &tls.Config{RootCAs: pool, ServerName: AgentServerName, MinVersion: tls.VersionTLS12}
+	if certFile != "" {
+		cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			return nil, fmt.Errorf("error loading client certificate: %v", err)
+		}
+		config.Certificates = []tls.Certificate{cert}
+	}
+	return config, nil
+}
+
+// issue signs template with the CA in dir and writes the certificate and key
+func issue(dir, name string, template *x509.Certificate) error {
+	caCert, caKey, err := loadCA(dir)
+	if err != nil {
+		return err
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return fmt.Errorf("error generating key: %v", err)
+	}
+	template.KeyUsage = x509.KeyUsageDigitalSignature
+
+	der, err := x509.CreateCertificate(rand.Reader, template, caCert, &key.PublicKey, caKey)
+	if err != nil {
+		return fmt.Errorf("error creating certificate for %s: %v", name, err)
+	}
+
+	certPath, keyPath := CertFile(dir, name)
+	return writePair(certPath, keyPath, der, key)
+}
+
+// newTemplate returns a certificate template with a random serial number
+func newTemplate(commonName string, validity time.Duration) (*x509.Certificate, error) {
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return nil, fmt.Errorf("error generating serial number: %v", err)
+	}
+
+	now := time.Now()
+	return &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{CommonName: commonName, Organization: []string{"Pulse"}},
+		NotBefore:    now.Add(-5 * time.Minute),
+		NotAfter:     now.Add(validity),
+	}, nil
+}
+
+// loadCA reads the CA certificate and key from dir
+func loadCA(dir string) (*x509.Certificate, *ecdsa.PrivateKey, error) {
+	pair, err := tls.LoadX509KeyPair(filepath.Join(dir, CAFile), filepath.Join(dir, CAKeyFile))
+	if err != nil {
+		return nil, nil, fmt.Errorf("error loading CA from %s (run `pulse certs ca` first): %v", dir, err)
+	}
+
+	cert, err := x509.ParseCertificate(pair.Certificate[0])
+	if err != nil {
+		return nil, nil, fmt.Errorf("error parsing CA certificate: %v", err)
+	}
+	key, ok := pair.PrivateKey.(*ecdsa.PrivateKey)
+	if !ok {
+		return nil, nil, errors.New("unsupported CA key type")
+	}
+	return cert, key, nil
+}
+
+// loadPool reads a PEM bundle into a certificate pool
+func loadPool(caFile string) (*x509.CertPool, error) {
+	data, err := os.ReadFile(caFile)
+	if err != nil {
+		return nil, fmt.Errorf("error reading CA: %v", err)
+	}
+
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(data) {
+		return nil, fmt.Errorf("no certificates found in %s", caFile)
+	}
+	return pool, nil
+}
+
+// writePair writes a certificate and its private key; the key is only readable by the owner
+func writePair(certPath, keyPath string, der []byte, key *ecdsa.PrivateKey) error {
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return fmt.Errorf("error encoding key: %v", err)
+	}
+
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	if err := os.WriteFile(certPath, certPEM, 0o644); err != nil {
+		return fmt.Errorf("error writing %s: %v", certPath, err)
+	}
+
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+	if err := os.WriteFile(keyPath, keyPEM, 0o600); err != nil {
+		return fmt.Errorf("error writing %s: %v", keyPath, err)
+	}
+	return nil
+}
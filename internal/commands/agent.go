@@ -0,0 +1,143 @@
+package commands
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os/signal"
+	"strconv"
+	"syscall"
+	"time"
+
+	"github.com/docker/docker/client"
+
+	"pulse/internal/agent"
+	"pulse/internal/auth"
+	"pulse/internal/certs"
+	"pulse/internal/config"
+	"pulse/internal/docker"
+)
+
+// runAgent serves this node's stack containers to Pulse instances on other nodes
+func runAgent(ctx context.Context, cli *client.Client, cfg config.Config, args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("agent", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	listen := fs.String("listen", net.JoinHostPort("", strconv.Itoa(cfg.AgentPort)), "Address to listen on")
+	token := fs.String("token", cfg.AgentToken, "Bearer token with the operate scope (default $PULSE_AGENT_TOKEN)")
+	tlsCert := fs.String("tls-cert", cfg.AgentTLS.Cert, "Node certificate to serve over TLS")
+	tlsKey := fs.String("tls-key", cfg.AgentTLS.Key, "Key of the node certificate")
+	tlsCA := fs.String("tls-ca", cfg.AgentTLS.CA, "CA that signs accepted client certificates")
+	if err := fs.Parse(args); err != nil || fs.NArg() > 0 {
+		fmt.Fprintln(stderr, "usage: pulse agent [-listen addr] [-token token] [-tls-cert file -tls-key file [-tls-ca file]]")
+		return ExitUsage
+	}
+
+	tokens, err := buildTokens("agent_tokens", *token, cfg.AgentTokens)
+	if err != nil {
+		fmt.Fprintf(stderr, "Error: %v\n", err)
+		return ExitUsage
+	}
+	if len(tokens) == 0 && *tlsCA == "" {
+		fmt.Fprintln(stderr, "Error: the agent requires a token or client certificates; set -token, agent_tokens or -tls-ca")
+		return ExitUsage
+	}
+
+	var tlsConfig *tls.Config
+	if *tlsCert != "" || *tlsKey != "" {
+		tlsConfig, err = certs.ServerTLSConfig(*tlsCert, *tlsKey, *tlsCA)
+		if err != nil {
+			fmt.Fprintf(stderr, "Error: %v\n", err)
+			return ExitError
+		}
+	} else if *tlsCA != "" {
+		fmt.Fprintln(stderr, "Error: -tls-ca requires -tls-cert and -tls-key")
+		return ExitUsage
+	}
+
+	node, err := docker.NodeName(ctx, cli)
+	if err != nil {
+		fmt.Fprintf(stderr, "Error: %v\n", err)
+		return ExitError
+	}
+
+	server := &http.Server{
+		Addr:              *listen,
+		Handler:           agent.NewServer(cli, node, tokens, cfg.LogTail).Handler(),
+		TLSConfig:         tlsConfig,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	return serveUntilSignal(ctx, stdout, stderr, fmt.Sprintf("Pulse agent for node %s listening on %s", node, *listen), server)
+}
+
+// buildTokens combines the single operate token with the scoped tokens from the
+// config file; key names the config setting in error messages
+func buildTokens(key, token string, configured []config.Token) ([]auth.Token, error) {
+	var tokens []auth.Token
+	if token != "" {
+		tokens = append(tokens, auth.Token{Name: "default", Value: token, Scope: auth.ScopeOperate})
+	}
+
+	for i, t := range configured {
+		if t.Token == "" {
+			return nil, fmt.Errorf("%s[%d] has no token", key, i)
+		}
+		scope, err := auth.ParseScope(t.Scope)
+		if err != nil {
+			return nil, fmt.Errorf("%s[%d]: %v", key, i, err)
+		}
+		name := t.Name
+		if name == "" {
+			name = fmt.Sprintf("token %d", i)
+		}
+		tokens = append(tokens, auth.Token{Name: name, Value: t.Token, Scope: scope})
+	}
+
+	return tokens, nil
+}
+
+// serveUntilSignal runs HTTP servers until SIGINT or SIGTERM, then shuts them down
+// gracefully; if any server fails the others are shut down too
+func serveUntilSignal(ctx context.Context, stdout, stderr io.Writer, banner string, servers ...*http.Server) int {
+	ctx, stop := signal.NotifyContext(ctx, syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	errCh := make(chan error, len(servers))
+	for _, server := range servers {
+		go func(server *http.Server) {
+			if server.TLSConfig != nil {
+				// The certificates are already loaded into TLSConfig
+				errCh <- server.ListenAndServeTLS("", "")
+			} else {
+				errCh <- server.ListenAndServe()
+			}
+		}(server)
+	}
+	fmt.Fprintln(stdout, banner)
+
+	code := ExitOK
+	select {
+	case err := <-errCh:
+		if !errors.Is(err, http.ErrServerClosed) {
+			fmt.Fprintf(stderr, "Error: %v\n", err)
+			code = ExitError
+		}
+	case <-ctx.Done():
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	for _, server := range servers {
+		if err := server.Shutdown(shutdownCtx); err != nil {
+			fmt.Fprintf(stderr, "Error: %v\n", err)
+			code = ExitError
+		}
+	}
+
+	return code
+}
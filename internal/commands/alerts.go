@@ -0,0 +1,101 @@
+package commands
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/docker/docker/client"
+
+	"pulse/internal/alerts"
+	"pulse/internal/config"
+)
+
+// runAlerts evaluates the alert rules periodically and watches logs for the log
+// triggers without the TUI, printing every transition and hit and sending it to the
+// configured notifiers
+func runAlerts(ctx context.Context, cli *client.Client, cfg config.Config, args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("alerts", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	interval := fs.Duration("interval", cfg.RefreshInterval, "Time between checks")
+	if err := fs.Parse(args); err != nil || fs.NArg() > 0 || *interval <= 0 {
+		fmt.Fprintln(stderr, "usage: pulse alerts [-interval duration]")
+		return ExitUsage
+	}
+
+	rules, err := alerts.NewRules(cfg.AlertRules)
+	if err != nil {
+		fmt.Fprintf(stderr, "Error: %v\n", err)
+		return ExitUsage
+	}
+	triggers, err := alerts.NewLogTriggers(cfg.LogTriggers)
+	if err != nil {
+		fmt.Fprintf(stderr, "Error: %v\n", err)
+		return ExitUsage
+	}
+	if len(rules) == 0 && len(triggers) == 0 {
+		fmt.Fprintln(stderr, "Error: no alert_rules or log_triggers in the config file")
+		return ExitUsage
+	}
+	notifiers, err := alerts.NewNotifiers(cfg.AlertNotifiers)
+	if err != nil {
+		fmt.Fprintf(stderr, "Error: %v\n", err)
+		return ExitUsage
+	}
+	engine := alerts.NewEngine(rules, notifiers)
+
+	ctx, stop := signal.NotifyContext(ctx, syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	hits := make(chan alerts.LogHit, 16)
+	if len(triggers) > 0 {
+		fmt.Fprintf(stdout, "Watching logs for %d log triggers\n", len(triggers))
+		go alerts.NewLogWatcher(cli, cfg, triggers).Run(ctx, hits)
+	}
+
+	// A nil channel never fires, so without rules only log hits are handled
+	var tick <-chan time.Time
+	if len(rules) > 0 {
+		fmt.Fprintf(stdout, "Checking %d alert rules every %s\n", len(rules), *interval)
+		ticker := time.NewTicker(*interval)
+		defer ticker.Stop()
+		tick = ticker.C
+		checkRules(ctx, cli, engine, *interval, stdout, stderr)
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ExitOK
+		case <-tick:
+			checkRules(ctx, cli, engine, *interval, stdout, stderr)
+		case hit := <-hits:
+			if hit.Throttled {
+				continue
+			}
+			alert := hit.Alert()
+			fmt.Fprintf(stdout, "%s %s\n", hit.Time.Format(time.RFC3339), alerts.Summary(alert))
+			if err := alerts.Notify(ctx, notifiers, []alerts.Alert{alert}); err != nil && ctx.Err() == nil {
+				fmt.Fprintf(stderr, "Error: %v\n", err)
+			}
+		}
+	}
+}
+
+// checkRules runs one check of the alert rules and prints the transitions
+func checkRules(ctx context.Context, cli *client.Client, engine *alerts.Engine, interval time.Duration, stdout, stderr io.Writer) {
+	checkCtx, cancel := context.WithTimeout(ctx, interval+30*time.Second)
+	defer cancel()
+
+	transitions, err := engine.Check(checkCtx, cli)
+	for _, alert := range transitions {
+		fmt.Fprintf(stdout, "%s %s\n", time.Now().Format(time.RFC3339), alerts.Summary(alert))
+	}
+	if err != nil && ctx.Err() == nil {
+		fmt.Fprintf(stderr, "Error: %v\n", err)
+	}
+}
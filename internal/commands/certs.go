@@ -0,0 +1,79 @@
+package commands
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/docker/docker/client"
+
+	"pulse/internal/auth"
+	"pulse/internal/certs"
+	"pulse/internal/config"
+)
+
+const certsUsage = `usage: pulse certs ca [-dir dir]
+       pulse certs node [-dir dir] [-hosts host,ip,...] <name>
+       pulse certs client [-dir dir] [-scope read|operate] <name>`
+
+// runCerts generates the CA and the node and client certificates used for agent mTLS
+func runCerts(ctx context.Context, cli *client.Client, cfg config.Config, args []string, stdout, stderr io.Writer) int {
+	if len(args) == 0 {
+		fmt.Fprintln(stderr, certsUsage)
+		return ExitUsage
+	}
+
+	fs := flag.NewFlagSet("certs "+args[0], flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	dir := fs.String("dir", config.DefaultCertsDir(), "Directory holding the CA and certificates")
+	hosts := fs.String("hosts", "", "Comma-separated host names and IPs the node certificate is valid for")
+	scope := fs.String("scope", string(auth.ScopeRead), "Scope granted to the client: read or operate")
+	if err := fs.Parse(args[1:]); err != nil {
+		fmt.Fprintln(stderr, certsUsage)
+		return ExitUsage
+	}
+
+	var err error
+	var written string
+	switch args[0] {
+	case "ca":
+		if fs.NArg() != 0 {
+			fmt.Fprintln(stderr, certsUsage)
+			return ExitUsage
+		}
+		err = certs.GenerateCA(*dir)
+		written = certs.CAFile
+	case "node", "client":
+		if fs.NArg() != 1 {
+			fmt.Fprintln(stderr, certsUsage)
+			return ExitUsage
+		}
+		name := fs.Arg(0)
+		if args[0] == "node" {
+			var hostList []string
+			if *hosts != "" {
+				hostList = strings.Split(*hosts, ",")
+			}
+			err = certs.IssueNode(*dir, name, hostList)
+		} else {
+			if _, scopeErr := auth.ParseScope(*scope); scopeErr != nil {
+				fmt.Fprintf(stderr, "Error: %v\n", scopeErr)
+				return ExitUsage
+			}
+			err = certs.IssueClient(*dir, name, *scope)
+		}
+		written = name + ".pem"
+	default:
+		fmt.Fprintln(stderr, certsUsage)
+		return ExitUsage
+	}
+
+	if err != nil {
+		fmt.Fprintf(stderr, "Error: %v\n", err)
+		return ExitError
+	}
+	fmt.Fprintf(stdout, "Wrote %s to %s\n", written, *dir)
+	return ExitOK
+}
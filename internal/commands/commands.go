@@ -0,0 +1,302 @@
+package commands
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"slices"
+	"sort"
+	"text/tabwriter"
+
+	"github.com/docker/docker/client"
+
+	"pulse/internal/config"
+	"pulse/internal/docker"
+)
+
+// Exit codes returned by Run
+const (
+	ExitOK       = 0
+	ExitError    = 1
+	ExitUsage    = 2
+	ExitNotFound = 3
+)
+
+// errNotFound is returned when the stack or container named on the command line does not exist
+var errNotFound = errors.New("not found")
+
+// command describes a headless subcommand
+type command struct {
+	usage string
+	args  int
+	run   func(ctx context.Context, cli *client.Client, cfg config.Config, args []string, p printer) error
+}
+
+var commands = map[string]command{
+	"stacks":     {usage: "stacks", args: 0, run: runStacks},
+	"stats":      {usage: "stats <stack>", args: 1, run: runStats},
+	"containers": {usage: "containers <stack>", args: 1, run: runContainers},
+	"inspect":    {usage: "inspect <container>", args: 1, run: runInspect},
+	"logs":       {usage: "logs <stack|container>", args: 1, run: runLogs},
+	"kill":       {usage: "kill <stack>", args: 1, run: runKill},
+	"restart":    {usage: "restart <stack>", args: 1, run: runRestart},
+}
+
+// services are long-running subcommands that parse their own flags
+var services = map[string]func(ctx context.Context, cli *client.Client, cfg config.Config, args []string, stdout, stderr io.Writer) int{
+	"agent":  runAgent,
+	"alerts": runAlerts,
+	"certs":  runCerts,
+	"serve":  runServe,
+	"web":    runWeb,
+}
+
+// Run executes a headless subcommand and returns the process exit code
+func Run(ctx context.Context, cli *client.Client, cfg config.Config, stdout, stderr io.Writer) int {
+	if service, ok := services[cfg.Command]; ok {
+		return service(ctx, cli, cfg, cfg.Args, stdout, stderr)
+	}
+
+	cmd, ok := commands[cfg.Command]
+	if !ok {
+		fmt.Fprintf(stderr, "unknown command %q\n", cfg.Command)
+		return ExitUsage
+	}
+
+	args, format, err := parseArgs(cfg.Args, cfg.Output)
+	if err != nil || len(args) != cmd.args {
+		fmt.Fprintf(stderr, "usage: pulse %s [--output table|json|yaml]\n", cmd.usage)
+		return ExitUsage
+	}
+	if !validFormat(format) {
+		fmt.Fprintf(stderr, "unsupported output format %q\n", format)
+		return ExitUsage
+	}
+
+	if err := cmd.run(ctx, cli, cfg, args, printer{out: stdout, format: format}); err != nil {
+		fmt.Fprintf(stderr, "Error: %v\n", err)
+		if errors.Is(err, errNotFound) {
+			return ExitNotFound
+		}
+		return ExitError
+	}
+	return ExitOK
+}
+
+// parseArgs separates positional arguments from an --output flag given after the
+// subcommand, so both "pulse --output json stacks" and "pulse stacks -o json" work
+func parseArgs(args []string, format string) ([]string, string, error) {
+	fs := flag.NewFlagSet("pulse", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	fs.StringVar(&format, "output", format, "")
+	fs.StringVar(&format, "o", format, "")
+
+	var positional []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, "", err
+		}
+		if fs.NArg() == 0 {
+			break
+		}
+		positional = append(positional, fs.Arg(0))
+		args = fs.Args()[1:]
+	}
+
+	return positional, format, nil
+}
+
+// runStacks prints every stack with its container counts
+func runStacks(ctx context.Context, cli *client.Client, cfg config.Config, args []string, p printer) error {
+	stacks, err := docker.ListStacks(ctx, cli)
+	if err != nil {
+		return err
+	}
+	stacks = cfg.VisibleStacks(stacks)
+	sort.Strings(stacks)
+
+	items := make([]docker.StackStats, 0, len(stacks))
+	for _, stack := range stacks {
+		stats, err := docker.GetStackStats(ctx, cli, stack)
+		if err != nil {
+			return err
+		}
+		items = append(items, stats)
+	}
+
+	return p.print("StackList", items, func(w *tabwriter.Writer) {
+		fmt.Fprintln(w, "STACK\tRUNNING\tSTOPPED\tOTHER")
+		for _, stats := range items {
+			fmt.Fprintf(w, "%s\t%d\t%d\t%d\n", stats.Stack, stats.Running, stats.Stopped, stats.Other)
+		}
+	})
+}
+
+// runStats prints the container counts of a single stack
+func runStats(ctx context.Context, cli *client.Client, cfg config.Config, args []string, p printer) error {
+	if err := requireStack(ctx, cli, cfg, args[0]); err != nil {
+		return err
+	}
+
+	stats, err := docker.GetStackStats(ctx, cli, args[0])
+	if err != nil {
+		return err
+	}
+
+	return p.print("StackStats", stats, func(w *tabwriter.Writer) {
+		fmt.Fprintf(w, "Stack:\t%s\n", stats.Stack)
+		fmt.Fprintf(w, "Running:\t%d\n", stats.Running)
+		fmt.Fprintf(w, "Stopped:\t%d\n", stats.Stopped)
+		fmt.Fprintf(w, "Other:\t%d\n", stats.Other)
+	})
+}
+
+// runContainers prints the containers of a stack
+func runContainers(ctx context.Context, cli *client.Client, cfg config.Config, args []string, p printer) error {
+	if err := requireStack(ctx, cli, cfg, args[0]); err != nil {
+		return err
+	}
+
+	containers, err := docker.ListContainers(ctx, cli, args[0])
+	if err != nil {
+		return err
+	}
+
+	items := make([]docker.ContainerSummary, 0, len(containers))
+	for _, c := range containers {
+		items = append(items, docker.SummarizeContainer(c))
+	}
+
+	return p.print("ContainerList", items, func(w *tabwriter.Writer) {
+		fmt.Fprintln(w, "ID\tNAME\tSTATE\tSTATUS\tIMAGE")
+		for _, c := range items {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", c.ID[:12], c.Name, c.State, c.Status, c.Image)
+		}
+	})
+}
+
+// runInspect prints the low-level details of a container
+func runInspect(ctx context.Context, cli *client.Client, cfg config.Config, args []string, p printer) error {
+	if err := requireContainer(ctx, cli, cfg, args[0]); err != nil {
+		return err
+	}
+
+	details, err := docker.InspectContainer(ctx, cli, args[0])
+	if err != nil {
+		return err
+	}
+
+	return p.print("ContainerInspect", details, func(w *tabwriter.Writer) {
+		fmt.Fprintf(w, "ID:\t%s\n", details.ID)
+		fmt.Fprintf(w, "Name:\t%s\n", details.Name)
+		fmt.Fprintf(w, "Image:\t%s\n", details.Config.Image)
+		fmt.Fprintf(w, "Created:\t%s\n", details.Created)
+		if details.State != nil {
+			fmt.Fprintf(w, "State:\t%s\n", details.State.Status)
+			fmt.Fprintf(w, "Started:\t%s\n", details.State.StartedAt)
+			if details.State.Health != nil {
+				fmt.Fprintf(w, "Health:\t%s\n", details.State.Health.Status)
+			}
+		}
+		fmt.Fprintf(w, "Restarts:\t%d\n", details.RestartCount)
+		labels := make([]string, 0, len(details.Config.Labels))
+		for key := range details.Config.Labels {
+			labels = append(labels, key)
+		}
+		sort.Strings(labels)
+		for _, key := range labels {
+			fmt.Fprintf(w, "Label:\t%s=%s\n", key, details.Config.Labels[key])
+		}
+	})
+}
+
+// runLogs prints the logs of a stack, or of a container when no stack has that name
+func runLogs(ctx context.Context, cli *client.Client, cfg config.Config, args []string, p printer) error {
+	name := args[0]
+
+	stacks, err := docker.ListStacks(ctx, cli)
+	if err != nil {
+		return err
+	}
+
+	var logs string
+	if slices.Contains(cfg.VisibleStacks(stacks), name) {
+		logs, err = docker.ViewStackLogs(ctx, cli, name, cfg.LogTail)
+	} else {
+		if err := requireContainer(ctx, cli, cfg, name); err != nil {
+			if errors.Is(err, errNotFound) {
+				return fmt.Errorf("no stack or container named %s: %w", name, errNotFound)
+			}
+			return err
+		}
+		logs, err = docker.ViewContainerLogs(ctx, cli, name, cfg.LogTail)
+	}
+	if err != nil {
+		return err
+	}
+
+	_, err = io.WriteString(p.out, logs)
+	return err
+}
+
+// runKill removes all services of a stack
+func runKill(ctx context.Context, cli *client.Client, cfg config.Config, args []string, p printer) error {
+	if err := requireStack(ctx, cli, cfg, args[0]); err != nil {
+		return err
+	}
+	if err := docker.KillStack(ctx, cli, args[0]); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(p.out, "Stack %s killed\n", args[0])
+	return nil
+}
+
+// runRestart forces a rolling restart of a stack's services
+func runRestart(ctx context.Context, cli *client.Client, cfg config.Config, args []string, p printer) error {
+	if err := requireStack(ctx, cli, cfg, args[0]); err != nil {
+		return err
+	}
+	if err := docker.RestartStack(ctx, cli, args[0]); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(p.out, "Stack %s restarted\n", args[0])
+	return nil
+}
+
+// requireStack returns errNotFound when the named stack does not exist or is hidden
+// by hidden_stacks, like in the TUI and the web UI
+func requireStack(ctx context.Context, cli *client.Client, cfg config.Config, name string) error {
+	stacks, err := docker.ListStacks(ctx, cli)
+	if err != nil {
+		return err
+	}
+	if !slices.Contains(cfg.VisibleStacks(stacks), name) {
+		return fmt.Errorf("stack %s: %w", name, errNotFound)
+	}
+	return nil
+}
+
+// requireContainer returns errNotFound when the named container does not exist or
+// belongs to a hidden stack
+func requireContainer(ctx context.Context, cli *client.Client, cfg config.Config, name string) error {
+	exists, err := docker.ContainerExists(ctx, cli, name)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return fmt.Errorf("container %s: %w", name, errNotFound)
+	}
+
+	details, err := docker.InspectContainer(ctx, cli, name)
+	if err != nil {
+		return err
+	}
+	if details.Config != nil && cfg.IsHidden(details.Config.Labels["com.docker.stack.namespace"]) {
+		return fmt.Errorf("container %s: %w", name, errNotFound)
+	}
+	return nil
+}
@@ -0,0 +1,73 @@
+package commands
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"text/tabwriter"
+
+	"gopkg.in/yaml.v3"
+
+	"pulse/internal/docker"
+)
+
+// Output formats accepted by --output
+const (
+	FormatTable = "table"
+	FormatJSON  = "json"
+	FormatYAML  = "yaml"
+)
+
+// document is the envelope wrapped around every machine-readable result
+type document struct {
+	SchemaVersion string `json:"schemaVersion"`
+	Kind          string `json:"kind"`
+	Items         any    `json:"items"`
+}
+
+// printer writes command results in the requested output format
+type printer struct {
+	out    io.Writer
+	format string
+}
+
+// validFormat reports whether format is a supported output format
+func validFormat(format string) bool {
+	return format == FormatTable || format == FormatJSON || format == FormatYAML
+}
+
+// print writes items as a versioned JSON or YAML document, or calls table to
+// render them for humans
+func (p printer) print(kind string, items any, table func(w *tabwriter.Writer)) error {
+	doc := document{SchemaVersion: docker.SchemaVersion, Kind: kind, Items: items}
+
+	switch p.format {
+	case FormatJSON:
+		enc := json.NewEncoder(p.out)
+		enc.SetIndent("", "  ")
+		return enc.Encode(doc)
+	case FormatYAML:
+		// Round-trip through JSON so YAML keys match the JSON schema, including
+		// for Docker API types that only carry json tags
+		raw, err := json.Marshal(doc)
+		if err != nil {
+			return err
+		}
+		var generic any
+		if err := json.Unmarshal(raw, &generic); err != nil {
+			return err
+		}
+		enc := yaml.NewEncoder(p.out)
+		enc.SetIndent(2)
+		if err := enc.Encode(generic); err != nil {
+			return err
+		}
+		return enc.Close()
+	case FormatTable:
+		w := tabwriter.NewWriter(p.out, 0, 4, 2, ' ', 0)
+		table(w)
+		return w.Flush()
+	}
+
+	return fmt.Errorf("unsupported output format %q", p.format)
+}
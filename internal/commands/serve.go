@@ -0,0 +1,99 @@
+package commands
+
+import (
+	"context"
+	"crypto/tls"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/docker/docker/client"
+
+	"pulse/internal/api"
+	"pulse/internal/certs"
+	"pulse/internal/config"
+	"pulse/internal/docker"
+	"pulse/internal/metrics"
+)
+
+const serveUsage = "usage: pulse serve [-metrics-addr addr] [-api [-api-addr addr] [-token token] [-tls-cert file -tls-key file [-tls-ca file]]]"
+
+// runServe exposes Pulse's data over HTTP for other tools to consume: Prometheus
+// metrics and, with -api, the REST API
+func runServe(ctx context.Context, cli *client.Client, cfg config.Config, args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("serve", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	metricsAddr := fs.String("metrics-addr", ":9323", "Address to serve Prometheus metrics on at /metrics, empty to disable")
+	enableAPI := fs.Bool("api", false, "Serve the REST API at /api/v1")
+	apiAddr := fs.String("api-addr", ":9326", "Address to serve the REST API on")
+	token := fs.String("token", cfg.APIToken, "API bearer token with the operate scope (default $PULSE_API_TOKEN)")
+	tlsCert := fs.String("tls-cert", "", "Certificate to serve the API over TLS")
+	tlsKey := fs.String("tls-key", "", "Key of the API certificate")
+	tlsCA := fs.String("tls-ca", "", "CA that signs accepted API client certificates")
+	if err := fs.Parse(args); err != nil || fs.NArg() > 0 {
+		fmt.Fprintln(stderr, serveUsage)
+		return ExitUsage
+	}
+	if *metricsAddr == "" && !*enableAPI {
+		fmt.Fprintln(stderr, "Error: nothing to serve; set -metrics-addr or -api")
+		return ExitUsage
+	}
+
+	var servers []*http.Server
+	var banner []string
+
+	if *metricsAddr != "" {
+		node, err := docker.NodeName(ctx, cli)
+		if err != nil {
+			fmt.Fprintf(stderr, "Error: %v\n", err)
+			return ExitError
+		}
+
+		mux := http.NewServeMux()
+		mux.Handle("GET /metrics", metrics.NewCollector(cli, node, stderr).Handler())
+		servers = append(servers, &http.Server{Addr: *metricsAddr, Handler: mux, ReadHeaderTimeout: 10 * time.Second})
+		banner = append(banner, fmt.Sprintf("Serving metrics for node %s on %s/metrics", node, *metricsAddr))
+	}
+
+	if *enableAPI {
+		tokens, err := buildTokens("api_tokens", *token, cfg.APITokens)
+		if err != nil {
+			fmt.Fprintf(stderr, "Error: %v\n", err)
+			return ExitUsage
+		}
+		if len(tokens) == 0 && *tlsCA == "" {
+			fmt.Fprintln(stderr, "Error: the API requires a token or client certificates; set -token, api_token, api_tokens or -tls-ca")
+			return ExitUsage
+		}
+
+		var tlsConfig *tls.Config
+		if *tlsCert != "" || *tlsKey != "" {
+			tlsConfig, err = certs.ServerTLSConfig(*tlsCert, *tlsKey, *tlsCA)
+			if err != nil {
+				fmt.Fprintf(stderr, "Error: %v\n", err)
+				return ExitError
+			}
+		} else if *tlsCA != "" {
+			fmt.Fprintln(stderr, "Error: -tls-ca requires -tls-cert and -tls-key")
+			return ExitUsage
+		}
+
+		if *apiAddr == *metricsAddr {
+			fmt.Fprintln(stderr, "Error: -api-addr and -metrics-addr must differ")
+			return ExitUsage
+		}
+
+		servers = append(servers, &http.Server{
+			Addr:              *apiAddr,
+			Handler:           api.NewServer(cli, cfg, tokens).Handler(),
+			TLSConfig:         tlsConfig,
+			ReadHeaderTimeout: 10 * time.Second,
+		})
+		banner = append(banner, fmt.Sprintf("Serving the API on %s/api/v1 (OpenAPI document at /api/v1/openapi.yaml)", *apiAddr))
+	}
+
+	return serveUntilSignal(ctx, stdout, stderr, strings.Join(banner, "\n"), servers...)
+}
@@ -0,0 +1,86 @@
+package commands
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/docker/docker/client"
+
+	"pulse/internal/auth"
+	"pulse/internal/config"
+	"pulse/internal/web"
+)
+
+// runWeb serves the web dashboard
+func runWeb(ctx context.Context, cli *client.Client, cfg config.Config, args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("web", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	listen := fs.String("listen", "127.0.0.1:9325", "Address to serve the dashboard on")
+	readOnly := fs.Bool("read-only", false, "Hide and refuse restart and kill")
+	token := fs.String("token", cfg.APIToken, "Bearer token for restart and kill (default $PULSE_API_TOKEN, or a random one)")
+	if err := fs.Parse(args); err != nil || fs.NArg() > 0 {
+		fmt.Fprintln(stderr, "usage: pulse web [-listen addr] [-read-only] [-token token]")
+		return ExitUsage
+	}
+
+	tokens, err := buildTokens("api_tokens", *token, cfg.APITokens)
+	if err != nil {
+		fmt.Fprintf(stderr, "Error: %v\n", err)
+		return ExitUsage
+	}
+
+	// Viewing needs a token as well once tokens are configured or the dashboard can be
+	// reached from other machines, since logs often carry secrets
+	readAuth := len(tokens) > 0 || !loopback(*listen)
+
+	// Without a configured token, the banner's link hands a fresh one to the browser in
+	// the URL fragment, which never reaches the server logs
+	url := "http://" + *listen
+	if len(tokens) == 0 {
+		generated, err := randomToken()
+		if err != nil {
+			fmt.Fprintf(stderr, "Error: %v\n", err)
+			return ExitError
+		}
+		tokens = []auth.Token{{Name: "web", Value: generated, Scope: auth.ScopeOperate}}
+		url += "/#token=" + generated
+	}
+
+	server := &http.Server{
+		Addr:              *listen,
+		Handler:           web.NewServer(cli, cfg, *readOnly, readAuth, *listen, tokens).Handler(),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	mode := ""
+	if *readOnly {
+		mode = " (read-only)"
+	}
+	return serveUntilSignal(ctx, stdout, stderr, fmt.Sprintf("Pulse web UI%s on %s", mode, url), server)
+}
+
+// loopback reports whether listen only accepts connections from this machine
+func loopback(listen string) bool {
+	host, _, err := net.SplitHostPort(listen)
+	if err != nil {
+		return false
+	}
+	ip := net.ParseIP(host)
+	return host == "localhost" || ip != nil && ip.IsLoopback()
+}
+
+// randomToken returns 128 random bits, hex encoded
+func randomToken() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("error generating a token: %v", err)
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -1,20 +1,486 @@
 package config
 
 import (
+	"errors"
 	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Defaults used when neither the config file nor the command line set a value
+const (
+	DefaultRefreshInterval = 5 * time.Second
+	DefaultLogTail         = 100
+	DefaultTheme           = "dark"
+	DefaultAgentPort       = 9324
+	DefaultSampleInterval  = 15 * time.Second
+	DefaultRetention       = 24 * time.Hour
+	DefaultGraphWindow     = 15 * time.Minute
+	DefaultStackSort       = "name"
+	DefaultKeymap          = "default"
 )
 
 // Config holds application configuration
 type Config struct {
-	Debug bool
+	Debug  bool
+	Output string
+
+	// Settings that can come from the config file, a profile or flags
+	Profile         string
+	Context         string
+	DockerHost      string
+	Hosts           map[string]string
+	RefreshInterval time.Duration
+	LogTail         int
+	Theme           string
+	Themes          map[string]Palette
+	Keymap          string
+	Keybindings     map[string]string
+	HiddenStacks    []string
+
+	// Agents running on other swarm nodes
+	Agents       []string
+	AgentService string
+	AgentPort    int
+	AgentToken   string
+	AgentTokens  []Token
+	AgentTLS     TLSFiles
+
+	// REST API served by `pulse serve -api`
+	APIToken  string
+	APITokens []Token
+
+	// Alert rules evaluated on every refresh, and where firing alerts are sent
+	AlertRules     []AlertRule
+	AlertNotifiers []AlertNotifier
+	LogTriggers    []LogTrigger
+
+	// How far back the event timeline is filled from the daemon's event history
+	EventsSince time.Duration
+
+	// Resource sampling and the on-disk history of events, exits and samples
+	History History
+
+	// Time span of the resource sparklines and graphs
+	GraphWindow time.Duration
+
+	// Initial order of the stack list and the columns of the container list
+	StackSort        StackSort
+	ContainerColumns []string
+
+	// Command is the headless subcommand to run, empty to launch the TUI
+	Command string
+	Args    []string
 }
 
-// ParseFlags parses command line flags and returns config
-func ParseFlags() Config {
+// settings mirrors the config file; pointers distinguish unset values from zero values
+type settings struct {
+	Context          *string            `yaml:"context"`
+	DockerHost       *string            `yaml:"docker_host"`
+	Hosts            map[string]string  `yaml:"hosts"`
+	RefreshInterval  *time.Duration     `yaml:"refresh_interval"`
+	LogTail          *int               `yaml:"log_tail"`
+	Theme            *string            `yaml:"theme"`
+	Themes           map[string]Palette `yaml:"themes"`
+	Keymap           *string            `yaml:"keymap"`
+	Keybindings      map[string]string  `yaml:"keybindings"`
+	HiddenStacks     []string           `yaml:"hidden_stacks"`
+	Agents           []string           `yaml:"agents"`
+	AgentService     *string            `yaml:"agent_service"`
+	AgentPort        *int               `yaml:"agent_port"`
+	AgentToken       *string            `yaml:"agent_token"`
+	AgentTokens      []Token            `yaml:"agent_tokens"`
+	AgentTLS         *TLSFiles          `yaml:"agent_tls"`
+	APIToken         *string            `yaml:"api_token"`
+	APITokens        []Token            `yaml:"api_tokens"`
+	AlertRules       []AlertRule        `yaml:"alert_rules"`
+	AlertNotifiers   []AlertNotifier    `yaml:"alert_notifiers"`
+	LogTriggers      []LogTrigger       `yaml:"log_triggers"`
+	EventsSince      *time.Duration     `yaml:"events_since"`
+	History          *History           `yaml:"history"`
+	GraphWindow      *time.Duration     `yaml:"graph_window"`
+	StackSort        *StackSort         `yaml:"stack_sort"`
+	ContainerColumns []string           `yaml:"container_columns"`
+}
+
+// History configures how often container resources are sampled and whether events,
+// container exits and samples are kept on disk, and for how long
+type History struct {
+	Enabled        bool          `yaml:"enabled"`
+	Dir            string        `yaml:"dir"`
+	Retention      time.Duration `yaml:"retention"`
+	SampleInterval time.Duration `yaml:"sample_interval"`
+}
+
+// Palette is a custom colour theme. Colours are hex values like "#FF5F87" or ANSI
+// colour numbers like "205"; colours left out are taken from the Base theme.
+type Palette struct {
+	Base       string `yaml:"base"`
+	Primary    string `yaml:"primary"`
+	Secondary  string `yaml:"secondary"`
+	Accent     string `yaml:"accent"`
+	Success    string `yaml:"success"`
+	Danger     string `yaml:"danger"`
+	Warning    string `yaml:"warning"`
+	Background string `yaml:"background"`
+	Text       string `yaml:"text"`
+	Subtext    string `yaml:"subtext"`
+	Highlight  string `yaml:"highlight"`
+}
+
+// StackSort orders the stack list by Key: name, running, cpu, memory, health or age
+type StackSort struct {
+	Key        string `yaml:"key"`
+	Descending bool   `yaml:"descending"`
+}
+
+// AlertRule is an alert condition; Stack and Service narrow it down, and the
+// meaning of Threshold and Window depends on the condition
+type AlertRule struct {
+	Name      string        `yaml:"name"`
+	Condition string        `yaml:"condition"`
+	Stack     string        `yaml:"stack"`
+	Service   string        `yaml:"service"`
+	For       time.Duration `yaml:"for"`
+	Threshold float64       `yaml:"threshold"`
+	Window    time.Duration `yaml:"window"`
+}
+
+// LogTrigger raises an alert when a log line of a stack or service matches Pattern,
+// a regular expression; Context is the number of surrounding lines to include
+type LogTrigger struct {
+	Name     string        `yaml:"name"`
+	Pattern  string        `yaml:"pattern"`
+	Stack    string        `yaml:"stack"`
+	Service  string        `yaml:"service"`
+	Context  *int          `yaml:"context"`
+	Cooldown time.Duration `yaml:"cooldown"`
+}
+
+// AlertNotifier is a destination for alerts: a JSON webhook, a Slack-compatible
+// webhook or a shell command
+type AlertNotifier struct {
+	Type    string   `yaml:"type"`
+	URL     string   `yaml:"url"`
+	Command []string `yaml:"command"`
+}
+
+// Token is a named bearer token accepted by the agent or the API, limited to a scope
+type Token struct {
+	Name  string `yaml:"name"`
+	Token string `yaml:"token"`
+	Scope string `yaml:"scope"`
+}
+
+// TLSFiles locates the CA, certificate and key used for mutual TLS with agents. On an
+// agent node they are its server certificate; elsewhere they are the client certificate.
+type TLSFiles struct {
+	CA   string `yaml:"ca"`
+	Cert string `yaml:"cert"`
+	Key  string `yaml:"key"`
+}
+
+// Enabled reports whether TLS is configured
+func (t TLSFiles) Enabled() bool {
+	return t.CA != ""
+}
+
+// file is the layout of config.yaml: top-level settings plus named profiles
+type file struct {
+	settings `yaml:",inline"`
+	Profiles map[string]settings `yaml:"profiles"`
+}
+
+// IsHidden reports whether a stack is listed in hidden_stacks
+func (c Config) IsHidden(stack string) bool {
+	return slices.Contains(c.HiddenStacks, stack)
+}
+
+// VisibleStacks drops the hidden stacks; the TUI, headless commands and web UI all
+// use it so they agree on which stacks exist
+func (c Config) VisibleStacks(stacks []string) []string {
+	visible := make([]string, 0, len(stacks))
+	for _, stack := range stacks {
+		if !c.IsHidden(stack) {
+			visible = append(visible, stack)
+		}
+	}
+	return visible
+}
+
+// ParseFlags parses command line flags, merges them over the config file and returns config
+func ParseFlags() (Config, error) {
+	flag.Usage = usage
 	debug := flag.Bool("debug", false, "Enable debug mode")
+	output := flag.String("output", "table", "Output format for commands: table, json or yaml")
+	flag.StringVar(output, "o", "table", "Shorthand for -output")
+	configPath := flag.String("config", DefaultPath(), "Path to the config file")
+	profile := flag.String("profile", "", "Named profile from the config file to use")
+	dockerContext := flag.String("context", "", "Docker context or configured host to connect to")
+	host := flag.String("host", "", "Docker daemon host, e.g. unix:///var/run/docker.sock")
+	refresh := flag.Duration("refresh", DefaultRefreshInterval, "Interval between automatic refreshes, 0 to disable")
+	tail := flag.Int("tail", DefaultLogTail, "Number of log lines to show")
+	theme := flag.String("theme", DefaultTheme, "Colour theme: dark, light, high-contrast, monochrome or one from themes")
+	eventsSince := flag.Duration("events-since", 0, "Fill the event timeline with events from this long ago")
 	flag.Parse()
 
-	return Config{
-		Debug: *debug,
+	cfg := Config{
+		Debug:           *debug,
+		Output:          *output,
+		Profile:         *profile,
+		RefreshInterval: DefaultRefreshInterval,
+		LogTail:         DefaultLogTail,
+		Theme:           DefaultTheme,
+		Keymap:          DefaultKeymap,
+		AgentPort:       DefaultAgentPort,
+		GraphWindow:     DefaultGraphWindow,
+		StackSort:       StackSort{Key: DefaultStackSort},
+		History: History{
+			Dir:            DefaultHistoryDir(),
+			Retention:      DefaultRetention,
+			SampleInterval: DefaultSampleInterval,
+		},
+		AgentToken: os.Getenv("PULSE_AGENT_TOKEN"),
+		APIToken:   os.Getenv("PULSE_API_TOKEN"),
+	}
+
+	if err := cfg.load(*configPath); err != nil {
+		return cfg, err
+	}
+
+	// Flags given explicitly on the command line win over the config file
+	flag.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "context":
+			cfg.Context = *dockerContext
+		case "host":
+			cfg.DockerHost = *host
+		case "refresh":
+			cfg.RefreshInterval = *refresh
+		case "tail":
+			cfg.LogTail = *tail
+		case "theme":
+			cfg.Theme = *theme
+		case "events-since":
+			cfg.EventsSince = *eventsSince
+		}
+	})
+
+	if flag.NArg() > 0 {
+		cfg.Command = flag.Arg(0)
+		cfg.Args = flag.Args()[1:]
+	}
+
+	return cfg, nil
+}
+
+// DefaultPath returns the config file location under XDG_CONFIG_HOME
+func DefaultPath() string {
+	dir := os.Getenv("XDG_CONFIG_HOME")
+	if dir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return ""
+		}
+		dir = filepath.Join(home, ".config")
 	}
+	return filepath.Join(dir, "pulse", "config.yaml")
+}
+
+// DefaultCertsDir returns the directory `pulse certs` writes to, next to the config file
+func DefaultCertsDir() string {
+	path := DefaultPath()
+	if path == "" {
+		return "certs"
+	}
+	return filepath.Join(filepath.Dir(path), "certs")
+}
+
+// DefaultHistoryDir returns the directory the history is kept in, under XDG_STATE_HOME
+func DefaultHistoryDir() string {
+	dir := os.Getenv("XDG_STATE_HOME")
+	if dir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "history"
+		}
+		dir = filepath.Join(home, ".local", "state")
+	}
+	return filepath.Join(dir, "pulse", "history")
+}
+
+// load applies the config file and the selected profile; a missing file is not an error
+func (c *Config) load(path string) error {
+	if path == "" {
+		return nil
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		if c.Profile != "" {
+			return fmt.Errorf("profile %q requested but no config file at %s", c.Profile, path)
+		}
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("error reading config file: %v", err)
+	}
+
+	var f file
+	if err := yaml.Unmarshal(data, &f); err != nil {
+		return fmt.Errorf("error parsing config file %s: %v", path, err)
+	}
+
+	c.apply(f.settings)
+	if c.Profile != "" {
+		profile, ok := f.Profiles[c.Profile]
+		if !ok {
+			return fmt.Errorf("profile %q not found in %s", c.Profile, path)
+		}
+		c.apply(profile)
+	}
+
+	return nil
+}
+
+// apply overlays the values set in s onto the config
+func (c *Config) apply(s settings) {
+	if s.Context != nil {
+		c.Context = *s.Context
+	}
+	if s.DockerHost != nil {
+		c.DockerHost = *s.DockerHost
+	}
+	if s.Hosts != nil {
+		if c.Hosts == nil {
+			c.Hosts = make(map[string]string)
+		}
+		for name, host := range s.Hosts {
+			c.Hosts[name] = host
+		}
+	}
+	if s.RefreshInterval != nil {
+		c.RefreshInterval = *s.RefreshInterval
+	}
+	if s.LogTail != nil {
+		c.LogTail = *s.LogTail
+	}
+	if s.Theme != nil {
+		c.Theme = *s.Theme
+	}
+	if s.Themes != nil {
+		if c.Themes == nil {
+			c.Themes = make(map[string]Palette)
+		}
+		for name, palette := range s.Themes {
+			c.Themes[name] = palette
+		}
+	}
+	if s.Keymap != nil {
+		c.Keymap = *s.Keymap
+	}
+	if s.Keybindings != nil {
+		if c.Keybindings == nil {
+			c.Keybindings = make(map[string]string)
+		}
+		for action, key := range s.Keybindings {
+			c.Keybindings[action] = key
+		}
+	}
+	if s.HiddenStacks != nil {
+		c.HiddenStacks = s.HiddenStacks
+	}
+	if s.Agents != nil {
+		c.Agents = s.Agents
+	}
+	if s.AgentService != nil {
+		c.AgentService = *s.AgentService
+	}
+	if s.AgentPort != nil {
+		c.AgentPort = *s.AgentPort
+	}
+	if s.AgentToken != nil {
+		c.AgentToken = *s.AgentToken
+	}
+	if s.AgentTokens != nil {
+		c.AgentTokens = s.AgentTokens
+	}
+	if s.AgentTLS != nil {
+		c.AgentTLS = *s.AgentTLS
+	}
+	if s.APIToken != nil {
+		c.APIToken = *s.APIToken
+	}
+	if s.APITokens != nil {
+		c.APITokens = s.APITokens
+	}
+	if s.AlertRules != nil {
+		c.AlertRules = s.AlertRules
+	}
+	if s.AlertNotifiers != nil {
+		c.AlertNotifiers = s.AlertNotifiers
+	}
+	if s.LogTriggers != nil {
+		c.LogTriggers = s.LogTriggers
+	}
+	if s.EventsSince != nil {
+		c.EventsSince = *s.EventsSince
+	}
+	if s.GraphWindow != nil && *s.GraphWindow > 0 {
+		c.GraphWindow = *s.GraphWindow
+	}
+	if s.StackSort != nil {
+		c.StackSort = *s.StackSort
+		if c.StackSort.Key == "" {
+			c.StackSort.Key = DefaultStackSort
+		}
+	}
+	if s.ContainerColumns != nil {
+		c.ContainerColumns = s.ContainerColumns
+	}
+	if s.History != nil {
+		c.History = *s.History
+		if c.History.Dir == "" {
+			c.History.Dir = DefaultHistoryDir()
+		}
+		if c.History.Retention <= 0 {
+			c.History.Retention = DefaultRetention
+		}
+		if c.History.SampleInterval <= 0 {
+			c.History.SampleInterval = DefaultSampleInterval
+		}
+	}
+}
+
+// HasAgents reports whether any agents are configured or discoverable
+func (c Config) HasAgents() bool {
+	return len(c.Agents) > 0 || c.AgentService != ""
+}
+
+// usage prints the help text for the TUI and the headless subcommands
+func usage() {
+	out := flag.CommandLine.Output()
+	fmt.Fprintf(out, "Usage: %s [flags] [command] [args]\n\n", os.Args[0])
+	fmt.Fprintln(out, "Without a command Pulse starts the interactive TUI.")
+	fmt.Fprintln(out, "\nCommands:")
+	fmt.Fprintln(out, "  stacks                      List stacks")
+	fmt.Fprintln(out, "  stats <stack>               Show container counts for a stack")
+	fmt.Fprintln(out, "  containers <stack>          List containers in a stack")
+	fmt.Fprintln(out, "  inspect <container>         Show low-level details of a container")
+	fmt.Fprintln(out, "  logs <stack|container>      Print logs for a stack or a container")
+	fmt.Fprintln(out, "  kill <stack>                Remove all services of a stack")
+	fmt.Fprintln(out, "  restart <stack>             Force a rolling restart of a stack's services")
+	fmt.Fprintln(out, "  agent [-listen addr]        Serve this node's containers to other Pulse instances")
+	fmt.Fprintln(out, "  alerts [-interval duration] Check alert_rules and log_triggers and send notifications")
+	fmt.Fprintln(out, "  certs <ca|node|client>      Generate a CA and certificates for agent mTLS")
+	fmt.Fprintln(out, "  serve [-metrics-addr addr]  Serve Prometheus metrics at /metrics")
+	fmt.Fprintln(out, "        [-api]               and the REST API at /api/v1")
+	fmt.Fprintln(out, "  web [-listen addr]          Serve the web dashboard")
+	fmt.Fprintln(out, "\nFlags:")
+	flag.PrintDefaults()
 }
@@ -1,10 +1,43 @@
 package docker
 
 import (
+	"fmt"
+	"net/http"
+	"os"
+
+	"github.com/docker/cli/cli/connhelper"
 	"github.com/docker/docker/client"
 )
 
-// NewClient creates a new Docker client
-func NewClient() (*client.Client, error) {
-	return client.NewClientWithOpts(client.FromEnv, client.WithAPIVersionNegotiation())
+// NewClient creates a new Docker client; host overrides DOCKER_HOST when set
+func NewClient(host string) (*client.Client, error) {
+	if host == "" {
+		host = os.Getenv("DOCKER_HOST")
+	}
+	opts := []client.Opt{client.FromEnv, client.WithAPIVersionNegotiation()}
+	if host != "" {
+		hostOpts, err := connectOpts(host)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, hostOpts...)
+	}
+	return client.NewClientWithOpts(opts...)
+}
+
+// connectOpts returns the options reaching the daemon at host. ssh:// hosts are
+// reached by running "docker system dial-stdio" over ssh, as with the Docker CLI.
+func connectOpts(host string) ([]client.Opt, error) {
+	helper, err := connhelper.GetConnectionHelper(host)
+	if err != nil {
+		return nil, fmt.Errorf("error connecting to %s: %v", host, err)
+	}
+	if helper == nil {
+		return []client.Opt{client.WithHost(host)}, nil
+	}
+	return []client.Opt{
+		client.WithHTTPClient(&http.Client{Transport: &http.Transport{DialContext: helper.Dialer}}),
+		client.WithHost(helper.Host),
+		client.WithDialContext(helper.Dialer),
+	}, nil
 }
@@ -0,0 +1,28 @@
+package docker
+
+import "testing"
+
+func TestNewClientHosts(t *testing.T) {
+	tests := []struct {
+		host string
+		want string // daemon the client sends requests to
+	}{
+		{host: "unix:///var/run/docker.sock", want: "unix:///var/run/docker.sock"},
+		{host: "tcp://10.0.0.5:2376", want: "tcp://10.0.0.5:2376"},
+		{host: "ssh://deploy@swarm.example.com", want: "http://docker.example.com"},
+		{host: "ssh://deploy@swarm.example.com:2222/run/docker.sock", want: "http://docker.example.com"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.host, func(t *testing.T) {
+			cli, err := NewClient(tt.host)
+			if err != nil {
+				t.Fatalf("NewClient(%q) error = %v", tt.host, err)
+			}
+			defer cli.Close()
+			if got := cli.DaemonHost(); got != tt.want {
+				t.Errorf("DaemonHost() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,217 @@
+package docker
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/docker/docker/client"
+)
+
+// Context is a Docker daemon Pulse can connect to
+type Context struct {
+	Name        string
+	Host        string
+	Description string
+	Source      string // "docker" for Docker CLI contexts, "pulse" for hosts from the config file
+	TLSDir      string // directory holding ca.pem, cert.pem and key.pem, if any
+	// The daemon is reached over TLS without checking its certificate
+	SkipTLSVerify bool
+}
+
+// contextMeta is the layout of a Docker CLI context's meta.json
+type contextMeta struct {
+	Name     string
+	Metadata struct {
+		Description string
+	}
+	Endpoints map[string]struct {
+		Host          string
+		SkipTLSVerify bool
+	}
+}
+
+// DefaultContext is the daemon selected by the environment, as with the Docker CLI
+var DefaultContext = Context{
+	Name:        "default",
+	Description: "Current DOCKER_HOST based configuration",
+	Source:      "docker",
+}
+
+// ListContexts returns the default context, the Docker CLI contexts and the hosts
+// defined in Pulse's config file
+func ListContexts(hosts map[string]string) ([]Context, error) {
+	contexts := []Context{DefaultContext}
+
+	cliContexts, err := dockerCLIContexts()
+	if err != nil {
+		return nil, err
+	}
+	contexts = append(contexts, cliContexts...)
+
+	names := make([]string, 0, len(hosts))
+	for name := range hosts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		contexts = append(contexts, Context{Name: name, Host: hosts[name], Source: "pulse"})
+	}
+
+	return contexts, nil
+}
+
+// dockerConfigDir returns the Docker CLI's configuration directory, ~/.docker unless
+// DOCKER_CONFIG says otherwise, or "" when there is no home directory
+func dockerConfigDir() string {
+	if dir := os.Getenv("DOCKER_CONFIG"); dir != "" {
+		return dir
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".docker")
+}
+
+// CurrentContext returns the context the Docker CLI would use: none when DOCKER_HOST
+// is set, otherwise DOCKER_CONTEXT or the currentContext of its config file
+func CurrentContext() string {
+	if os.Getenv("DOCKER_HOST") != "" {
+		return ""
+	}
+	if name := os.Getenv("DOCKER_CONTEXT"); name != "" {
+		return name
+	}
+
+	configDir := dockerConfigDir()
+	if configDir == "" {
+		return ""
+	}
+	data, err := os.ReadFile(filepath.Join(configDir, "config.json"))
+	if err != nil {
+		return ""
+	}
+	var config struct {
+		CurrentContext string `json:"currentContext"`
+	}
+	if err := json.Unmarshal(data, &config); err != nil {
+		return ""
+	}
+	return config.CurrentContext
+}
+
+// dockerCLIContexts reads the contexts stored by the Docker CLI under ~/.docker/contexts
+func dockerCLIContexts() ([]Context, error) {
+	configDir := dockerConfigDir()
+	if configDir == "" {
+		return nil, nil
+	}
+
+	metaFiles, err := filepath.Glob(filepath.Join(configDir, "contexts", "meta", "*", "meta.json"))
+	if err != nil {
+		return nil, err
+	}
+
+	var contexts []Context
+	for _, path := range metaFiles {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("error reading docker context %s: %v", path, err)
+		}
+
+		var meta contextMeta
+		if err := json.Unmarshal(data, &meta); err != nil {
+			return nil, fmt.Errorf("error parsing docker context %s: %v", path, err)
+		}
+
+		endpoint, ok := meta.Endpoints["docker"]
+		if !ok {
+			continue
+		}
+
+		c := Context{
+			Name:          meta.Name,
+			Host:          endpoint.Host,
+			Description:   meta.Metadata.Description,
+			Source:        "docker",
+			SkipTLSVerify: endpoint.SkipTLSVerify,
+		}
+
+		// TLS material lives under the same digest-named directory as the metadata
+		tlsDir := filepath.Join(configDir, "contexts", "tls", filepath.Base(filepath.Dir(path)), "docker")
+		if _, err := os.Stat(tlsDir); err == nil {
+			c.TLSDir = tlsDir
+		}
+
+		contexts = append(contexts, c)
+	}
+
+	sort.Slice(contexts, func(i, j int) bool { return contexts[i].Name < contexts[j].Name })
+	return contexts, nil
+}
+
+// NewContextClient creates a Docker client connected to the daemon of a context.
+// Contexts with TLS material or SkipTLSVerify connect over TLS, as with the Docker CLI.
+func NewContextClient(c Context) (*client.Client, error) {
+	if c.TLSDir == "" && !c.SkipTLSVerify {
+		return NewClient(c.Host)
+	}
+
+	tlsConfig, err := contextTLSConfig(c)
+	if err != nil {
+		return nil, err
+	}
+	opts := []client.Opt{
+		client.FromEnv,
+		client.WithAPIVersionNegotiation(),
+		client.WithHTTPClient(&http.Client{Transport: &http.Transport{TLSClientConfig: tlsConfig}}),
+		client.WithHost(c.Host),
+	}
+	return client.NewClientWithOpts(opts...)
+}
+
+// contextTLSConfig loads the CA and client certificate a context has, if any
+func contextTLSConfig(c Context) (*tls.Config, error) {
+	config := &tls.Config{InsecureSkipVerify: c.SkipTLSVerify, MinVersion: tls.VersionTLS12}
+	if c.TLSDir == "" {
+		return config, nil
+	}
+
+	caFile := filepath.Join(c.TLSDir, "ca.pem")
+	ca, err := os.ReadFile(caFile)
+	if err == nil {
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(ca) {
+			return nil, fmt.Errorf("error loading %s: no certificates found", caFile)
+		}
+		config.RootCAs = pool
+	} else if !os.IsNotExist(err) {
+		return nil, fmt.Errorf("error reading %s: %v", caFile, err)
+	}
+
+	certFile, keyFile := filepath.Join(c.TLSDir, "cert.pem"), filepath.Join(c.TLSDir, "key.pem")
+	if _, err := os.Stat(certFile); err == nil {
+		cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			return nil, fmt.Errorf("error loading client certificate of context %s: %v", c.Name, err)
+		}
+		config.Certificates = []tls.Certificate{cert}
+	}
+	return config, nil
+}
+
+// FindContext returns the context with the given name
+func FindContext(contexts []Context, name string) (Context, error) {
+	for _, c := range contexts {
+		if c.Name == name {
+			return c, nil
+		}
+	}
+	return Context{}, fmt.Errorf("unknown context %s", name)
+}
@@ -0,0 +1,50 @@
+package docker
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/client"
+)
+
+// ChangeKind is how a path in a container differs from its image
+type ChangeKind string
+
+const (
+	ChangeAdded   ChangeKind = "added"
+	ChangeChanged ChangeKind = "changed"
+	ChangeDeleted ChangeKind = "deleted"
+)
+
+// Change is a path a container added, changed or deleted on top of its image
+type Change struct {
+	Path string     `json:"path" yaml:"path"`
+	Kind ChangeKind `json:"kind" yaml:"kind"`
+}
+
+// ContainerChanges returns the changes of a container's writable layer, sorted by path
+func ContainerChanges(ctx context.Context, cli *client.Client, containerID string) ([]Change, error) {
+	diff, err := cli.ContainerDiff(ctx, containerID)
+	if err != nil {
+		return nil, fmt.Errorf("error reading changes of container %s: %v", containerID, err)
+	}
+
+	changes := make([]Change, 0, len(diff))
+	for _, d := range diff {
+		kind := ChangeChanged
+		switch d.Kind {
+		case container.ChangeAdd:
+			kind = ChangeAdded
+		case container.ChangeDelete:
+			kind = ChangeDeleted
+		}
+		changes = append(changes, Change{Path: d.Path, Kind: kind})
+	}
+
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].Path < changes[j].Path
+	})
+	return changes, nil
+}
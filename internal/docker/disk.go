@@ -0,0 +1,127 @@
+package docker
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/filters"
+	"github.com/docker/docker/client"
+)
+
+// DiskUsageSection summarises the disk usage of one kind of Docker object
+type DiskUsageSection struct {
+	Name        string
+	Count       int
+	Active      int
+	Size        int64
+	Reclaimable int64
+}
+
+// DiskUsageSummary is the disk usage of the daemon broken down by object kind
+type DiskUsageSummary struct {
+	Images     DiskUsageSection
+	Containers DiskUsageSection
+	Volumes    DiskUsageSection
+	BuildCache DiskUsageSection
+}
+
+// Sections returns the summary sections in display order
+func (d DiskUsageSummary) Sections() []DiskUsageSection {
+	return []DiskUsageSection{d.Images, d.Containers, d.Volumes, d.BuildCache}
+}
+
+// Total returns the combined size of all sections
+func (d DiskUsageSummary) Total() int64 {
+	return d.Images.Size + d.Containers.Size + d.Volumes.Size + d.BuildCache.Size
+}
+
+// GetDiskUsage returns the daemon's disk usage, computed the same way as `docker system df`
+func GetDiskUsage(ctx context.Context, cli *client.Client) (DiskUsageSummary, error) {
+	usage, err := cli.DiskUsage(ctx, types.DiskUsageOptions{})
+	if err != nil {
+		return DiskUsageSummary{}, fmt.Errorf("error getting disk usage: %v", err)
+	}
+
+	summary := DiskUsageSummary{
+		Images:     DiskUsageSection{Name: "Images", Count: len(usage.Images), Size: usage.LayersSize},
+		Containers: DiskUsageSection{Name: "Containers", Count: len(usage.Containers)},
+		Volumes:    DiskUsageSection{Name: "Volumes", Count: len(usage.Volumes)},
+		BuildCache: DiskUsageSection{Name: "Build Cache", Count: len(usage.BuildCache)},
+	}
+
+	// Space held by images in use cannot be reclaimed; shared layers only count once
+	var imagesInUse int64
+	for _, img := range usage.Images {
+		if img.Containers > 0 {
+			summary.Images.Active++
+			used := img.Size
+			if img.SharedSize > 0 {
+				used -= img.SharedSize
+			}
+			imagesInUse += used
+		}
+	}
+	summary.Images.Reclaimable = summary.Images.Size - imagesInUse
+
+	for _, c := range usage.Containers {
+		summary.Containers.Size += c.SizeRw
+		if c.State == "running" || c.State == "paused" || c.State == "restarting" {
+			summary.Containers.Active++
+		} else {
+			summary.Containers.Reclaimable += c.SizeRw
+		}
+	}
+
+	for _, v := range usage.Volumes {
+		if v.UsageData == nil || v.UsageData.Size < 0 {
+			continue
+		}
+		summary.Volumes.Size += v.UsageData.Size
+		if v.UsageData.RefCount > 0 {
+			summary.Volumes.Active++
+		} else {
+			summary.Volumes.Reclaimable += v.UsageData.Size
+		}
+	}
+
+	for _, cache := range usage.BuildCache {
+		summary.BuildCache.Size += cache.Size
+		if cache.InUse {
+			summary.BuildCache.Active++
+		} else if !cache.Shared {
+			summary.BuildCache.Reclaimable += cache.Size
+		}
+	}
+
+	return summary, nil
+}
+
+// PruneContainers removes all stopped containers
+func PruneContainers(ctx context.Context, cli *client.Client) (PruneResult, error) {
+	report, err := cli.ContainersPrune(ctx, filters.NewArgs())
+	if err != nil {
+		return PruneResult{}, fmt.Errorf("error pruning containers: %v", err)
+	}
+	return PruneResult{Deleted: len(report.ContainersDeleted), SpaceReclaimed: report.SpaceReclaimed}, nil
+}
+
+// PruneVolumes removes the anonymous volumes not referenced by a container. Named
+// volumes hold data someone chose to keep, so they are left alone.
+func PruneVolumes(ctx context.Context, cli *client.Client) (PruneResult, error) {
+	// Since API 1.42 named volumes are only pruned when "all" is set
+	report, err := cli.VolumesPrune(ctx, filters.NewArgs())
+	if err != nil {
+		return PruneResult{}, fmt.Errorf("error pruning volumes: %v", err)
+	}
+	return PruneResult{Deleted: len(report.VolumesDeleted), SpaceReclaimed: report.SpaceReclaimed}, nil
+}
+
+// PruneBuildCache removes all build cache not in use
+func PruneBuildCache(ctx context.Context, cli *client.Client) (PruneResult, error) {
+	report, err := cli.BuildCachePrune(ctx, types.BuildCachePruneOptions{All: true})
+	if err != nil {
+		return PruneResult{}, fmt.Errorf("error pruning build cache: %v", err)
+	}
+	return PruneResult{Deleted: len(report.CachesDeleted), SpaceReclaimed: report.SpaceReclaimed}, nil
+}
@@ -0,0 +1,329 @@
+package docker
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/events"
+	"github.com/docker/docker/api/types/filters"
+	"github.com/docker/docker/api/types/swarm"
+	"github.com/docker/docker/client"
+)
+
+// EventType groups the events shown on the timeline
+type EventType string
+
+// Event types
+const (
+	EventContainer EventType = "container"
+	EventHealth    EventType = "health"
+	EventService   EventType = "service"
+	EventTask      EventType = "task"
+)
+
+// EventTypes lists every event type, in the order filters cycle through them
+var EventTypes = []EventType{EventContainer, EventHealth, EventService, EventTask}
+
+// EventLevel says how worrying an event is
+type EventLevel string
+
+// Event levels
+const (
+	EventOK      EventLevel = "ok"
+	EventInfo    EventLevel = "info"
+	EventWarning EventLevel = "warning"
+	EventError   EventLevel = "error"
+)
+
+// taskPollInterval is how often task states are compared; swarm does not report
+// task state changes as events
+const taskPollInterval = 5 * time.Second
+
+// Event is something that happened to a stack's containers, services or tasks
+type Event struct {
+	Time    time.Time  `json:"time" yaml:"time"`
+	Type    EventType  `json:"type" yaml:"type"`
+	Action  string     `json:"action" yaml:"action"`
+	Level   EventLevel `json:"level" yaml:"level"`
+	Stack   string     `json:"stack" yaml:"stack"`
+	Service string     `json:"service" yaml:"service"`
+	Subject string     `json:"subject" yaml:"subject"`
+	Message string     `json:"message,omitempty" yaml:"message,omitempty"`
+}
+
+// WatchEvents sends the container, service and task events of stacks to out until
+// ctx is cancelled or the event stream fails. With a non-zero since, events from the
+// daemon's history and task states reached after since are sent first. It returns the
+// time of the newest event sent, for watching again from there after a failure.
+func WatchEvents(ctx context.Context, cli *client.Client, since time.Time, out chan<- Event) (time.Time, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	w := &eventWatcher{cli: cli, out: out, stacks: make(map[string]string)}
+	w.loadServices(ctx)
+
+	eventFilter := filters.NewArgs()
+	eventFilter.Add("type", string(events.ContainerEventType))
+	eventFilter.Add("type", string(events.ServiceEventType))
+	for _, action := range []events.Action{events.ActionStart, events.ActionDie, events.ActionOOM, events.ActionHealthStatus,
+		events.ActionCreate, events.ActionUpdate, events.ActionRemove} {
+		eventFilter.Add("event", string(action))
+	}
+
+	options := events.ListOptions{Filters: eventFilter}
+	if !since.IsZero() {
+		options.Since = strconv.FormatInt(since.Unix(), 10)
+	}
+	messages, errs := cli.Events(ctx, options)
+
+	go w.pollTasks(ctx, since)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return w.newest(), nil
+		case err := <-errs:
+			if ctx.Err() != nil {
+				return w.newest(), nil
+			}
+			return w.newest(), fmt.Errorf("error reading events: %v", err)
+		case message := <-messages:
+			if event, ok := w.convert(ctx, message); ok {
+				w.send(ctx, event)
+			}
+		}
+	}
+}
+
+// eventWatcher turns Docker events and task state changes into timeline events
+type eventWatcher struct {
+	cli *client.Client
+	out chan<- Event
+
+	// Stack of each service by name, remembered so removed services keep their stack,
+	// and the time of the newest event sent
+	mu     sync.Mutex
+	stacks map[string]string
+	last   time.Time
+}
+
+// send delivers an event unless ctx is cancelled first
+func (w *eventWatcher) send(ctx context.Context, event Event) {
+	select {
+	case w.out <- event:
+		w.mu.Lock()
+		if event.Time.After(w.last) {
+			w.last = event.Time
+		}
+		w.mu.Unlock()
+	case <-ctx.Done():
+	}
+}
+
+// newest returns the time of the newest event sent
+func (w *eventWatcher) newest() time.Time {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.last
+}
+
+// loadServices remembers the stack of every service
+func (w *eventWatcher) loadServices(ctx context.Context) map[string]swarm.Service {
+	services, err := w.cli.ServiceList(ctx, types.ServiceListOptions{})
+	if err != nil {
+		return nil
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	byID := make(map[string]swarm.Service, len(services))
+	for _, service := range services {
+		if stack, ok := service.Spec.Labels["com.docker.stack.namespace"]; ok {
+			w.stacks[service.Spec.Name] = stack
+		}
+		byID[service.ID] = service
+	}
+	return byID
+}
+
+// serviceStack returns the stack of a service, reloading the services once for new ones
+func (w *eventWatcher) serviceStack(ctx context.Context, name string) string {
+	w.mu.Lock()
+	stack, ok := w.stacks[name]
+	w.mu.Unlock()
+	if !ok {
+		w.loadServices(ctx)
+		w.mu.Lock()
+		stack = w.stacks[name]
+		w.mu.Unlock()
+	}
+	return stack
+}
+
+// convert turns a Docker event into a timeline event; events outside stacks are dropped
+func (w *eventWatcher) convert(ctx context.Context, message events.Message) (Event, bool) {
+	attributes := message.Actor.Attributes
+	event := Event{
+		Time:   time.Unix(0, message.TimeNano),
+		Action: string(message.Action),
+		Level:  EventInfo,
+	}
+
+	switch message.Type {
+	case events.ContainerEventType:
+		event.Type = EventContainer
+		event.Stack = attributes["com.docker.stack.namespace"]
+		event.Service = attributes["com.docker.swarm.service.name"]
+		event.Subject = attributes["name"]
+
+		switch {
+		case message.Action == events.ActionStart:
+			event.Level = EventOK
+		case message.Action == events.ActionDie:
+			event.Message = "exit code " + attributes["exitCode"]
+			if attributes["exitCode"] != "0" {
+				event.Level = EventError
+			}
+		case message.Action == events.ActionOOM:
+			event.Level = EventError
+			event.Message = "killed for running out of memory"
+		case strings.HasPrefix(event.Action, string(events.ActionHealthStatus)):
+			event.Type = EventHealth
+			event.Action = string(events.ActionHealthStatus)
+			event.Message = strings.TrimSpace(strings.TrimPrefix(string(message.Action), string(events.ActionHealthStatus)+":"))
+			switch event.Message {
+			case "healthy":
+				event.Level = EventOK
+			case "unhealthy":
+				event.Level = EventError
+			}
+		default:
+			return Event{}, false
+		}
+	case events.ServiceEventType:
+		event.Type = EventService
+		event.Service = attributes["name"]
+		event.Stack = w.serviceStack(ctx, event.Service)
+		event.Subject = event.Service
+		event.Message, event.Level = describeServiceEvent(message.Action, attributes)
+	default:
+		return Event{}, false
+	}
+
+	return event, event.Stack != ""
+}
+
+// describeServiceEvent summarises what changed in a service event
+func describeServiceEvent(action events.Action, attributes map[string]string) (string, EventLevel) {
+	if action == events.ActionRemove {
+		return "service removed", EventWarning
+	}
+	if action == events.ActionCreate {
+		return "service created", EventInfo
+	}
+
+	var changes []string
+	level := EventInfo
+	if image := attributes["image.new"]; image != "" {
+		changes = append(changes, "image "+strings.SplitN(image, "@", 2)[0])
+	}
+	if replicas := attributes["replicas.new"]; replicas != "" {
+		changes = append(changes, fmt.Sprintf("replicas %s → %s", attributes["replicas.old"], replicas))
+	}
+	if state := attributes["updatestate.new"]; state != "" {
+		changes = append(changes, "update "+state)
+		switch swarm.UpdateState(state) {
+		case swarm.UpdateStatePaused, swarm.UpdateStateRollbackStarted, swarm.UpdateStateRollbackPaused:
+			level = EventWarning
+		case swarm.UpdateStateCompleted, swarm.UpdateStateRollbackCompleted:
+			level = EventOK
+		}
+	}
+	if len(changes) == 0 {
+		return "service updated", level
+	}
+	return strings.Join(changes, ", "), level
+}
+
+// pollTasks reports task state changes until ctx is cancelled. Without swarm, or when
+// the node is not a manager, there are no tasks and polling stops.
+func (w *eventWatcher) pollTasks(ctx context.Context, since time.Time) {
+	states := make(map[string]swarm.TaskState)
+	first := true
+
+	ticker := time.NewTicker(taskPollInterval)
+	defer ticker.Stop()
+
+	for {
+		services := w.loadServices(ctx)
+		tasks, err := w.cli.TaskList(ctx, types.TaskListOptions{})
+		if err != nil {
+			return
+		}
+
+		for _, task := range tasks {
+			previous, seen := states[task.ID]
+			states[task.ID] = task.Status.State
+			if seen && previous == task.Status.State {
+				continue
+			}
+			// The first poll only establishes the current states, apart from the backfill
+			if first && (since.IsZero() || task.Status.Timestamp.Before(since)) {
+				continue
+			}
+			if event, ok := taskEvent(task, services[task.ServiceID]); ok {
+				w.send(ctx, event)
+			}
+		}
+		first = false
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// taskEvent describes a task that reached a state worth showing on the timeline
+func taskEvent(task swarm.Task, service swarm.Service) (Event, bool) {
+	stack, ok := service.Spec.Labels["com.docker.stack.namespace"]
+	if !ok {
+		return Event{}, false
+	}
+
+	event := Event{
+		Time:    task.Status.Timestamp,
+		Type:    EventTask,
+		Action:  string(task.Status.State),
+		Stack:   stack,
+		Service: service.Spec.Name,
+		Subject: fmt.Sprintf("%s.%d", service.Spec.Name, task.Slot),
+		Message: task.Status.Message,
+	}
+	if task.Slot == 0 {
+		event.Subject = fmt.Sprintf("%s.%.12s", service.Spec.Name, task.NodeID)
+	}
+
+	switch task.Status.State {
+	case swarm.TaskStateRunning:
+		event.Level = EventOK
+	case swarm.TaskStateComplete, swarm.TaskStateShutdown:
+		event.Level = EventInfo
+	case swarm.TaskStateOrphaned, swarm.TaskStateRemove:
+		event.Level = EventWarning
+	case swarm.TaskStateFailed, swarm.TaskStateRejected:
+		event.Level = EventError
+		if task.Status.Err != "" {
+			event.Message = task.Status.Err
+		}
+	default:
+		return Event{}, false
+	}
+	return event, true
+}
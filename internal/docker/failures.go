@@ -0,0 +1,171 @@
+package docker
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/filters"
+	"github.com/docker/docker/api/types/swarm"
+	"github.com/docker/docker/client"
+)
+
+// TaskFailure is a swarm task of a stack service that failed or was rejected
+type TaskFailure struct {
+	ServiceID string
+	Stack     string
+	Service   string
+	Time      time.Time
+	Message   string
+}
+
+// ContainerExit describes a stack container that has stopped
+type ContainerExit struct {
+	ID         string
+	Name       string
+	Stack      string
+	Service    string
+	ExitCode   int
+	OOMKilled  bool
+	FinishedAt time.Time
+}
+
+// ContainerHealth returns the healthcheck status shown in a container's status
+// line: healthy, unhealthy, starting, or none when there is no healthcheck
+func ContainerHealth(c types.Container) string {
+	switch {
+	case strings.Contains(c.Status, "(unhealthy)"):
+		return "unhealthy"
+	case strings.Contains(c.Status, "(healthy)"):
+		return "healthy"
+	case strings.Contains(c.Status, "(health: starting)"):
+		return "starting"
+	default:
+		return "none"
+	}
+}
+
+// ListTaskFailures returns the stack tasks that failed or were rejected after since
+func ListTaskFailures(ctx context.Context, cli *client.Client, since time.Time) ([]TaskFailure, error) {
+	services, err := cli.ServiceList(ctx, types.ServiceListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("error listing services: %v", err)
+	}
+	byID := make(map[string]swarm.Service)
+	for _, service := range services {
+		byID[service.ID] = service
+	}
+
+	tasks, err := cli.TaskList(ctx, types.TaskListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("error listing tasks: %v", err)
+	}
+
+	var failures []TaskFailure
+	for _, task := range tasks {
+		if task.Status.State != swarm.TaskStateFailed && task.Status.State != swarm.TaskStateRejected {
+			continue
+		}
+		if task.Status.Timestamp.Before(since) {
+			continue
+		}
+		service, ok := byID[task.ServiceID]
+		if !ok {
+			continue
+		}
+		stack, ok := service.Spec.Labels["com.docker.stack.namespace"]
+		if !ok {
+			continue
+		}
+
+		message := task.Status.Err
+		if message == "" {
+			message = task.Status.Message
+		}
+		failures = append(failures, TaskFailure{
+			ServiceID: task.ServiceID,
+			Stack:     stack,
+			Service:   service.Spec.Name,
+			Time:      task.Status.Timestamp,
+			Message:   message,
+		})
+	}
+	return failures, nil
+}
+
+// ListContainerExits returns the stack containers on this node that stopped after since.
+// Swarm keeps the containers of old tasks around, so recent crashes can be inspected.
+func ListContainerExits(ctx context.Context, cli *client.Client, since time.Time) ([]ContainerExit, error) {
+	containerFilter := filters.NewArgs()
+	containerFilter.Add("label", "com.docker.stack.namespace")
+	containerFilter.Add("status", "exited")
+
+	containers, err := cli.ContainerList(ctx, container.ListOptions{All: true, Filters: containerFilter})
+	if err != nil {
+		return nil, fmt.Errorf("error listing stopped containers: %v", err)
+	}
+
+	var exits []ContainerExit
+	for _, c := range containers {
+		details, err := cli.ContainerInspect(ctx, c.ID)
+		if err != nil {
+			continue
+		}
+		finished, err := time.Parse(time.RFC3339Nano, details.State.FinishedAt)
+		if err != nil || finished.Before(since) {
+			continue
+		}
+
+		exits = append(exits, ContainerExit{
+			ID:         c.ID,
+			Name:       strings.TrimPrefix(details.Name, "/"),
+			Stack:      c.Labels["com.docker.stack.namespace"],
+			Service:    c.Labels["com.docker.swarm.service.name"],
+			ExitCode:   details.State.ExitCode,
+			OOMKilled:  details.State.OOMKilled,
+			FinishedAt: finished,
+		})
+	}
+	return exits, nil
+}
+
+// SlotRestarts counts, for each task, the earlier tasks of the same service slot that
+// swarm still remembers (task_history_limit, 5 by default): the times swarm replaced
+// the container. Containers are not restarted in place in a swarm, so this stands in
+// for their restart count. Keys are task IDs, as in the com.docker.swarm.task.id label.
+func SlotRestarts(ctx context.Context, cli *client.Client) (map[string]int, error) {
+	tasks, err := cli.TaskList(ctx, types.TaskListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("error listing tasks: %v", err)
+	}
+
+	// Global services have no slots; they run one task per node instead
+	type slot struct {
+		service string
+		slot    int
+		node    string
+	}
+	bySlot := make(map[slot][]swarm.Task)
+	for _, task := range tasks {
+		key := slot{service: task.ServiceID, slot: task.Slot}
+		if task.Slot == 0 {
+			key.node = task.NodeID
+		}
+		bySlot[key] = append(bySlot[key], task)
+	}
+
+	restarts := make(map[string]int, len(tasks))
+	for _, slotTasks := range bySlot {
+		sort.Slice(slotTasks, func(i, j int) bool {
+			return slotTasks[i].CreatedAt.Before(slotTasks[j].CreatedAt)
+		})
+		for i, task := range slotTasks {
+			restarts[task.ID] = i
+		}
+	}
+	return restarts, nil
+}
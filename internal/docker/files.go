@@ -0,0 +1,398 @@
+package docker
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/client"
+)
+
+// MaxDirEntries bounds a directory listing
+const MaxDirEntries = 5000
+
+// ErrNotText is returned when a file looks binary and cannot be shown as text
+var ErrNotText = errors.New("file is not text")
+
+// FileEntry is a file or directory in a container
+type FileEntry struct {
+	Name       string      `json:"name" yaml:"name"`
+	Path       string      `json:"path" yaml:"path"`
+	Size       int64       `json:"size" yaml:"size"`
+	Mode       fs.FileMode `json:"mode" yaml:"mode"`
+	ModTime    time.Time   `json:"modTime" yaml:"modTime"`
+	LinkTarget string      `json:"linkTarget,omitempty" yaml:"linkTarget,omitempty"`
+}
+
+// IsDir reports whether the entry is a directory
+func (e FileEntry) IsDir() bool {
+	return e.Mode.IsDir()
+}
+
+// StatPath describes a path in a container
+func StatPath(ctx context.Context, cli *client.Client, containerID, p string) (FileEntry, error) {
+	stat, err := cli.ContainerStatPath(ctx, containerID, p)
+	if err != nil {
+		return FileEntry{}, fmt.Errorf("error reading %s in container %s: %v", p, containerID, err)
+	}
+	return FileEntry{
+		Name:       stat.Name,
+		Path:       p,
+		Size:       stat.Size,
+		Mode:       stat.Mode,
+		ModTime:    stat.Mtime,
+		LinkTarget: stat.LinkTarget,
+	}, nil
+}
+
+// ListDir returns the entries of a directory in a container, directories first, and
+// whether the listing was cut short at MaxDirEntries. The names come from find in the
+// container and each one is described by the daemon; containers without find, or not
+// running, fall back to reading the directory's archive, which holds everything below it.
+func ListDir(ctx context.Context, cli *client.Client, containerID, dir string) ([]FileEntry, bool, error) {
+	names, err := childNames(ctx, cli, containerID, dir)
+	if ctx.Err() != nil {
+		return nil, false, fmt.Errorf("error reading %s in container %s: %v", dir, containerID, ctx.Err())
+	}
+	if err != nil {
+		return listArchive(ctx, cli, containerID, dir)
+	}
+
+	truncated := len(names) > MaxDirEntries
+	names = names[:min(len(names), MaxDirEntries)]
+	entries := make([]FileEntry, 0, len(names))
+	for _, name := range names {
+		entry, err := StatPath(ctx, cli, containerID, path.Join(dir, name))
+		if ctx.Err() != nil {
+			return nil, false, fmt.Errorf("error reading %s in container %s: %v", dir, containerID, ctx.Err())
+		}
+		// The entry was removed since it was listed
+		if err != nil {
+			continue
+		}
+		entry.Name = name
+		entries = append(entries, entry)
+	}
+	sortEntries(entries)
+	return entries, truncated, nil
+}
+
+// childNames lists the names in a directory by running find in the container
+func childNames(ctx context.Context, cli *client.Client, containerID, dir string) ([]string, error) {
+	result, err := ExecInContainer(ctx, cli, containerID, []string{"find", dir, "-mindepth", "1", "-maxdepth", "1", "-print0"})
+	if err != nil {
+		return nil, err
+	}
+	if result.ExitCode != 0 {
+		return nil, fmt.Errorf("error listing %s in container %s: %s", dir, containerID, strings.TrimSpace(result.Stderr))
+	}
+
+	var names []string
+	for _, p := range strings.Split(result.Stdout, "\x00") {
+		if p != "" {
+			names = append(names, path.Base(p))
+		}
+	}
+	return names, nil
+}
+
+// listArchive lists a directory from its archive, giving up after MaxDirEntries
+// archive entries since those include every file below it
+func listArchive(ctx context.Context, cli *client.Client, containerID, dir string) ([]FileEntry, bool, error) {
+	reader, _, err := cli.CopyFromContainer(ctx, containerID, dir)
+	if err != nil {
+		return nil, false, fmt.Errorf("error reading %s in container %s: %v", dir, containerID, err)
+	}
+	defer reader.Close()
+
+	var entries []FileEntry
+	truncated := false
+	archive := tar.NewReader(reader)
+	for read := 0; ; read++ {
+		if read == MaxDirEntries {
+			truncated = true
+			break
+		}
+		header, err := archive.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, false, fmt.Errorf("error reading %s in container %s: %v", dir, containerID, err)
+		}
+
+		// Archive names start with the directory's own name; only its children are listed
+		_, name, ok := strings.Cut(strings.TrimSuffix(header.Name, "/"), "/")
+		if !ok || name == "" || strings.Contains(name, "/") {
+			continue
+		}
+		entries = append(entries, FileEntry{
+			Name:       name,
+			Path:       path.Join(dir, name),
+			Size:       header.Size,
+			Mode:       header.FileInfo().Mode(),
+			ModTime:    header.ModTime,
+			LinkTarget: header.Linkname,
+		})
+	}
+
+	sortEntries(entries)
+	return entries, truncated, nil
+}
+
+// sortEntries orders directory entries by name, directories first
+func sortEntries(entries []FileEntry) {
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].IsDir() != entries[j].IsDir() {
+			return entries[i].IsDir()
+		}
+		return entries[i].Name < entries[j].Name
+	})
+}
+
+// ReadFile returns up to limit bytes of a text file in a container, or ErrNotText
+func ReadFile(ctx context.Context, cli *client.Client, containerID, p string, limit int64) ([]byte, error) {
+	reader, _, err := cli.CopyFromContainer(ctx, containerID, p)
+	if err != nil {
+		return nil, fmt.Errorf("error reading %s in container %s: %v", p, containerID, err)
+	}
+	defer reader.Close()
+
+	archive := tar.NewReader(reader)
+	header, err := archive.Next()
+	if err != nil {
+		return nil, fmt.Errorf("error reading %s in container %s: %v", p, containerID, err)
+	}
+	if header.Typeflag != tar.TypeReg {
+		return nil, fmt.Errorf("%s is not a regular file", p)
+	}
+
+	content, err := io.ReadAll(io.LimitReader(archive, limit))
+	if err != nil {
+		return nil, fmt.Errorf("error reading %s in container %s: %v", p, containerID, err)
+	}
+	if bytes.IndexByte(content[:min(len(content), 8192)], 0) >= 0 {
+		return nil, ErrNotText
+	}
+	return content, nil
+}
+
+// progressReader reports the size of every read to progress, if set
+type progressReader struct {
+	r        io.Reader
+	progress func(n int)
+}
+
+func (p progressReader) Read(b []byte) (int, error) {
+	n, err := p.r.Read(b)
+	if n > 0 && p.progress != nil {
+		p.progress(n)
+	}
+	return n, err
+}
+
+// DownloadPath copies a file or directory out of a container, reporting the archive
+// bytes read to progress. When dest ends in .tar the archive is saved as is, and
+// removed again if the copy fails; otherwise it is extracted into the directory dest.
+func DownloadPath(ctx context.Context, cli *client.Client, containerID, p, dest string, progress func(n int)) error {
+	reader, _, err := cli.CopyFromContainer(ctx, containerID, p)
+	if err != nil {
+		return fmt.Errorf("error copying %s from container %s: %v", p, containerID, err)
+	}
+	defer reader.Close()
+	r := progressReader{reader, progress}
+
+	if strings.HasSuffix(dest, ".tar") {
+		f, err := os.Create(dest)
+		if err != nil {
+			return fmt.Errorf("error creating %s: %v", dest, err)
+		}
+		if _, err := io.Copy(f, r); err != nil {
+			f.Close()
+			_ = os.Remove(dest)
+			return fmt.Errorf("error writing %s: %v", dest, err)
+		}
+		return f.Close()
+	}
+
+	return extractTar(r, dest)
+}
+
+// extractTar unpacks regular files, directories and symlinks below dest. The archive
+// comes from a container that may be compromised, so entries that would escape dest
+// are refused: names leaving it, writes through a symlink and symlinks pointing out.
+func extractTar(r io.Reader, dest string) error {
+	if err := os.MkdirAll(dest, 0o755); err != nil {
+		return fmt.Errorf("error creating %s: %v", dest, err)
+	}
+
+	archive := tar.NewReader(r)
+	for {
+		header, err := archive.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("error reading archive: %v", err)
+		}
+
+		name := path.Clean(strings.TrimSuffix(header.Name, "/"))
+		if !filepath.IsLocal(filepath.FromSlash(name)) {
+			return fmt.Errorf("archive entry %s is outside %s", header.Name, dest)
+		}
+		if err := checkNoSymlinks(dest, name); err != nil {
+			return err
+		}
+		target := filepath.Join(dest, filepath.FromSlash(name))
+
+		switch header.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(target, 0o755); err != nil {
+				return fmt.Errorf("error creating %s: %v", target, err)
+			}
+		case tar.TypeReg:
+			if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+				return fmt.Errorf("error creating %s: %v", filepath.Dir(target), err)
+			}
+			if err := writeFile(target, archive, header.FileInfo().Mode().Perm()); err != nil {
+				return err
+			}
+		case tar.TypeSymlink:
+			link := path.Join(path.Dir(name), header.Linkname)
+			if path.IsAbs(header.Linkname) || !filepath.IsLocal(filepath.FromSlash(link)) {
+				return fmt.Errorf("archive entry %s links outside %s: %s", header.Name, dest, header.Linkname)
+			}
+			if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+				return fmt.Errorf("error creating %s: %v", filepath.Dir(target), err)
+			}
+			_ = os.Remove(target)
+			if err := os.Symlink(header.Linkname, target); err != nil {
+				return fmt.Errorf("error creating %s: %v", target, err)
+			}
+		}
+	}
+}
+
+// checkNoSymlinks refuses an archive entry when a directory between dest and the
+// entry is a symlink, which an earlier entry may have planted to redirect the write
+func checkNoSymlinks(dest, name string) error {
+	dir := dest
+	for _, part := range strings.Split(path.Dir(name), "/") {
+		if part == "." {
+			continue
+		}
+		dir = filepath.Join(dir, part)
+		info, err := os.Lstat(dir)
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("error reading %s: %v", dir, err)
+		}
+		if info.Mode()&fs.ModeSymlink != 0 {
+			return fmt.Errorf("archive entry %s is below the symlink %s", name, dir)
+		}
+	}
+	return nil
+}
+
+// writeFile creates a regular file from r, refusing to follow a symlink at target
+func writeFile(target string, r io.Reader, perm fs.FileMode) error {
+	if info, err := os.Lstat(target); err == nil && info.Mode()&fs.ModeSymlink != 0 {
+		return fmt.Errorf("refusing to write through the symlink %s", target)
+	}
+	f, err := os.OpenFile(target, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, perm)
+	if err != nil {
+		return fmt.Errorf("error creating %s: %v", target, err)
+	}
+	if _, err := io.Copy(f, r); err != nil {
+		f.Close()
+		return fmt.Errorf("error writing %s: %v", target, err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("error writing %s: %v", target, err)
+	}
+	return nil
+}
+
+// UploadPath copies a local file or directory into a directory of a container,
+// reporting the archive bytes sent to progress
+func UploadPath(ctx context.Context, cli *client.Client, containerID, local, dir string, progress func(n int)) error {
+	info, err := os.Stat(local)
+	if err != nil {
+		return fmt.Errorf("error reading %s: %v", local, err)
+	}
+
+	pr, pw := io.Pipe()
+	go func() {
+		pw.CloseWithError(writeTar(pw, local, info))
+	}()
+
+	if err := cli.CopyToContainer(ctx, containerID, dir, progressReader{pr, progress}, container.CopyToContainerOptions{}); err != nil {
+		pr.CloseWithError(err)
+		return fmt.Errorf("error copying %s to %s in container %s: %v", local, dir, containerID, err)
+	}
+	return nil
+}
+
+// writeTar archives a file or directory under its base name
+func writeTar(w io.Writer, local string, info fs.FileInfo) error {
+	archive := tar.NewWriter(w)
+	root := filepath.Dir(local)
+
+	err := filepath.WalkDir(local, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		link := ""
+		if info.Mode()&fs.ModeSymlink != 0 {
+			if link, err = os.Readlink(p); err != nil {
+				return err
+			}
+		}
+
+		header, err := tar.FileInfoHeader(info, link)
+		if err != nil {
+			return err
+		}
+		name, err := filepath.Rel(root, p)
+		if err != nil {
+			return err
+		}
+		header.Name = filepath.ToSlash(name)
+		if err := archive.WriteHeader(header); err != nil {
+			return err
+		}
+
+		if info.Mode().IsRegular() {
+			f, err := os.Open(p)
+			if err != nil {
+				return err
+			}
+			defer f.Close()
+			if _, err := io.Copy(archive, f); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("error archiving %s: %v", info.Name(), err)
+	}
+	return archive.Close()
+}
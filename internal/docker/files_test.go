@@ -0,0 +1,121 @@
+package docker
+
+import (
+	"archive/tar"
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// tarEntry is an archive entry for tests: a directory when name ends in "/", a symlink
+// when link is set and a regular file otherwise
+type tarEntry struct {
+	name, link, content string
+}
+
+func buildTar(t *testing.T, entries []tarEntry) *bytes.Buffer {
+	t.Helper()
+	var buf bytes.Buffer
+	archive := tar.NewWriter(&buf)
+	for _, e := range entries {
+		header := &tar.Header{Name: e.name, Mode: 0o644, Typeflag: tar.TypeReg, Size: int64(len(e.content))}
+		switch {
+		case strings.HasSuffix(e.name, "/"):
+			header.Typeflag, header.Mode = tar.TypeDir, 0o755
+		case e.link != "":
+			header.Typeflag, header.Linkname = tar.TypeSymlink, e.link
+		}
+		if err := archive.WriteHeader(header); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := archive.Write([]byte(e.content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := archive.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return &buf
+}
+
+func TestExtractTar(t *testing.T) {
+	tests := []struct {
+		name    string
+		entries []tarEntry
+		wantErr string
+		want    map[string]string
+	}{
+		{
+			name:    "files and directories",
+			entries: []tarEntry{{name: "app/"}, {name: "app/config.yml", content: "port: 80"}, {name: "app/logs/today.log", content: "ok"}},
+			want:    map[string]string{"app/config.yml": "port: 80", "app/logs/today.log": "ok"},
+		},
+		{
+			name:    "relative symlink inside dest",
+			entries: []tarEntry{{name: "app/config.yml", content: "x"}, {name: "app/current", link: "config.yml"}},
+			want:    map[string]string{"app/current": "x"},
+		},
+		{
+			name:    "parent directory in name",
+			entries: []tarEntry{{name: "../escape", content: "x"}},
+			wantErr: "outside",
+		},
+		{
+			name:    "parent directory inside name",
+			entries: []tarEntry{{name: "app/../../escape", content: "x"}},
+			wantErr: "outside",
+		},
+		{
+			name:    "absolute name",
+			entries: []tarEntry{{name: "/etc/escape", content: "x"}},
+			wantErr: "outside",
+		},
+		{
+			name:    "absolute symlink",
+			entries: []tarEntry{{name: "ssh", link: "/root/.ssh"}},
+			wantErr: "links outside",
+		},
+		{
+			name:    "relative symlink leaving dest",
+			entries: []tarEntry{{name: "app/up", link: "../../outside"}},
+			wantErr: "links outside",
+		},
+		{
+			name:    "write through a symlinked directory",
+			entries: []tarEntry{{name: "dir/"}, {name: "x", link: "dir"}, {name: "x/authorized_keys", content: "key"}},
+			wantErr: "below the symlink",
+		},
+		{
+			name:    "write through a symlinked file",
+			entries: []tarEntry{{name: "target", content: "keep"}, {name: "x", link: "target"}, {name: "x", content: "overwrite"}},
+			wantErr: "through the symlink",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dest := filepath.Join(t.TempDir(), "dest")
+			err := extractTar(buildTar(t, tt.entries), dest)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("extractTar() error = %v, want one containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("extractTar() error = %v", err)
+			}
+			for name, content := range tt.want {
+				got, err := os.ReadFile(filepath.Join(dest, filepath.FromSlash(name)))
+				if err != nil {
+					t.Fatalf("reading %s: %v", name, err)
+				}
+				if string(got) != content {
+					t.Errorf("%s = %q, want %q", name, got, content)
+				}
+			}
+		})
+	}
+}
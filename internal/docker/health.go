@@ -0,0 +1,210 @@
+package docker
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/filters"
+	"github.com/docker/docker/api/types/swarm"
+	"github.com/docker/docker/client"
+)
+
+// HealthState summarises how well a service or stack is running
+type HealthState string
+
+// Health states, from best to worst
+const (
+	HealthHealthy    HealthState = "healthy"
+	HealthConverging HealthState = "converging"
+	HealthDegraded   HealthState = "degraded"
+	HealthUnhealthy  HealthState = "unhealthy"
+)
+
+// Restart loop detection: this many failed tasks within the window make a service
+// unhealthy, fewer make it degraded
+const (
+	RestartLoopFailures = 3
+	RestartLoopWindow   = 10 * time.Minute
+)
+
+// severity orders the health states so the worst one wins
+var severity = map[HealthState]int{
+	HealthHealthy:    0,
+	HealthConverging: 1,
+	HealthDegraded:   2,
+	HealthUnhealthy:  3,
+}
+
+// ServiceHealth is the health of one stack service and the reasons for it
+type ServiceHealth struct {
+	Service string      `json:"service" yaml:"service"`
+	State   HealthState `json:"state" yaml:"state"`
+	Reasons []string    `json:"reasons,omitempty" yaml:"reasons,omitempty"`
+}
+
+// StackHealth is the health of a stack: the worst health of its services
+type StackHealth struct {
+	Stack    string          `json:"stack" yaml:"stack"`
+	State    HealthState     `json:"state" yaml:"state"`
+	Services []ServiceHealth `json:"services" yaml:"services"`
+}
+
+// worsen raises the state of a service to state if that is worse, recording why
+func (h *ServiceHealth) worsen(state HealthState, reason string) {
+	if severity[state] > severity[h.State] {
+		h.State = state
+	}
+	h.Reasons = append(h.Reasons, reason)
+}
+
+// ListStackHealth computes the health of every stack from its services' replica
+// counts, update status and task history, and from the healthchecks of containers.
+// Containers are passed in so the caller can include those reported by agents.
+func ListStackHealth(ctx context.Context, cli *client.Client, containers []types.Container) (map[string]StackHealth, error) {
+	services, err := cli.ServiceList(ctx, types.ServiceListOptions{
+		Filters: filters.NewArgs(filters.Arg("label", "com.docker.stack.namespace")),
+		Status:  true,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error listing services: %v", err)
+	}
+	if len(services) == 0 {
+		return map[string]StackHealth{}, nil
+	}
+
+	// Only the tasks of stack services, rather than of the whole swarm
+	taskFilter := filters.NewArgs()
+	for _, service := range services {
+		taskFilter.Add("service", service.ID)
+	}
+	tasks, err := cli.TaskList(ctx, types.TaskListOptions{Filters: taskFilter})
+	if err != nil {
+		return nil, fmt.Errorf("error listing tasks: %v", err)
+	}
+
+	return stackHealth(services, tasks, containers, time.Now()), nil
+}
+
+// stackHealth groups the service health by stack
+func stackHealth(services []swarm.Service, tasks []swarm.Task, containers []types.Container, now time.Time) map[string]StackHealth {
+	tasksByService := make(map[string][]swarm.Task)
+	for _, task := range tasks {
+		tasksByService[task.ServiceID] = append(tasksByService[task.ServiceID], task)
+	}
+	containersByService := make(map[string][]types.Container)
+	for _, c := range containers {
+		id := c.Labels["com.docker.swarm.service.id"]
+		containersByService[id] = append(containersByService[id], c)
+	}
+
+	stacks := make(map[string]StackHealth)
+	for _, service := range services {
+		stack, ok := service.Spec.Labels["com.docker.stack.namespace"]
+		if !ok {
+			continue
+		}
+
+		health := serviceHealth(service, tasksByService[service.ID], containersByService[service.ID], now)
+		sh := stacks[stack]
+		sh.Stack = stack
+		if sh.State == "" || severity[health.State] > severity[sh.State] {
+			sh.State = health.State
+		}
+		sh.Services = append(sh.Services, health)
+		stacks[stack] = sh
+	}
+	return stacks
+}
+
+// serviceHealth works out the health of one service
+func serviceHealth(service swarm.Service, tasks []swarm.Task, containers []types.Container, now time.Time) ServiceHealth {
+	health := ServiceHealth{Service: service.Spec.Name, State: HealthHealthy}
+
+	var desired, running uint64
+	if service.ServiceStatus != nil {
+		desired = service.ServiceStatus.DesiredTasks
+		running = service.ServiceStatus.RunningTasks
+	}
+
+	// Tasks that should run but have not reached the running state yet
+	var starting, failures int
+	for _, task := range tasks {
+		if task.DesiredState == swarm.TaskStateRunning && isStarting(task.Status.State) {
+			starting++
+		}
+		if (task.Status.State == swarm.TaskStateFailed || task.Status.State == swarm.TaskStateRejected) &&
+			now.Sub(task.Status.Timestamp) <= RestartLoopWindow {
+			failures++
+		}
+	}
+
+	updating := false
+	if update := service.UpdateStatus; update != nil {
+		switch update.State {
+		case swarm.UpdateStateUpdating, swarm.UpdateStateRollbackStarted:
+			updating = true
+			health.worsen(HealthConverging, "Update in progress")
+		case swarm.UpdateStatePaused, swarm.UpdateStateRollbackPaused:
+			health.worsen(HealthDegraded, fmt.Sprintf("Update paused: %s", update.Message))
+		}
+	}
+
+	if failures >= RestartLoopFailures {
+		health.worsen(HealthUnhealthy, fmt.Sprintf("Restart loop: %d tasks failed in the last %s", failures, RestartLoopWindow))
+	} else if failures > 0 {
+		health.worsen(HealthDegraded, fmt.Sprintf("%d task(s) failed in the last %s", failures, RestartLoopWindow))
+	}
+
+	if running < desired {
+		replicas := fmt.Sprintf("%d/%d replicas running", running, desired)
+		switch {
+		case updating || (starting > 0 && failures < RestartLoopFailures):
+			health.worsen(HealthConverging, fmt.Sprintf("%s, %d starting", replicas, starting))
+		case running == 0:
+			health.worsen(HealthUnhealthy, replicas)
+		default:
+			health.worsen(HealthDegraded, replicas)
+		}
+	}
+
+	var unhealthy, healthStarting, checked int
+	for _, c := range containers {
+		if c.State != "running" {
+			continue
+		}
+		switch ContainerHealth(c) {
+		case "unhealthy":
+			unhealthy++
+			checked++
+		case "starting":
+			healthStarting++
+			checked++
+		case "healthy":
+			checked++
+		}
+	}
+	if unhealthy > 0 {
+		state := HealthDegraded
+		if unhealthy == checked {
+			state = HealthUnhealthy
+		}
+		health.worsen(state, fmt.Sprintf("%d container(s) failing health checks", unhealthy))
+	}
+	if healthStarting > 0 {
+		health.worsen(HealthConverging, fmt.Sprintf("%d container(s) waiting for their first health check", healthStarting))
+	}
+
+	return health
+}
+
+// isStarting reports whether a task is on its way to running
+func isStarting(state swarm.TaskState) bool {
+	switch state {
+	case swarm.TaskStateNew, swarm.TaskStatePending, swarm.TaskStateAllocated, swarm.TaskStateAssigned,
+		swarm.TaskStateAccepted, swarm.TaskStatePreparing, swarm.TaskStateReady, swarm.TaskStateStarting:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,222 @@
+package docker
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/swarm"
+)
+
+var healthNow = time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+
+func testService(id, stack string, running, desired uint64) swarm.Service {
+	service := swarm.Service{
+		ID:            id,
+		ServiceStatus: &swarm.ServiceStatus{RunningTasks: running, DesiredTasks: desired},
+	}
+	service.Spec.Name = stack + "_" + id
+	service.Spec.Labels = map[string]string{"com.docker.stack.namespace": stack}
+	return service
+}
+
+func testTask(serviceID string, state swarm.TaskState, age time.Duration) swarm.Task {
+	return swarm.Task{
+		ServiceID:    serviceID,
+		DesiredState: swarm.TaskStateRunning,
+		Status:       swarm.TaskStatus{State: state, Timestamp: healthNow.Add(-age)},
+	}
+}
+
+func testContainer(serviceID, status string) types.Container {
+	return types.Container{
+		State:  "running",
+		Status: status,
+		Labels: map[string]string{"com.docker.swarm.service.id": serviceID},
+	}
+}
+
+func TestServiceHealth(t *testing.T) {
+	tests := []struct {
+		name       string
+		service    swarm.Service
+		tasks      []swarm.Task
+		containers []types.Container
+		want       HealthState
+		reasons    []string
+	}{
+		{
+			name:    "all tasks running",
+			service: testService("web", "shop", 3, 3),
+			tasks: []swarm.Task{
+				testTask("web", swarm.TaskStateRunning, time.Minute),
+				testTask("web", swarm.TaskStateRunning, time.Minute),
+				testTask("web", swarm.TaskStateRunning, time.Minute),
+			},
+			want: HealthHealthy,
+		},
+		{
+			name:    "no tasks",
+			service: testService("web", "shop", 0, 0),
+			want:    HealthHealthy,
+		},
+		{
+			name:    "no tasks although replicas are desired",
+			service: testService("web", "shop", 0, 2),
+			want:    HealthUnhealthy,
+			reasons: []string{"0/2 replicas running"},
+		},
+		{
+			name:    "no service status",
+			service: swarm.Service{ID: "web"},
+			want:    HealthHealthy,
+		},
+		{
+			name:    "replicas below desired",
+			service: testService("web", "shop", 2, 3),
+			tasks: []swarm.Task{
+				testTask("web", swarm.TaskStateRunning, time.Minute),
+				testTask("web", swarm.TaskStateRunning, time.Minute),
+			},
+			want:    HealthDegraded,
+			reasons: []string{"2/3 replicas running"},
+		},
+		{
+			name:    "replicas below desired while starting",
+			service: testService("web", "shop", 2, 3),
+			tasks: []swarm.Task{
+				testTask("web", swarm.TaskStateRunning, time.Minute),
+				testTask("web", swarm.TaskStateRunning, time.Minute),
+				testTask("web", swarm.TaskStatePreparing, time.Second),
+			},
+			want:    HealthConverging,
+			reasons: []string{"2/3 replicas running, 1 starting"},
+		},
+		{
+			name:    "one failed task",
+			service: testService("web", "shop", 3, 3),
+			tasks: []swarm.Task{
+				testTask("web", swarm.TaskStateRunning, time.Minute),
+				testTask("web", swarm.TaskStateFailed, 5*time.Minute),
+			},
+			want:    HealthDegraded,
+			reasons: []string{"1 task(s) failed in the last 10m0s"},
+		},
+		{
+			name:    "failed tasks outside the window",
+			service: testService("web", "shop", 3, 3),
+			tasks: []swarm.Task{
+				testTask("web", swarm.TaskStateFailed, time.Hour),
+				testTask("web", swarm.TaskStateFailed, time.Hour),
+				testTask("web", swarm.TaskStateFailed, time.Hour),
+			},
+			want: HealthHealthy,
+		},
+		{
+			name:    "restart loop",
+			service: testService("web", "shop", 0, 1),
+			tasks: []swarm.Task{
+				testTask("web", swarm.TaskStateFailed, time.Minute),
+				testTask("web", swarm.TaskStateRejected, 2*time.Minute),
+				testTask("web", swarm.TaskStateFailed, 3*time.Minute),
+				testTask("web", swarm.TaskStateStarting, time.Second),
+			},
+			want:    HealthUnhealthy,
+			reasons: []string{"Restart loop: 3 tasks failed in the last 10m0s", "0/1 replicas running"},
+		},
+		{
+			name:       "some containers failing health checks",
+			service:    testService("web", "shop", 2, 2),
+			containers: []types.Container{testContainer("web", "Up 1 hour (healthy)"), testContainer("web", "Up 1 hour (unhealthy)")},
+			want:       HealthDegraded,
+			reasons:    []string{"1 container(s) failing health checks"},
+		},
+		{
+			name:       "every container failing health checks",
+			service:    testService("web", "shop", 1, 1),
+			containers: []types.Container{testContainer("web", "Up 1 hour (unhealthy)")},
+			want:       HealthUnhealthy,
+			reasons:    []string{"1 container(s) failing health checks"},
+		},
+		{
+			name:       "waiting for the first health check",
+			service:    testService("web", "shop", 1, 1),
+			containers: []types.Container{testContainer("web", "Up 5 seconds (health: starting)")},
+			want:       HealthConverging,
+			reasons:    []string{"1 container(s) waiting for their first health check"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := serviceHealth(tt.service, tt.tasks, tt.containers, healthNow)
+			if got.State != tt.want {
+				t.Errorf("serviceHealth() state = %q, want %q", got.State, tt.want)
+			}
+			if !reflect.DeepEqual(got.Reasons, tt.reasons) {
+				t.Errorf("serviceHealth() reasons = %q, want %q", got.Reasons, tt.reasons)
+			}
+		})
+	}
+}
+
+func TestStackHealth(t *testing.T) {
+	unlabelled := testService("cron", "", 0, 1)
+	unlabelled.Spec.Labels = nil
+
+	tests := []struct {
+		name     string
+		services []swarm.Service
+		tasks    []swarm.Task
+		want     map[string]HealthState
+	}{
+		{
+			name:     "no services",
+			services: nil,
+			want:     map[string]HealthState{},
+		},
+		{
+			name:     "all running",
+			services: []swarm.Service{testService("web", "shop", 2, 2), testService("db", "shop", 1, 1)},
+			tasks: []swarm.Task{
+				testTask("web", swarm.TaskStateRunning, time.Minute),
+				testTask("web", swarm.TaskStateRunning, time.Minute),
+				testTask("db", swarm.TaskStateRunning, time.Minute),
+			},
+			want: map[string]HealthState{"shop": HealthHealthy},
+		},
+		{
+			name:     "worst service wins",
+			services: []swarm.Service{testService("web", "shop", 1, 2), testService("db", "shop", 0, 1)},
+			want:     map[string]HealthState{"shop": HealthUnhealthy},
+		},
+		{
+			name:     "failures stay within their stack",
+			services: []swarm.Service{testService("web", "shop", 1, 1), testService("api", "blog", 1, 1)},
+			tasks:    []swarm.Task{testTask("api", swarm.TaskStateFailed, time.Minute)},
+			want:     map[string]HealthState{"shop": HealthHealthy, "blog": HealthDegraded},
+		},
+		{
+			name:     "services outside stacks are skipped",
+			services: []swarm.Service{testService("web", "shop", 1, 1), unlabelled},
+			want:     map[string]HealthState{"shop": HealthHealthy},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stacks := stackHealth(tt.services, tt.tasks, nil, healthNow)
+			got := make(map[string]HealthState)
+			for name, stack := range stacks {
+				if stack.Stack != name {
+					t.Errorf("stack %q is named %q", name, stack.Stack)
+				}
+				got[name] = stack.State
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("stackHealth() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
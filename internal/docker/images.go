@@ -0,0 +1,231 @@
+package docker
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/filters"
+	"github.com/docker/docker/api/types/image"
+	"github.com/docker/docker/client"
+)
+
+// ImageInfo describes a local image and what is using it
+type ImageInfo struct {
+	ID         string
+	Tags       []string
+	Digests    []string
+	Size       int64
+	CreatedAt  time.Time
+	Containers []string
+	Services   []string
+}
+
+// Dangling reports whether the image has no tags
+func (i ImageInfo) Dangling() bool {
+	return len(i.Tags) == 0
+}
+
+// Unused reports whether no container or service uses the image. Images of services
+// count as used even when no task runs on this node, since swarm may schedule one here.
+func (i ImageInfo) Unused() bool {
+	return len(i.Containers) == 0 && len(i.Services) == 0
+}
+
+// ImageLayer is one entry in an image's layer history
+type ImageLayer struct {
+	ID        string
+	CreatedAt time.Time
+	CreatedBy string
+	Size      int64
+	Comment   string
+}
+
+// PruneResult summarises a prune operation
+type PruneResult struct {
+	Deleted        int
+	SpaceReclaimed uint64
+}
+
+// ListImages returns all local images along with the containers and services using them
+func ListImages(ctx context.Context, cli *client.Client) ([]ImageInfo, error) {
+	summaries, err := cli.ImageList(ctx, image.ListOptions{All: false})
+	if err != nil {
+		return nil, fmt.Errorf("error listing images: %v", err)
+	}
+
+	containers, err := cli.ContainerList(ctx, container.ListOptions{All: true})
+	if err != nil {
+		return nil, fmt.Errorf("error listing containers: %v", err)
+	}
+
+	containerUsers := make(map[string][]string)
+	for _, c := range containers {
+		name := c.ID[:12]
+		if len(c.Names) > 0 {
+			name = strings.TrimPrefix(c.Names[0], "/")
+		}
+		containerUsers[c.ImageID] = append(containerUsers[c.ImageID], name)
+	}
+
+	// Nodes that are not swarm managers cannot list services, so images are
+	// still shown without service usage in that case
+	services, _ := cli.ServiceList(ctx, types.ServiceListOptions{})
+
+	images := make([]ImageInfo, 0, len(summaries))
+	for _, summary := range summaries {
+		info := ImageInfo{
+			ID:         summary.ID,
+			Digests:    summary.RepoDigests,
+			Size:       summary.Size,
+			CreatedAt:  time.Unix(summary.Created, 0),
+			Containers: containerUsers[summary.ID],
+		}
+		for _, tag := range summary.RepoTags {
+			if tag != "<none>:<none>" {
+				info.Tags = append(info.Tags, tag)
+			}
+		}
+
+		for _, service := range services {
+			spec := service.Spec.TaskTemplate.ContainerSpec
+			if spec != nil && imageMatches(spec.Image, info) {
+				info.Services = append(info.Services, service.Spec.Name)
+			}
+		}
+
+		images = append(images, info)
+	}
+
+	sort.Slice(images, func(i, j int) bool { return images[i].CreatedAt.After(images[j].CreatedAt) })
+	return images, nil
+}
+
+// imageMatches reports whether a service image reference such as
+// "alpine:latest@sha256:..." refers to the given local image
+func imageMatches(ref string, info ImageInfo) bool {
+	name, digest, _ := strings.Cut(ref, "@")
+	if digest != "" {
+		for _, repoDigest := range info.Digests {
+			if strings.HasSuffix(repoDigest, "@"+digest) {
+				return true
+			}
+		}
+	}
+
+	if !strings.Contains(name[strings.LastIndex(name, "/")+1:], ":") {
+		name += ":latest"
+	}
+	for _, tag := range info.Tags {
+		if tag == name {
+			return true
+		}
+	}
+	return false
+}
+
+// ImageHistory returns the layer history of an image, newest layer first
+func ImageHistory(ctx context.Context, cli *client.Client, imageID string) ([]ImageLayer, error) {
+	history, err := cli.ImageHistory(ctx, imageID)
+	if err != nil {
+		return nil, fmt.Errorf("error getting history for image %s: %v", imageID, err)
+	}
+
+	layers := make([]ImageLayer, 0, len(history))
+	for _, item := range history {
+		layers = append(layers, ImageLayer{
+			ID:        item.ID,
+			CreatedAt: time.Unix(item.Created, 0),
+			CreatedBy: item.CreatedBy,
+			Size:      item.Size,
+			Comment:   item.Comment,
+		})
+	}
+	return layers, nil
+}
+
+// RemoveImage removes an image and its untagged parents
+func RemoveImage(ctx context.Context, cli *client.Client, imageID string, force bool) error {
+	_, err := cli.ImageRemove(ctx, imageID, image.RemoveOptions{Force: force, PruneChildren: true})
+	if err != nil {
+		return fmt.Errorf("error removing image %s: %v", imageID, err)
+	}
+	return nil
+}
+
+// PruneImages removes dangling images, or with all every image that no container or
+// service uses
+func PruneImages(ctx context.Context, cli *client.Client, all bool) (PruneResult, error) {
+	if all {
+		return pruneUnusedImages(ctx, cli)
+	}
+
+	report, err := cli.ImagesPrune(ctx, filters.NewArgs(filters.Arg("dangling", "true")))
+	if err != nil {
+		return PruneResult{}, fmt.Errorf("error pruning images: %v", err)
+	}
+
+	deleted := 0
+	for _, item := range report.ImagesDeleted {
+		if item.Deleted != "" {
+			deleted++
+		}
+	}
+	return PruneResult{Deleted: deleted, SpaceReclaimed: report.SpaceReclaimed}, nil
+}
+
+// pruneUnusedImages removes the unused images one by one, since the prune endpoint
+// knows nothing of services. The space freed is measured on the image layers, which
+// removed images may share.
+func pruneUnusedImages(ctx context.Context, cli *client.Client) (PruneResult, error) {
+	images, err := ListImages(ctx, cli)
+	if err != nil {
+		return PruneResult{}, err
+	}
+	before, err := layersSize(ctx, cli)
+	if err != nil {
+		return PruneResult{}, err
+	}
+
+	// Images with several tags are removed tag by tag; removing them by ID would need force
+	var result PruneResult
+	var failed error
+	for _, img := range images {
+		if !img.Unused() {
+			continue
+		}
+		refs := img.Tags
+		if img.Dangling() {
+			refs = []string{img.ID}
+		}
+		for _, ref := range refs {
+			if err = RemoveImage(ctx, cli, ref, false); err != nil {
+				break
+			}
+		}
+		if err != nil {
+			failed = err
+			continue
+		}
+		result.Deleted++
+	}
+
+	after, err := layersSize(ctx, cli)
+	if err == nil && after < before {
+		result.SpaceReclaimed = uint64(before - after)
+	}
+	return result, failed
+}
+
+// layersSize is the disk space taken by every image layer
+func layersSize(ctx context.Context, cli *client.Client) (int64, error) {
+	usage, err := cli.DiskUsage(ctx, types.DiskUsageOptions{Types: []types.DiskUsageObject{types.ImageObject}})
+	if err != nil {
+		return 0, fmt.Errorf("error getting disk usage: %v", err)
+	}
+	return usage.LayersSize, nil
+}
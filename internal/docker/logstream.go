@@ -0,0 +1,134 @@
+package docker
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"strconv"
+	"sync"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/filters"
+	"github.com/docker/docker/api/types/swarm"
+	"github.com/docker/docker/client"
+	"github.com/docker/docker/pkg/stdcopy"
+)
+
+// FollowContainerLogs sends the last tail log lines of a container and then every new
+// line to lines, until ctx is cancelled or the container stops
+func FollowContainerLogs(ctx context.Context, cli *client.Client, containerID string, tail int, lines chan<- string) error {
+	details, err := cli.ContainerInspect(ctx, containerID)
+	if err != nil {
+		return fmt.Errorf("error inspecting container %s: %v", containerID, err)
+	}
+
+	logs, err := cli.ContainerLogs(ctx, containerID, container.LogsOptions{
+		ShowStdout: true,
+		ShowStderr: true,
+		Follow:     true,
+		Tail:       strconv.Itoa(tail),
+		Timestamps: true,
+	})
+	if err != nil {
+		return fmt.Errorf("error getting logs for container %s: %v", containerID, err)
+	}
+	defer logs.Close()
+
+	return scanLogs(ctx, logs, details.Config.Tty, "", lines)
+}
+
+// FollowStackLogs follows the logs of every service in a stack at once; each line is
+// prefixed with the name of the service it came from
+func FollowStackLogs(ctx context.Context, cli *client.Client, stackName string, tail int, lines chan<- string) error {
+	serviceFilter := filters.NewArgs()
+	serviceFilter.Add("label", fmt.Sprintf("com.docker.stack.namespace=%s", stackName))
+
+	services, err := cli.ServiceList(ctx, types.ServiceListOptions{
+		Filters: serviceFilter,
+	})
+	if err != nil {
+		return fmt.Errorf("error listing services for stack %s: %v", stackName, err)
+	}
+
+	var wg sync.WaitGroup
+	errs := make(chan error, len(services))
+	for _, service := range services {
+		logs, err := cli.ServiceLogs(ctx, service.ID, container.LogsOptions{
+			ShowStdout: true,
+			ShowStderr: true,
+			Follow:     true,
+			Tail:       strconv.Itoa(tail),
+		})
+		if err != nil {
+			return fmt.Errorf("error getting logs for service %s: %v", service.Spec.Name, err)
+		}
+
+		tty := service.Spec.TaskTemplate.ContainerSpec != nil && service.Spec.TaskTemplate.ContainerSpec.TTY
+		wg.Add(1)
+		go func(name string, logs io.ReadCloser) {
+			defer wg.Done()
+			defer logs.Close()
+			errs <- scanLogs(ctx, logs, tty, name+" | ", lines)
+		}(service.Spec.Name, logs)
+	}
+
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// FollowServiceLogs sends the last tail log lines of a service and then every new line
+// to lines, prefixed with the name of the service, until ctx is cancelled or the
+// service is removed
+func FollowServiceLogs(ctx context.Context, cli *client.Client, service swarm.Service, tail int, lines chan<- string) error {
+	logs, err := cli.ServiceLogs(ctx, service.ID, container.LogsOptions{
+		ShowStdout: true,
+		ShowStderr: true,
+		Follow:     true,
+		Tail:       strconv.Itoa(tail),
+	})
+	if err != nil {
+		return fmt.Errorf("error getting logs for service %s: %v", service.Spec.Name, err)
+	}
+	defer logs.Close()
+
+	tty := service.Spec.TaskTemplate.ContainerSpec != nil && service.Spec.TaskTemplate.ContainerSpec.TTY
+	return scanLogs(ctx, logs, tty, service.Spec.Name+" | ", lines)
+}
+
+// scanLogs splits a log stream into lines; streams of containers without a TTY
+// multiplex stdout and stderr and are demultiplexed first
+func scanLogs(ctx context.Context, logs io.Reader, tty bool, prefix string, lines chan<- string) error {
+	reader := logs
+	if !tty {
+		pr, pw := io.Pipe()
+		go func() {
+			_, err := stdcopy.StdCopy(pw, pw, logs)
+			pw.CloseWithError(err)
+		}()
+		defer pr.Close()
+		reader = pr
+	}
+
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		select {
+		case lines <- prefix + scanner.Text():
+		case <-ctx.Done():
+			return nil
+		}
+	}
+
+	if err := scanner.Err(); err != nil && ctx.Err() == nil {
+		return fmt.Errorf("error reading logs: %v", err)
+	}
+	return nil
+}
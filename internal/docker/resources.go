@@ -0,0 +1,136 @@
+package docker
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/client"
+	"github.com/docker/docker/pkg/stdcopy"
+)
+
+// ResourceStats is a point-in-time sample of a container's resource usage
+type ResourceStats struct {
+	ContainerID string    `json:"containerId" yaml:"containerId"`
+	Time        time.Time `json:"time" yaml:"time"`
+	CPUPercent  float64   `json:"cpuPercent" yaml:"cpuPercent"`
+	MemoryUsage uint64    `json:"memoryUsage" yaml:"memoryUsage"`
+	MemoryLimit uint64    `json:"memoryLimit" yaml:"memoryLimit"`
+	NetworkRx   uint64    `json:"networkRx" yaml:"networkRx"`
+	NetworkTx   uint64    `json:"networkTx" yaml:"networkTx"`
+	BlockRead   uint64    `json:"blockRead" yaml:"blockRead"`
+	BlockWrite  uint64    `json:"blockWrite" yaml:"blockWrite"`
+}
+
+// MemoryPercent returns memory usage as a share of the limit
+func (s ResourceStats) MemoryPercent() float64 {
+	if s.MemoryLimit == 0 {
+		return 0
+	}
+	return float64(s.MemoryUsage) / float64(s.MemoryLimit) * 100
+}
+
+// ExecResult is the outcome of a command run inside a container
+type ExecResult struct {
+	ExitCode int    `json:"exitCode"`
+	Stdout   string `json:"stdout"`
+	Stderr   string `json:"stderr"`
+}
+
+// GetResourceStats samples the resource usage of a container. The daemon waits
+// for a second sample so the CPU percentage can be computed like `docker stats`.
+func GetResourceStats(ctx context.Context, cli *client.Client, containerID string) (ResourceStats, error) {
+	resp, err := cli.ContainerStats(ctx, containerID, false)
+	if err != nil {
+		return ResourceStats{}, fmt.Errorf("error getting stats for container %s: %v", containerID, err)
+	}
+	defer resp.Body.Close()
+
+	var raw container.StatsResponse
+	if err := json.NewDecoder(resp.Body).Decode(&raw); err != nil {
+		return ResourceStats{}, fmt.Errorf("error decoding stats for container %s: %v", containerID, err)
+	}
+
+	stats := ResourceStats{
+		ContainerID: containerID,
+		Time:        raw.Read,
+		MemoryUsage: raw.MemoryStats.Usage,
+		MemoryLimit: raw.MemoryStats.Limit,
+	}
+
+	// Page cache is reclaimable, so it is left out of the usage like the Docker CLI does
+	if cache, ok := raw.MemoryStats.Stats["inactive_file"]; ok && cache < stats.MemoryUsage {
+		stats.MemoryUsage -= cache
+	} else if cache, ok := raw.MemoryStats.Stats["total_inactive_file"]; ok && cache < stats.MemoryUsage {
+		stats.MemoryUsage -= cache
+	}
+
+	cpuDelta := float64(raw.CPUStats.CPUUsage.TotalUsage) - float64(raw.PreCPUStats.CPUUsage.TotalUsage)
+	systemDelta := float64(raw.CPUStats.SystemUsage) - float64(raw.PreCPUStats.SystemUsage)
+	onlineCPUs := float64(raw.CPUStats.OnlineCPUs)
+	if onlineCPUs == 0 {
+		onlineCPUs = float64(len(raw.CPUStats.CPUUsage.PercpuUsage))
+	}
+	if cpuDelta > 0 && systemDelta > 0 {
+		stats.CPUPercent = cpuDelta / systemDelta * onlineCPUs * 100
+	}
+
+	for _, network := range raw.Networks {
+		stats.NetworkRx += network.RxBytes
+		stats.NetworkTx += network.TxBytes
+	}
+
+	for _, entry := range raw.BlkioStats.IoServiceBytesRecursive {
+		switch strings.ToLower(entry.Op) {
+		case "read":
+			stats.BlockRead += entry.Value
+		case "write":
+			stats.BlockWrite += entry.Value
+		}
+	}
+
+	return stats, nil
+}
+
+// ExecInContainer runs a command inside a container and waits for it to finish
+func ExecInContainer(ctx context.Context, cli *client.Client, containerID string, cmd []string) (ExecResult, error) {
+	exec, err := cli.ContainerExecCreate(ctx, containerID, container.ExecOptions{
+		Cmd:          cmd,
+		AttachStdout: true,
+		AttachStderr: true,
+	})
+	if err != nil {
+		return ExecResult{}, fmt.Errorf("error creating exec in container %s: %v", containerID, err)
+	}
+
+	attach, err := cli.ContainerExecAttach(ctx, exec.ID, container.ExecAttachOptions{})
+	if err != nil {
+		return ExecResult{}, fmt.Errorf("error attaching to exec in container %s: %v", containerID, err)
+	}
+	defer attach.Close()
+
+	var stdout, stderr bytes.Buffer
+	if _, err := stdcopy.StdCopy(&stdout, &stderr, attach.Reader); err != nil {
+		return ExecResult{}, fmt.Errorf("error reading exec output: %v", err)
+	}
+
+	inspect, err := cli.ContainerExecInspect(ctx, exec.ID)
+	if err != nil {
+		return ExecResult{}, fmt.Errorf("error inspecting exec in container %s: %v", containerID, err)
+	}
+
+	return ExecResult{ExitCode: inspect.ExitCode, Stdout: stdout.String(), Stderr: stderr.String()}, nil
+}
+
+// NodeName returns the name of the node the Docker daemon runs on
+func NodeName(ctx context.Context, cli *client.Client) (string, error) {
+	info, err := cli.Info(ctx)
+	if err != nil {
+		return "", fmt.Errorf("error getting daemon info: %v", err)
+	}
+	return info.Name, nil
+}
@@ -0,0 +1,241 @@
+package docker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/swarm"
+	"github.com/docker/docker/client"
+)
+
+// SwarmResource describes a swarm secret or config and the services consuming it
+type SwarmResource struct {
+	Kind      string // "secret" or "config"
+	ID        string
+	Name      string
+	Labels    map[string]string
+	CreatedAt time.Time
+	Services  []string
+}
+
+// secretVersionPattern matches a trailing "_v<n>" version suffix on a secret name
+var secretVersionPattern = regexp.MustCompile(`^(.*)_v(\d+)$`)
+
+// ListSecrets returns all swarm secrets along with their consuming services
+func ListSecrets(ctx context.Context, cli *client.Client) ([]SwarmResource, error) {
+	secrets, err := cli.SecretList(ctx, types.SecretListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("error listing secrets: %v", err)
+	}
+
+	secretUsers, _, err := resourceConsumers(ctx, cli)
+	if err != nil {
+		return nil, err
+	}
+
+	resources := make([]SwarmResource, 0, len(secrets))
+	for _, secret := range secrets {
+		resources = append(resources, SwarmResource{
+			Kind:      "secret",
+			ID:        secret.ID,
+			Name:      secret.Spec.Name,
+			Labels:    secret.Spec.Labels,
+			CreatedAt: secret.CreatedAt,
+			Services:  secretUsers[secret.ID],
+		})
+	}
+
+	sort.Slice(resources, func(i, j int) bool { return resources[i].Name < resources[j].Name })
+	return resources, nil
+}
+
+// ListConfigs returns all swarm configs along with their consuming services
+func ListConfigs(ctx context.Context, cli *client.Client) ([]SwarmResource, error) {
+	configs, err := cli.ConfigList(ctx, types.ConfigListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("error listing configs: %v", err)
+	}
+
+	_, configUsers, err := resourceConsumers(ctx, cli)
+	if err != nil {
+		return nil, err
+	}
+
+	resources := make([]SwarmResource, 0, len(configs))
+	for _, config := range configs {
+		resources = append(resources, SwarmResource{
+			Kind:      "config",
+			ID:        config.ID,
+			Name:      config.Spec.Name,
+			Labels:    config.Spec.Labels,
+			CreatedAt: config.CreatedAt,
+			Services:  configUsers[config.ID],
+		})
+	}
+
+	sort.Slice(resources, func(i, j int) bool { return resources[i].Name < resources[j].Name })
+	return resources, nil
+}
+
+// resourceConsumers maps secret and config IDs to the names of the services referencing them
+func resourceConsumers(ctx context.Context, cli *client.Client) (map[string][]string, map[string][]string, error) {
+	services, err := cli.ServiceList(ctx, types.ServiceListOptions{})
+	if err != nil {
+		return nil, nil, fmt.Errorf("error listing services: %v", err)
+	}
+
+	secretUsers := make(map[string][]string)
+	configUsers := make(map[string][]string)
+	for _, service := range services {
+		spec := service.Spec.TaskTemplate.ContainerSpec
+		if spec == nil {
+			continue
+		}
+		for _, ref := range spec.Secrets {
+			secretUsers[ref.SecretID] = append(secretUsers[ref.SecretID], service.Spec.Name)
+		}
+		for _, ref := range spec.Configs {
+			configUsers[ref.ConfigID] = append(configUsers[ref.ConfigID], service.Spec.Name)
+		}
+	}
+
+	return secretUsers, configUsers, nil
+}
+
+// ViewConfigContent returns the content of a swarm config
+func ViewConfigContent(ctx context.Context, cli *client.Client, configID string) (string, error) {
+	config, _, err := cli.ConfigInspectWithRaw(ctx, configID)
+	if err != nil {
+		return "", fmt.Errorf("error inspecting config %s: %v", configID, err)
+	}
+	return string(config.Spec.Data), nil
+}
+
+// CreateConfigFromFile creates a swarm config from the content of a local file
+func CreateConfigFromFile(ctx context.Context, cli *client.Client, name, path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("error reading %s: %v", path, err)
+	}
+
+	resp, err := cli.ConfigCreate(ctx, swarm.ConfigSpec{
+		Annotations: swarm.Annotations{Name: name},
+		Data:        data,
+	})
+	if err != nil {
+		return "", fmt.Errorf("error creating config %s: %v", name, err)
+	}
+	return resp.ID, nil
+}
+
+// CreateSecretFromFile creates a swarm secret from the content of a local file
+func CreateSecretFromFile(ctx context.Context, cli *client.Client, name, path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("error reading %s: %v", path, err)
+	}
+
+	resp, err := cli.SecretCreate(ctx, swarm.SecretSpec{
+		Annotations: swarm.Annotations{Name: name},
+		Data:        data,
+	})
+	if err != nil {
+		return "", fmt.Errorf("error creating secret %s: %v", name, err)
+	}
+	return resp.ID, nil
+}
+
+// SecretRotation is the outcome of rotating a secret
+type SecretRotation struct {
+	NewName string
+	// Services now using the new secret, and those left on the old one
+	Updated []string
+	Failed  []string
+	// Whether the new secret was removed again because no service could use it
+	Removed bool
+}
+
+// RotateSecret creates a new version of a secret from a local file and points
+// every consuming service at it. The mount target inside the containers is kept,
+// so services see the new value at the same path. A service that cannot be updated
+// does not stop the others; when none could be, the new secret is removed again.
+func RotateSecret(ctx context.Context, cli *client.Client, secretID, path string) (SecretRotation, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return SecretRotation{}, fmt.Errorf("error reading %s: %v", path, err)
+	}
+
+	old, _, err := cli.SecretInspectWithRaw(ctx, secretID)
+	if err != nil {
+		return SecretRotation{}, fmt.Errorf("error inspecting secret %s: %v", secretID, err)
+	}
+
+	rotation := SecretRotation{NewName: nextSecretVersion(old.Spec.Name)}
+	resp, err := cli.SecretCreate(ctx, swarm.SecretSpec{
+		Annotations: swarm.Annotations{Name: rotation.NewName, Labels: old.Spec.Labels},
+		Data:        data,
+	})
+	if err != nil {
+		return SecretRotation{}, fmt.Errorf("error creating secret %s: %v", rotation.NewName, err)
+	}
+
+	services, err := cli.ServiceList(ctx, types.ServiceListOptions{})
+	if err != nil {
+		rotation.Removed = cli.SecretRemove(ctx, resp.ID) == nil
+		return rotation, fmt.Errorf("error listing services: %v", err)
+	}
+
+	var errs []error
+	for _, service := range services {
+		spec := service.Spec
+		if spec.TaskTemplate.ContainerSpec == nil {
+			continue
+		}
+
+		updated := false
+		for _, ref := range spec.TaskTemplate.ContainerSpec.Secrets {
+			if ref.SecretID != old.ID {
+				continue
+			}
+			if ref.File != nil && ref.File.Name == "" {
+				ref.File.Name = old.Spec.Name
+			}
+			ref.SecretID = resp.ID
+			ref.SecretName = rotation.NewName
+			updated = true
+		}
+		if !updated {
+			continue
+		}
+
+		if _, err := cli.ServiceUpdate(ctx, service.ID, service.Version, spec, types.ServiceUpdateOptions{}); err != nil {
+			rotation.Failed = append(rotation.Failed, spec.Name)
+			errs = append(errs, fmt.Errorf("error updating service %s: %v", spec.Name, err))
+			continue
+		}
+		rotation.Updated = append(rotation.Updated, spec.Name)
+	}
+
+	if len(rotation.Failed) > 0 && len(rotation.Updated) == 0 {
+		rotation.Removed = cli.SecretRemove(ctx, resp.ID) == nil
+	}
+	return rotation, errors.Join(errs...)
+}
+
+// nextSecretVersion derives the name of the next version of a secret,
+// e.g. "db_password" becomes "db_password_v2" and "db_password_v2" becomes "db_password_v3"
+func nextSecretVersion(name string) string {
+	if match := secretVersionPattern.FindStringSubmatch(name); match != nil {
+		if version, err := strconv.Atoi(match[2]); err == nil {
+			return fmt.Sprintf("%s_v%d", match[1], version+1)
+		}
+	}
+	return name + "_v2"
+}
@@ -0,0 +1,144 @@
+package docker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/filters"
+	"github.com/docker/docker/api/types/swarm"
+	"github.com/docker/docker/client"
+)
+
+// ServiceStatus compares the desired and running replicas of a stack service
+type ServiceStatus struct {
+	ID          string `json:"id" yaml:"id"`
+	Stack       string `json:"stack" yaml:"stack"`
+	Service     string `json:"service" yaml:"service"`
+	Mode        string `json:"mode" yaml:"mode"`
+	Desired     uint64 `json:"desired" yaml:"desired"`
+	Running     uint64 `json:"running" yaml:"running"`
+	FailedTasks int    `json:"failedTasks" yaml:"failedTasks"`
+}
+
+// ListServiceStatus returns the replica counts of every stack service, sorted by stack
+// and service. Failed tasks are counted over the task history swarm retains, so they
+// show services that keep crashing and being rescheduled.
+func ListServiceStatus(ctx context.Context, cli *client.Client) ([]ServiceStatus, error) {
+	services, err := cli.ServiceList(ctx, types.ServiceListOptions{Status: true})
+	if err != nil {
+		return nil, fmt.Errorf("error listing services: %v", err)
+	}
+
+	tasks, err := cli.TaskList(ctx, types.TaskListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("error listing tasks: %v", err)
+	}
+	failed := make(map[string]int)
+	for _, task := range tasks {
+		if task.Status.State == swarm.TaskStateFailed || task.Status.State == swarm.TaskStateRejected {
+			failed[task.ServiceID]++
+		}
+	}
+
+	var statuses []ServiceStatus
+	for _, service := range services {
+		stack, ok := service.Spec.Labels["com.docker.stack.namespace"]
+		if !ok {
+			continue
+		}
+
+		status := ServiceStatus{
+			ID:          service.ID,
+			Stack:       stack,
+			Service:     service.Spec.Name,
+			Mode:        "replicated",
+			FailedTasks: failed[service.ID],
+		}
+		if service.Spec.Mode.Global != nil {
+			status.Mode = "global"
+		}
+		if service.ServiceStatus != nil {
+			status.Desired = service.ServiceStatus.DesiredTasks
+			status.Running = service.ServiceStatus.RunningTasks
+		}
+		statuses = append(statuses, status)
+	}
+
+	sort.Slice(statuses, func(i, j int) bool {
+		if statuses[i].Stack != statuses[j].Stack {
+			return statuses[i].Stack < statuses[j].Stack
+		}
+		return statuses[i].Service < statuses[j].Service
+	})
+	return statuses, nil
+}
+
+// ListStackServices returns the services of every stack
+func ListStackServices(ctx context.Context, cli *client.Client) ([]swarm.Service, error) {
+	serviceFilter := filters.NewArgs()
+	serviceFilter.Add("label", "com.docker.stack.namespace")
+
+	services, err := cli.ServiceList(ctx, types.ServiceListOptions{Filters: serviceFilter})
+	if err != nil {
+		return nil, fmt.Errorf("error listing services: %v", err)
+	}
+	return services, nil
+}
+
+// ErrServiceNotFound is returned when a stack has no service of the requested name
+var ErrServiceNotFound = errors.New("service not found")
+
+// ErrGlobalService is returned when scaling a service that runs one task per node
+var ErrGlobalService = errors.New("global services run one task per node and cannot be scaled")
+
+// FindStackService returns a service of a stack by its full name ("stack_web") or
+// by its name within the stack ("web")
+func FindStackService(ctx context.Context, cli *client.Client, stackName, name string) (swarm.Service, error) {
+	serviceFilter := filters.NewArgs()
+	serviceFilter.Add("label", fmt.Sprintf("com.docker.stack.namespace=%s", stackName))
+
+	services, err := cli.ServiceList(ctx, types.ServiceListOptions{Filters: serviceFilter})
+	if err != nil {
+		return swarm.Service{}, fmt.Errorf("error listing services for stack %s: %v", stackName, err)
+	}
+
+	for _, service := range services {
+		if service.Spec.Name == name || service.Spec.Name == stackName+"_"+name {
+			return service, nil
+		}
+	}
+	return swarm.Service{}, fmt.Errorf("%w: %s in stack %s", ErrServiceNotFound, name, stackName)
+}
+
+// ScaleService sets the number of replicas of a replicated service
+func ScaleService(ctx context.Context, cli *client.Client, service swarm.Service, replicas uint64) error {
+	if service.Spec.Mode.Replicated == nil {
+		return ErrGlobalService
+	}
+
+	spec := service.Spec
+	spec.Mode.Replicated.Replicas = &replicas
+	if _, err := cli.ServiceUpdate(ctx, service.ID, service.Version, spec, types.ServiceUpdateOptions{}); err != nil {
+		return fmt.Errorf("error scaling service %s: %v", service.Spec.Name, err)
+	}
+	return nil
+}
+
+// DeployImage rolls a service out to a new image, using the service's update config
+func DeployImage(ctx context.Context, cli *client.Client, service swarm.Service, image string) error {
+	spec := service.Spec
+	if spec.TaskTemplate.ContainerSpec == nil {
+		return fmt.Errorf("service %s does not run containers", service.Spec.Name)
+	}
+	spec.TaskTemplate.ContainerSpec.Image = image
+
+	// Resolve the tag to a digest on the manager so every node runs the same image
+	opts := types.ServiceUpdateOptions{QueryRegistry: true}
+	if _, err := cli.ServiceUpdate(ctx, service.ID, service.Version, spec, opts); err != nil {
+		return fmt.Errorf("error deploying %s to service %s: %v", image, service.Spec.Name, err)
+	}
+	return nil
+}
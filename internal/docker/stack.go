@@ -4,15 +4,19 @@ import (
 	"context"
 	"fmt"
 	"io"
+	"sort"
+	"strconv"
 	"strings"
 
 	"github.com/docker/docker/api/types"
 	"github.com/docker/docker/api/types/container"
 	"github.com/docker/docker/api/types/filters"
+	"github.com/docker/docker/api/types/swarm"
 	"github.com/docker/docker/client"
+	"github.com/docker/docker/pkg/stdcopy"
 )
 
-// ListStacks returns all Docker stacks
+// ListStacks returns all Docker stacks, sorted by name
 func ListStacks(ctx context.Context, cli *client.Client) ([]string, error) {
 	services, err := cli.ServiceList(ctx, types.ServiceListOptions{})
 	if err != nil {
@@ -30,6 +34,7 @@ func ListStacks(ctx context.Context, cli *client.Client) ([]string, error) {
 	for stackName := range stackMap {
 		stacks = append(stacks, stackName)
 	}
+	sort.Strings(stacks)
 
 	return stacks, nil
 }
@@ -48,6 +53,22 @@ func ListContainers(ctx context.Context, cli *client.Client, stackName string) (
 	return containers, nil
 }
 
+// ListAllStackContainers returns the running containers of every stack on this node,
+// or with all the stopped ones as well
+func ListAllStackContainers(ctx context.Context, cli *client.Client, all bool) ([]types.Container, error) {
+	containerFilter := filters.NewArgs()
+	containerFilter.Add("label", "com.docker.stack.namespace")
+
+	containers, err := cli.ContainerList(ctx, container.ListOptions{
+		All:     all,
+		Filters: containerFilter,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error listing stack containers: %v", err)
+	}
+	return containers, nil
+}
+
 // KillStack kills a Docker stack by removing all its services
 func KillStack(ctx context.Context, cli *client.Client, stackName string) error {
 	serviceFilter := filters.NewArgs()
@@ -69,17 +90,46 @@ func KillStack(ctx context.Context, cli *client.Client, stackName string) error
 	return nil
 }
 
-// RestartStack restarts a Docker stack
+// RestartStack restarts a Docker stack by forcing a rolling update of each of its services
 func RestartStack(ctx context.Context, cli *client.Client, stackName string) error {
-	if err := KillStack(ctx, cli, stackName); err != nil {
-		return fmt.Errorf("error killing stack: %v", err)
+	serviceFilter := filters.NewArgs()
+	serviceFilter.Add("label", fmt.Sprintf("com.docker.stack.namespace=%s", stackName))
+
+	services, err := cli.ServiceList(ctx, types.ServiceListOptions{
+		Filters: serviceFilter,
+	})
+	if err != nil {
+		return fmt.Errorf("error listing services for stack %s: %v", stackName, err)
+	}
+
+	for _, service := range services {
+		spec := service.Spec
+		spec.TaskTemplate.ForceUpdate++
+		if _, err := cli.ServiceUpdate(ctx, service.ID, service.Version, spec, types.ServiceUpdateOptions{}); err != nil {
+			return fmt.Errorf("error restarting service %s: %v", service.Spec.Name, err)
+		}
 	}
 
-	return fmt.Errorf("full stack restart requires external deployment mechanism")
+	return nil
+}
+
+// LogOptions selects the log lines returned by StackLogs and ContainerLogs
+type LogOptions struct {
+	// Tail is the number of lines to return from the end of the logs, negative for all
+	Tail int
+	// Since and Until are Unix or RFC 3339 timestamps, or durations like "10m" before now
+	Since      string
+	Until      string
+	Timestamps bool
 }
 
-// ViewStackLogs returns logs for all services in a stack
-func ViewStackLogs(ctx context.Context, cli *client.Client, stackName string) (string, error) {
+// ViewStackLogs returns the last tail log lines of every service in a stack
+func ViewStackLogs(ctx context.Context, cli *client.Client, stackName string, tail int) (string, error) {
+	return StackLogs(ctx, cli, stackName, LogOptions{Tail: tail})
+}
+
+// StackLogs returns the log lines of every service in a stack, one section per service
+func StackLogs(ctx context.Context, cli *client.Client, stackName string, opts LogOptions) (string, error) {
 	serviceFilter := filters.NewArgs()
 	serviceFilter.Add("label", fmt.Sprintf("com.docker.stack.namespace=%s", stackName))
 
@@ -93,50 +143,106 @@ func ViewStackLogs(ctx context.Context, cli *client.Client, stackName string) (s
 	var logBuilder strings.Builder
 
 	for _, service := range services {
-		logs, err := cli.ServiceLogs(ctx, service.ID, container.LogsOptions{
-			ShowStdout: true,
-			ShowStderr: true,
-			Tail:       "50",
-		})
+		logs, err := ServiceLogs(ctx, cli, service, opts)
 		if err != nil {
 			logBuilder.WriteString(fmt.Sprintf("Error getting logs for service %s: %v\n", service.Spec.Name, err))
 			continue
 		}
-		defer func(logs io.ReadCloser) {
-			_ = logs.Close()
-		}(logs)
 
 		logBuilder.WriteString(fmt.Sprintf("Logs for service: %s\n", service.Spec.Name))
-		logBytes, err := io.ReadAll(logs)
-		if err != nil {
-			logBuilder.WriteString(fmt.Sprintf("Error reading logs: %v\n", err))
-		} else {
-			logBuilder.Write(logBytes)
-		}
+		logBuilder.WriteString(logs)
 		logBuilder.WriteString("\n---\n")
 	}
 
 	return logBuilder.String(), nil
 }
 
-// ViewContainerLogs returns logs for a specific container
-func ViewContainerLogs(ctx context.Context, cli *client.Client, containerID string) (string, error) {
-	logs, err := cli.ContainerLogs(ctx, containerID, container.LogsOptions{
-		ShowStdout: true,
-		ShowStderr: true,
-		Tail:       "100",
-		Timestamps: true,
-	})
+// ServiceLogs returns the log lines of every task of a service selected by opts
+func ServiceLogs(ctx context.Context, cli *client.Client, service swarm.Service, opts LogOptions) (string, error) {
+	logs, err := cli.ServiceLogs(ctx, service.ID, logsOptions(opts))
 	if err != nil {
-		return "", fmt.Errorf("error getting logs for container %s: %v", containerID, err)
+		return "", fmt.Errorf("error getting logs for service %s: %v", service.Spec.Name, err)
 	}
 	defer logs.Close()
 
-	// Read container logs
-	logBytes, err := io.ReadAll(logs)
+	var logBuilder strings.Builder
+	tty := service.Spec.TaskTemplate.ContainerSpec != nil && service.Spec.TaskTemplate.ContainerSpec.TTY
+	if err := readLogs(&logBuilder, logs, tty); err != nil {
+		return "", fmt.Errorf("error reading logs for service %s: %v", service.Spec.Name, err)
+	}
+	return logBuilder.String(), nil
+}
+
+// ViewContainerLogs returns the last tail log lines of a specific container
+func ViewContainerLogs(ctx context.Context, cli *client.Client, containerID string, tail int) (string, error) {
+	return ContainerLogs(ctx, cli, containerID, LogOptions{Tail: tail, Timestamps: true})
+}
+
+// ContainerLogs returns the log lines of a container selected by opts
+func ContainerLogs(ctx context.Context, cli *client.Client, containerID string, opts LogOptions) (string, error) {
+	details, err := cli.ContainerInspect(ctx, containerID)
+	if err != nil {
+		return "", fmt.Errorf("error inspecting container %s: %v", containerID, err)
+	}
+
+	logs, err := cli.ContainerLogs(ctx, containerID, logsOptions(opts))
 	if err != nil {
+		return "", fmt.Errorf("error getting logs for container %s: %v", containerID, err)
+	}
+	defer logs.Close()
+
+	var logBuilder strings.Builder
+	if err := readLogs(&logBuilder, logs, details.Config.Tty); err != nil {
 		return "", fmt.Errorf("error reading container logs: %v", err)
 	}
 
-	return string(logBytes), nil
+	return logBuilder.String(), nil
+}
+
+// logsOptions converts LogOptions to the options of the Docker API
+func logsOptions(opts LogOptions) container.LogsOptions {
+	tail := "all"
+	if opts.Tail >= 0 {
+		tail = strconv.Itoa(opts.Tail)
+	}
+
+	return container.LogsOptions{
+		ShowStdout: true,
+		ShowStderr: true,
+		Tail:       tail,
+		Since:      opts.Since,
+		Until:      opts.Until,
+		Timestamps: opts.Timestamps,
+	}
+}
+
+// readLogs copies a log stream to w; streams of containers without a TTY multiplex
+// stdout and stderr and are demultiplexed
+func readLogs(w io.Writer, logs io.Reader, tty bool) error {
+	if tty {
+		_, err := io.Copy(w, logs)
+		return err
+	}
+	_, err := stdcopy.StdCopy(w, w, logs)
+	return err
+}
+
+// ContainerExists reports whether a container with the given name or ID exists
+func ContainerExists(ctx context.Context, cli *client.Client, containerID string) (bool, error) {
+	if _, err := cli.ContainerInspect(ctx, containerID); err != nil {
+		if client.IsErrNotFound(err) {
+			return false, nil
+		}
+		return false, fmt.Errorf("error inspecting container %s: %v", containerID, err)
+	}
+	return true, nil
+}
+
+// InspectContainer returns the low-level details of a container
+func InspectContainer(ctx context.Context, cli *client.Client, containerID string) (container.InspectResponse, error) {
+	details, err := cli.ContainerInspect(ctx, containerID)
+	if err != nil {
+		return container.InspectResponse{}, fmt.Errorf("error inspecting container %s: %v", containerID, err)
+	}
+	return details, nil
 }
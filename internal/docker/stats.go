@@ -0,0 +1,86 @@
+package docker
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/client"
+)
+
+// SchemaVersion identifies the layout of the structs Pulse emits as machine-readable
+// output. It changes whenever a field is renamed or removed; adding fields does not
+// change it.
+const SchemaVersion = "pulse/v1"
+
+// StackStats holds statistics for a stack
+type StackStats struct {
+	Stack       string `json:"stack" yaml:"stack"`
+	Running     int    `json:"running" yaml:"running"`
+	Stopped     int    `json:"stopped" yaml:"stopped"`
+	Other       int    `json:"other" yaml:"other"`
+	TotalMemory string `json:"totalMemory,omitempty" yaml:"totalMemory,omitempty"`
+	TotalCPU    string `json:"totalCPU,omitempty" yaml:"totalCPU,omitempty"`
+}
+
+// Total returns the number of containers counted in the stats
+func (s StackStats) Total() int {
+	return s.Running + s.Stopped + s.Other
+}
+
+// ContainerSummary is the stable description of a container used for machine-readable output
+type ContainerSummary struct {
+	ID      string    `json:"id" yaml:"id"`
+	Name    string    `json:"name" yaml:"name"`
+	Stack   string    `json:"stack,omitempty" yaml:"stack,omitempty"`
+	Service string    `json:"service,omitempty" yaml:"service,omitempty"`
+	Image   string    `json:"image" yaml:"image"`
+	State   string    `json:"state" yaml:"state"`
+	Status  string    `json:"status" yaml:"status"`
+	Created time.Time `json:"created" yaml:"created"`
+}
+
+// SummarizeContainer converts a container from the Docker API into a ContainerSummary
+func SummarizeContainer(c types.Container) ContainerSummary {
+	name := ""
+	if len(c.Names) > 0 {
+		name = strings.TrimPrefix(c.Names[0], "/")
+	}
+
+	return ContainerSummary{
+		ID:      c.ID,
+		Name:    name,
+		Stack:   c.Labels["com.docker.stack.namespace"],
+		Service: c.Labels["com.docker.swarm.service.name"],
+		Image:   c.Image,
+		State:   c.State,
+		Status:  c.Status,
+		Created: time.Unix(c.Created, 0),
+	}
+}
+
+// GetStackStats counts the containers of a stack by state
+func GetStackStats(ctx context.Context, cli *client.Client, stackName string) (StackStats, error) {
+	containers, err := ListContainers(ctx, cli, stackName)
+	if err != nil {
+		return StackStats{}, err
+	}
+	return CountContainers(stackName, containers), nil
+}
+
+// CountContainers counts containers by state
+func CountContainers(stackName string, containers []types.Container) StackStats {
+	stats := StackStats{Stack: stackName}
+	for _, c := range containers {
+		switch c.State {
+		case "running":
+			stats.Running++
+		case "exited", "stopped":
+			stats.Stopped++
+		default:
+			stats.Other++
+		}
+	}
+	return stats
+}
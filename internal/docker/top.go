@@ -0,0 +1,79 @@
+package docker
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/docker/docker/client"
+)
+
+// Process is a process running in a container, as reported by ps on its node
+type Process struct {
+	PID     string `json:"pid" yaml:"pid"`
+	User    string `json:"user" yaml:"user"`
+	CPU     string `json:"cpu" yaml:"cpu"`
+	Memory  string `json:"memory" yaml:"memory"`
+	RSS     string `json:"rss" yaml:"rss"`
+	Command string `json:"command" yaml:"command"`
+}
+
+// topArgs asks ps for the columns shown in the process list
+var topArgs = []string{"-o", "pid,user,pcpu,pmem,rss,args"}
+
+// Signals offered when signalling a container, most common first
+var Signals = []string{"SIGTERM", "SIGHUP", "SIGINT", "SIGUSR1", "SIGUSR2", "SIGQUIT", "SIGKILL"}
+
+// ListProcesses returns the processes of a container. Daemons whose ps rejects the
+// column list, like busybox ps, fall back to the default columns.
+func ListProcesses(ctx context.Context, cli *client.Client, containerID string) ([]Process, error) {
+	top, err := cli.ContainerTop(ctx, containerID, topArgs)
+	if err != nil {
+		top, err = cli.ContainerTop(ctx, containerID, nil)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error listing processes of container %s: %v", containerID, err)
+	}
+
+	column := func(names ...string) int {
+		for i, title := range top.Titles {
+			for _, name := range names {
+				if strings.EqualFold(title, name) {
+					return i
+				}
+			}
+		}
+		return -1
+	}
+	pid, user := column("PID"), column("USER", "UID")
+	cpu, mem, rss := column("%CPU", "C"), column("%MEM"), column("RSS")
+	command := column("COMMAND", "CMD", "ARGS")
+
+	field := func(row []string, i int) string {
+		if i < 0 || i >= len(row) {
+			return ""
+		}
+		return row[i]
+	}
+
+	processes := make([]Process, 0, len(top.Processes))
+	for _, row := range top.Processes {
+		processes = append(processes, Process{
+			PID:     field(row, pid),
+			User:    field(row, user),
+			CPU:     field(row, cpu),
+			Memory:  field(row, mem),
+			RSS:     field(row, rss),
+			Command: field(row, command),
+		})
+	}
+	return processes, nil
+}
+
+// SignalContainer sends a signal, such as SIGHUP, to the main process of a container
+func SignalContainer(ctx context.Context, cli *client.Client, containerID, signal string) error {
+	if err := cli.ContainerKill(ctx, containerID, signal); err != nil {
+		return fmt.Errorf("error sending %s to container %s: %v", signal, containerID, err)
+	}
+	return nil
+}
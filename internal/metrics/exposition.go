@@ -0,0 +1,80 @@
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// labelEscaper escapes label values as the Prometheus text format requires
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// sample is one value of a metric family
+type sample struct {
+	labels [][2]string
+	value  float64
+}
+
+// family is a metric with its help text, type and samples
+type family struct {
+	name    string
+	help    string
+	kind    string
+	samples []sample
+}
+
+// add records a sample; labels are given as alternating names and values
+func (f *family) add(value float64, labels ...string) {
+	s := sample{value: value}
+	for i := 0; i+1 < len(labels); i += 2 {
+		s.labels = append(s.labels, [2]string{labels[i], labels[i+1]})
+	}
+	f.samples = append(f.samples, s)
+}
+
+// write renders the family in the Prometheus text exposition format
+func (f *family) write(w io.Writer) error {
+	if len(f.samples) == 0 {
+		return nil
+	}
+
+	if _, err := fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", f.name, f.help, f.name, f.kind); err != nil {
+		return err
+	}
+	for _, s := range f.samples {
+		var b strings.Builder
+		b.WriteString(f.name)
+		if len(s.labels) > 0 {
+			b.WriteByte('{')
+			for i, label := range s.labels {
+				if i > 0 {
+					b.WriteByte(',')
+				}
+				fmt.Fprintf(&b, `%s="%s"`, label[0], labelEscaper.Replace(label[1]))
+			}
+			b.WriteByte('}')
+		}
+		b.WriteByte(' ')
+		b.WriteString(formatValue(s.value))
+		b.WriteByte('\n')
+		if _, err := io.WriteString(w, b.String()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func formatValue(v float64) string {
+	switch {
+	case math.IsNaN(v):
+		return "NaN"
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	default:
+		return strconv.FormatFloat(v, 'g', -1, 64)
+	}
+}
@@ -0,0 +1,235 @@
+package metrics
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/client"
+
+	"pulse/internal/docker"
+)
+
+// statsConcurrency bounds the stats requests in flight; each one takes about a
+// second because the daemon waits for a second CPU sample
+const statsConcurrency = 8
+
+// Health states reported for containers; "none" means no healthcheck is defined
+var healthStates = []string{"healthy", "unhealthy", "starting", "none"}
+
+// Collector gathers stack, service and container metrics from the Docker daemon on
+// every scrape. Container metrics cover the containers of the local node; service
+// metrics are only available when the daemon is a swarm manager.
+type Collector struct {
+	cli     *client.Client
+	node    string
+	timeout time.Duration
+	errors  io.Writer
+
+	// Service listing fails on every scrape of a worker node, so it is only reported once
+	servicesWarning sync.Once
+}
+
+// NewCollector creates a collector for the daemon of node; collection errors are
+// written to errors and leave the affected metrics out of the scrape
+func NewCollector(cli *client.Client, node string, errors io.Writer) *Collector {
+	return &Collector{cli: cli, node: node, timeout: 10 * time.Second, errors: errors}
+}
+
+// Handler returns the HTTP handler serving /metrics
+func (c *Collector) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithTimeout(r.Context(), c.timeout)
+		defer cancel()
+
+		families := c.collect(ctx)
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		for _, f := range families {
+			if err := f.write(w); err != nil {
+				return
+			}
+		}
+	})
+}
+
+// collect gathers all metric families for one scrape
+func (c *Collector) collect(ctx context.Context) []*family {
+	start := time.Now()
+
+	up := &family{name: "pulse_up", help: "Whether the Docker daemon could be reached.", kind: "gauge"}
+	if _, err := c.cli.Ping(ctx); err != nil {
+		c.logError("pinging Docker daemon", err)
+		up.add(0)
+		return []*family{up}
+	}
+	up.add(1)
+
+	families := []*family{up}
+	families = append(families, c.collectServices(ctx)...)
+	families = append(families, c.collectContainers(ctx)...)
+
+	duration := &family{name: "pulse_scrape_duration_seconds", help: "Time taken to collect the metrics.", kind: "gauge"}
+	duration.add(time.Since(start).Seconds())
+	return append(families, duration)
+}
+
+// collectServices reports desired and running replicas and failed tasks per service
+func (c *Collector) collectServices(ctx context.Context) []*family {
+	desired := &family{name: "pulse_service_replicas_desired", help: "Number of tasks the service should be running.", kind: "gauge"}
+	running := &family{name: "pulse_service_replicas_running", help: "Number of tasks of the service that are running.", kind: "gauge"}
+	failed := &family{name: "pulse_service_tasks_failed", help: "Failed or rejected tasks in the service's retained task history.", kind: "gauge"}
+
+	services, err := docker.ListServiceStatus(ctx, c.cli)
+	if err != nil {
+		c.servicesWarning.Do(func() {
+			c.logError("listing services (service metrics need a swarm manager)", err)
+		})
+		return nil
+	}
+
+	for _, s := range services {
+		desired.add(float64(s.Desired), "stack", s.Stack, "service", s.Service, "mode", s.Mode)
+		running.add(float64(s.Running), "stack", s.Stack, "service", s.Service, "mode", s.Mode)
+		failed.add(float64(s.FailedTasks), "stack", s.Stack, "service", s.Service)
+	}
+	return []*family{desired, running, failed}
+}
+
+// collectContainers reports per-stack container counts from StackStats and the
+// resource usage, restarts and health of each running stack container on this node.
+// Stopped containers are only counted; swarm keeps the exited ones of old tasks.
+func (c *Collector) collectContainers(ctx context.Context) []*family {
+	containers, err := docker.ListAllStackContainers(ctx, c.cli, true)
+	if err != nil {
+		c.logError("listing containers", err)
+		return nil
+	}
+
+	counts := &family{name: "pulse_stack_containers", help: "Stack containers on this node by state.", kind: "gauge"}
+	byStack := make(map[string][]types.Container)
+	var stacks []string
+	for _, ctr := range containers {
+		stack := ctr.Labels["com.docker.stack.namespace"]
+		if _, ok := byStack[stack]; !ok {
+			stacks = append(stacks, stack)
+		}
+		byStack[stack] = append(byStack[stack], ctr)
+	}
+	for _, stack := range stacks {
+		stats := docker.CountContainers(stack, byStack[stack])
+		counts.add(float64(stats.Running), "node", c.node, "stack", stack, "state", "running")
+		counts.add(float64(stats.Stopped), "node", c.node, "stack", stack, "state", "stopped")
+		counts.add(float64(stats.Other), "node", c.node, "stack", stack, "state", "other")
+	}
+
+	cpu := &family{name: "pulse_container_cpu_percent", help: "CPU usage of the container as a percentage of one CPU.", kind: "gauge"}
+	memory := &family{name: "pulse_container_memory_usage_bytes", help: "Memory used by the container, excluding page cache.", kind: "gauge"}
+	memoryLimit := &family{name: "pulse_container_memory_limit_bytes", help: "Memory limit of the container.", kind: "gauge"}
+	restarts := &family{name: "pulse_container_restarts_total", help: "Times the daemon restarted the container.", kind: "counter"}
+	health := &family{name: "pulse_container_health_status", help: "Healthcheck status of the container; 1 for the current status.", kind: "gauge"}
+
+	var running []types.Container
+	for _, ctr := range containers {
+		if ctr.State == "running" {
+			running = append(running, ctr)
+		}
+	}
+	samples := c.sampleContainers(ctx, running)
+	for _, ctr := range running {
+		s, ok := samples[ctr.ID]
+		if !ok {
+			continue
+		}
+		labels := []string{
+			"node", c.node,
+			"stack", ctr.Labels["com.docker.stack.namespace"],
+			"service", ctr.Labels["com.docker.swarm.service.name"],
+			"container", strings.TrimPrefix(firstName(ctr.Names), "/"),
+		}
+
+		if s.stats != nil {
+			cpu.add(s.stats.CPUPercent, labels...)
+			memory.add(float64(s.stats.MemoryUsage), labels...)
+			memoryLimit.add(float64(s.stats.MemoryLimit), labels...)
+		}
+		restarts.add(float64(s.restarts), labels...)
+		for _, state := range healthStates {
+			value := 0.0
+			if state == s.health {
+				value = 1
+			}
+			health.add(value, append(labels, "status", state)...)
+		}
+	}
+
+	return []*family{counts, cpu, memory, memoryLimit, restarts, health}
+}
+
+// containerSample is what one container contributes to a scrape
+type containerSample struct {
+	stats    *docker.ResourceStats
+	restarts int
+	health   string
+}
+
+// sampleContainers inspects every container and samples the stats of running ones
+// concurrently
+func (c *Collector) sampleContainers(ctx context.Context, containers []types.Container) map[string]containerSample {
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	samples := make(map[string]containerSample)
+	sem := make(chan struct{}, statsConcurrency)
+
+	for _, ctr := range containers {
+		wg.Add(1)
+		go func(ctr types.Container) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			details, err := docker.InspectContainer(ctx, c.cli, ctr.ID)
+			if err != nil {
+				c.logError("inspecting container "+ctr.ID, err)
+				return
+			}
+
+			s := containerSample{restarts: details.RestartCount, health: "none"}
+			if details.State != nil && details.State.Health != nil {
+				s.health = details.State.Health.Status
+			}
+			if ctr.State == "running" {
+				stats, err := docker.GetResourceStats(ctx, c.cli, ctr.ID)
+				if err != nil {
+					c.logError("sampling stats", err)
+				} else {
+					s.stats = &stats
+				}
+			}
+
+			mu.Lock()
+			samples[ctr.ID] = s
+			mu.Unlock()
+		}(ctr)
+	}
+
+	wg.Wait()
+	return samples
+}
+
+func (c *Collector) logError(action string, err error) {
+	if c.errors != nil {
+		fmt.Fprintf(c.errors, "Error %s: %v\n", action, err)
+	}
+}
+
+func firstName(names []string) string {
+	if len(names) == 0 {
+		return ""
+	}
+	return names[0]
+}
@@ -0,0 +1,246 @@
+// Package store keeps a history of events, container exits and resource samples in
+// append-only JSON Lines files, so it survives restarts of Pulse.
+package store
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"pulse/internal/docker"
+)
+
+// Files in the store directory, one JSON record per line
+const (
+	EventsFile = "events.jsonl"
+	ExitsFile  = "exits.jsonl"
+	StatsFile  = "stats.jsonl"
+)
+
+// maxLineSize bounds a single record; event messages and task errors are short
+const maxLineSize = 1 << 20
+
+// ErrClosed is returned when writing to a store after it was closed
+var ErrClosed = errors.New("history store is closed")
+
+// Sample is a resource usage sample of a stack container
+type Sample struct {
+	Stack     string `json:"stack"`
+	Service   string `json:"service"`
+	Container string `json:"container"`
+	docker.ResourceStats
+}
+
+// Store appends records to the history files and drops those older than the retention
+type Store struct {
+	dir       string
+	retention time.Duration
+
+	mu     sync.Mutex
+	files  map[string]*os.File
+	closed bool
+}
+
+// Open opens or creates the store in dir and drops records past the retention
+func Open(dir string, retention time.Duration) (*Store, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("error creating history directory: %v", err)
+	}
+
+	s := &Store{dir: dir, retention: retention, files: make(map[string]*os.File)}
+	if err := s.Compact(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Close closes the history files; later writes fail with ErrClosed instead of
+// opening them again
+func (s *Store) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.closed = true
+	var firstErr error
+	for name, f := range s.files {
+		if err := f.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+		delete(s.files, name)
+	}
+	return firstErr
+}
+
+// AppendEvent records a timeline event
+func (s *Store) AppendEvent(event docker.Event) error {
+	return s.append(EventsFile, event)
+}
+
+// AppendExit records a container that stopped
+func (s *Store) AppendExit(exit docker.ContainerExit) error {
+	return s.append(ExitsFile, exit)
+}
+
+// AppendSamples records resource samples
+func (s *Store) AppendSamples(samples []Sample) error {
+	records := make([]any, len(samples))
+	for i, sample := range samples {
+		records[i] = sample
+	}
+	return s.append(StatsFile, records...)
+}
+
+// Events returns the recorded events after since, oldest first
+func (s *Store) Events(since time.Time) ([]docker.Event, error) {
+	return read(s, EventsFile, since, func(e docker.Event) time.Time { return e.Time })
+}
+
+// Exits returns the recorded container exits after since, oldest first
+func (s *Store) Exits(since time.Time) ([]docker.ContainerExit, error) {
+	return read(s, ExitsFile, since, func(e docker.ContainerExit) time.Time { return e.FinishedAt })
+}
+
+// Samples returns the recorded resource samples after since, oldest first
+func (s *Store) Samples(since time.Time) ([]Sample, error) {
+	return read(s, StatsFile, since, func(e Sample) time.Time { return e.Time })
+}
+
+// append writes records to the end of a file
+func (s *Store) append(name string, records ...any) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return ErrClosed
+	}
+
+	f, err := s.file(name)
+	if err != nil {
+		return err
+	}
+
+	w := bufio.NewWriter(f)
+	enc := json.NewEncoder(w)
+	for _, record := range records {
+		if err := enc.Encode(record); err != nil {
+			return fmt.Errorf("error encoding history record: %v", err)
+		}
+	}
+	if err := w.Flush(); err != nil {
+		return fmt.Errorf("error writing %s: %v", name, err)
+	}
+	return nil
+}
+
+// file returns the open append handle of a history file
+func (s *Store) file(name string) (*os.File, error) {
+	if f, ok := s.files[name]; ok {
+		return f, nil
+	}
+	f, err := os.OpenFile(filepath.Join(s.dir, name), os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("error opening %s: %v", name, err)
+	}
+	s.files[name] = f
+	return f, nil
+}
+
+// Compact rewrites the history files without the records past the retention
+func (s *Store) Compact() error {
+	cutoff := time.Now().Add(-s.retention)
+	if err := compact(s, EventsFile, cutoff, func(e docker.Event) time.Time { return e.Time }); err != nil {
+		return err
+	}
+	if err := compact(s, ExitsFile, cutoff, func(e docker.ContainerExit) time.Time { return e.FinishedAt }); err != nil {
+		return err
+	}
+	return compact(s, StatsFile, cutoff, func(e Sample) time.Time { return e.Time })
+}
+
+// read decodes the records of a file logged after since
+func read[T any](s *Store, name string, since time.Time, at func(T) time.Time) ([]T, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return decode(s, name, since, at)
+}
+
+// decode reads the records of a file logged after since, with s.mu held. Lines that
+// fail to decode, such as one cut short by a crash, are skipped.
+func decode[T any](s *Store, name string, since time.Time, at func(T) time.Time) ([]T, error) {
+	f, err := os.Open(filepath.Join(s.dir, name))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error opening %s: %v", name, err)
+	}
+	defer f.Close()
+
+	var records []T
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), maxLineSize)
+	for scanner.Scan() {
+		var record T
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			continue
+		}
+		if at(record).After(since) {
+			records = append(records, record)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("error reading %s: %v", name, err)
+	}
+	return records, nil
+}
+
+// compact keeps the records of a file logged after cutoff, replacing the file atomically
+func compact[T any](s *Store, name string, cutoff time.Time, at func(T) time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return ErrClosed
+	}
+
+	records, err := decode(s, name, cutoff, at)
+	if err != nil {
+		return err
+	}
+
+	path := filepath.Join(s.dir, name)
+	tmp, err := os.CreateTemp(s.dir, name+".*")
+	if err != nil {
+		return fmt.Errorf("error compacting %s: %v", name, err)
+	}
+	defer os.Remove(tmp.Name())
+
+	w := bufio.NewWriter(tmp)
+	enc := json.NewEncoder(w)
+	for _, record := range records {
+		if err := enc.Encode(record); err != nil {
+			tmp.Close()
+			return fmt.Errorf("error compacting %s: %v", name, err)
+		}
+	}
+	if err := w.Flush(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("error compacting %s: %v", name, err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("error compacting %s: %v", name, err)
+	}
+
+	// The append handle points at the old file, so it is reopened on the next write
+	if f, ok := s.files[name]; ok {
+		f.Close()
+		delete(s.files, name)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("error compacting %s: %v", name, err)
+	}
+	return nil
+}
@@ -0,0 +1,186 @@
+package store
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"pulse/internal/docker"
+)
+
+// eventLine is a line of the events file for an event logged at the given time
+func eventLine(t *testing.T, subject string, at time.Time) string {
+	t.Helper()
+	return `{"time":"` + at.Format(time.RFC3339Nano) + `","type":"container","action":"die","level":"error","stack":"shop","service":"shop_web","subject":"` + subject + `"}`
+}
+
+func subjects(events []docker.Event) string {
+	names := make([]string, len(events))
+	for i, e := range events {
+		names[i] = e.Subject
+	}
+	return strings.Join(names, ",")
+}
+
+func TestDecode(t *testing.T) {
+	now := time.Now()
+	tests := []struct {
+		name  string
+		lines []string // nil leaves the file out
+		since time.Time
+		want  string
+	}{
+		{name: "missing file", lines: nil, since: now.Add(-time.Hour), want: ""},
+		{
+			name:  "records after since",
+			lines: []string{eventLine(t, "old", now.Add(-2*time.Hour)), eventLine(t, "a", now.Add(-time.Minute)), eventLine(t, "b", now)},
+			since: now.Add(-time.Hour),
+			want:  "a,b",
+		},
+		{
+			name:  "record at since is left out",
+			lines: []string{eventLine(t, "edge", now.Add(-time.Hour)), eventLine(t, "a", now)},
+			since: now.Add(-time.Hour),
+			want:  "a",
+		},
+		{
+			name:  "broken lines are skipped",
+			lines: []string{eventLine(t, "a", now.Add(-time.Minute)), `{"time":"2024-`, "", "not json", eventLine(t, "b", now)},
+			since: now.Add(-time.Hour),
+			want:  "a,b",
+		},
+		{
+			name:  "record cut short by a crash",
+			lines: []string{eventLine(t, "a", now.Add(-time.Minute)), eventLine(t, "b", now)[:40]},
+			since: now.Add(-time.Hour),
+			want:  "a",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &Store{dir: t.TempDir(), files: make(map[string]*os.File)}
+			if tt.lines != nil {
+				content := strings.Join(tt.lines, "\n") + "\n"
+				if err := os.WriteFile(filepath.Join(s.dir, EventsFile), []byte(content), 0o600); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			events, err := decode(s, EventsFile, tt.since, func(e docker.Event) time.Time { return e.Time })
+			if err != nil {
+				t.Fatalf("decode() error = %v", err)
+			}
+			if got := subjects(events); got != tt.want {
+				t.Errorf("decode() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCompact(t *testing.T) {
+	now := time.Now()
+	tests := []struct {
+		name      string
+		retention time.Duration
+		events    []docker.Event
+		want      string
+	}{
+		{name: "empty store", retention: time.Hour, want: ""},
+		{
+			name:      "drops records past the retention",
+			retention: time.Hour,
+			events: []docker.Event{
+				{Time: now.Add(-3 * time.Hour), Subject: "old"},
+				{Time: now.Add(-30 * time.Minute), Subject: "a"},
+				{Time: now.Add(-2 * time.Hour), Subject: "older"},
+				{Time: now, Subject: "b"},
+			},
+			want: "a,b",
+		},
+		{
+			name:      "keeps everything within the retention",
+			retention: 24 * time.Hour,
+			events:    []docker.Event{{Time: now.Add(-time.Hour), Subject: "a"}, {Time: now, Subject: "b"}},
+			want:      "a,b",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := Open(t.TempDir(), tt.retention)
+			if err != nil {
+				t.Fatalf("Open() error = %v", err)
+			}
+			defer s.Close()
+
+			for _, e := range tt.events {
+				if err := s.AppendEvent(e); err != nil {
+					t.Fatalf("AppendEvent() error = %v", err)
+				}
+			}
+			if err := s.Compact(); err != nil {
+				t.Fatalf("Compact() error = %v", err)
+			}
+
+			events, err := s.Events(time.Time{})
+			if err != nil {
+				t.Fatalf("Events() error = %v", err)
+			}
+			if got := subjects(events); got != tt.want {
+				t.Errorf("Events() after Compact() = %q, want %q", got, tt.want)
+			}
+
+			// The append handle is reopened on the new file
+			if err := s.AppendEvent(docker.Event{Time: now, Subject: "after"}); err != nil {
+				t.Fatalf("AppendEvent() after Compact() error = %v", err)
+			}
+			events, _ = s.Events(time.Time{})
+			if got, want := subjects(events), strings.TrimPrefix(tt.want+",after", ","); got != want {
+				t.Errorf("Events() after appending = %q, want %q", got, want)
+			}
+		})
+	}
+}
+
+func TestClosedStore(t *testing.T) {
+	s, err := Open(t.TempDir(), time.Hour)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	if err := s.AppendEvent(docker.Event{Time: time.Now(), Subject: "a"}); err != nil {
+		t.Fatalf("AppendEvent() error = %v", err)
+	}
+	if err := s.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	tests := []struct {
+		name  string
+		write func() error
+	}{
+		{name: "event", write: func() error { return s.AppendEvent(docker.Event{Time: time.Now(), Subject: "b"}) }},
+		{name: "exit", write: func() error { return s.AppendExit(docker.ContainerExit{FinishedAt: time.Now()}) }},
+		{name: "samples", write: func() error { return s.AppendSamples([]Sample{{Stack: "shop"}}) }},
+		{name: "compact", write: s.Compact},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.write(); !errors.Is(err, ErrClosed) {
+				t.Errorf("error = %v, want ErrClosed", err)
+			}
+		})
+	}
+
+	// Nothing was written after closing
+	events, err := s.Events(time.Time{})
+	if err != nil {
+		t.Fatalf("Events() error = %v", err)
+	}
+	if got := subjects(events); got != "a" {
+		t.Errorf("Events() = %q, want %q", got, "a")
+	}
+}
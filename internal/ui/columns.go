@@ -0,0 +1,212 @@
+package ui
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/docker/docker/api/types"
+
+	"pulse/internal/docker"
+)
+
+// containerColumn is a column of the container list. Flexible columns share the width
+// the fixed ones leave over, never going below width.
+type containerColumn struct {
+	title string
+	width int
+	flex  bool
+	value func(m Model, c types.Container) string
+	style func(t Theme, value string) lipgloss.Style
+}
+
+// containerColumns are the columns that container_columns can choose from
+var containerColumns = map[string]containerColumn{
+	"name": {title: "NAME", width: 16, flex: true, value: func(_ Model, c types.Container) string {
+		return strings.TrimPrefix(c.Names[0], "/")
+	}},
+	"status": {title: "STATUS", width: 10, value: func(_ Model, c types.Container) string {
+		return c.State
+	}, style: Theme.stateStyle},
+	"id": {title: "ID", width: 10, value: func(_ Model, c types.Container) string {
+		return c.ID[:10]
+	}},
+	"image": {title: "IMAGE", width: 16, flex: true, value: func(_ Model, c types.Container) string {
+		image, _, _ := strings.Cut(c.Image, "@")
+		return image
+	}},
+	"cpu": {title: "CPU", width: sparkWidth, value: func(m Model, c types.Container) string {
+		return m.containerSpark(c.ID)
+	}},
+	"ports": {title: "PORTS", width: 12, flex: true, value: func(_ Model, c types.Container) string {
+		return formatPorts(c.Ports)
+	}},
+	"uptime": {title: "UPTIME", width: 14, value: func(_ Model, c types.Container) string {
+		return uptime(c)
+	}},
+	"restarts": {title: "RESTARTS", width: 8, value: func(m Model, c types.Container) string {
+		restarts, ok := m.containerRestarts[c.Labels["com.docker.swarm.task.id"]]
+		if !ok {
+			return "-"
+		}
+		return fmt.Sprint(restarts)
+	}},
+	"node": {title: "NODE", width: 12, flex: true, value: func(m Model, c types.Container) string {
+		if node := m.containerNodes[c.ID]; node != "" {
+			return node
+		}
+		return "local"
+	}},
+	"health": {title: "HEALTH", width: 9, value: func(_ Model, c types.Container) string {
+		health := docker.ContainerHealth(c)
+		if health == "none" {
+			return "-"
+		}
+		return health
+	}, style: Theme.containerHealthStyle},
+}
+
+// defaultContainerColumns are shown when container_columns is not set; the node column
+// is added when agents report containers from other nodes
+var defaultContainerColumns = []string{"name", "status", "id", "image", "cpu"}
+
+// stateStyle colours a container state
+func (t Theme) stateStyle(state string) lipgloss.Style {
+	switch strings.TrimSpace(state) {
+	case "running":
+		return t.Running
+	case "exited", "stopped":
+		return t.Stopped
+	default:
+		return t.Other
+	}
+}
+
+// containerHealthStyle colours a container's healthcheck status
+func (t Theme) containerHealthStyle(health string) lipgloss.Style {
+	switch strings.TrimSpace(health) {
+	case "healthy":
+		return t.Running
+	case "unhealthy":
+		return t.Stopped
+	default:
+		return t.Other
+	}
+}
+
+// formatPorts lists the ports of a container as public→private/protocol, skipping the
+// duplicates reported for IPv4 and IPv6
+func formatPorts(ports []types.Port) string {
+	var formatted []string
+	for _, p := range ports {
+		port := fmt.Sprintf("%d/%s", p.PrivatePort, p.Type)
+		if p.PublicPort != 0 {
+			port = fmt.Sprintf("%d→%s", p.PublicPort, port)
+		}
+		if !slices.Contains(formatted, port) {
+			formatted = append(formatted, port)
+		}
+	}
+	if len(formatted) == 0 {
+		return "-"
+	}
+	return strings.Join(formatted, ",")
+}
+
+// uptime extracts how long a running container has been up from its status line,
+// e.g. "Up 3 hours (healthy)"
+func uptime(c types.Container) string {
+	if c.State != "running" {
+		return "-"
+	}
+	up, _, _ := strings.Cut(strings.TrimPrefix(c.Status, "Up "), " (")
+	return up
+}
+
+// containerColumnNames returns the configured columns of the container list
+func (m Model) containerColumnNames() []string {
+	if len(m.cfg.ContainerColumns) > 0 {
+		return m.cfg.ContainerColumns
+	}
+	if len(m.agents) > 0 {
+		return append(slices.Clone(defaultContainerColumns), "node")
+	}
+	return defaultContainerColumns
+}
+
+// unknownContainerColumns lists the configured columns that do not exist
+func unknownContainerColumns(names []string) []string {
+	var unknown []string
+	for _, name := range names {
+		if _, ok := containerColumns[name]; !ok {
+			unknown = append(unknown, name)
+		}
+	}
+	return unknown
+}
+
+// columnWidths fits columns into width: fixed columns keep their width and flexible
+// ones share what is left evenly
+func columnWidths(columns []containerColumn, width int) []int {
+	widths := make([]int, len(columns))
+	used, flex := len(columns)-1, 0
+	for i, column := range columns {
+		widths[i] = column.width
+		used += column.width
+		if column.flex {
+			flex++
+		}
+	}
+
+	extra := width - used
+	for i, column := range columns {
+		if column.flex && extra > 0 {
+			share := extra / flex
+			widths[i] += share
+			extra -= share
+			flex--
+		}
+	}
+	return widths
+}
+
+// pad truncates or pads s to exactly width cells
+func pad(s string, width int) string {
+	s = truncate(s, width)
+	return s + strings.Repeat(" ", max(width-lipgloss.Width(s), 0))
+}
+
+// renderContainerRows renders the header, rule and one row per container for the
+// configured columns, sized to the terminal
+func (m Model) renderContainerRows() (string, string, []string) {
+	var columns []containerColumn
+	for _, name := range m.containerColumnNames() {
+		if column, ok := containerColumns[name]; ok {
+			columns = append(columns, column)
+		}
+	}
+
+	// The container panel's border and padding, and the selection marker
+	widths := columnWidths(columns, m.viewportWidth-12)
+
+	headers := make([]string, len(columns))
+	rules := make([]string, len(columns))
+	for i, column := range columns {
+		headers[i] = pad(column.title, widths[i])
+		rules[i] = strings.Repeat("━", widths[i])
+	}
+
+	rows := make([]string, len(m.containers))
+	for r, c := range m.containers {
+		cells := make([]string, len(columns))
+		for i, column := range columns {
+			cells[i] = pad(column.value(m, c), widths[i])
+			if column.style != nil {
+				cells[i] = column.style(m.theme, cells[i]).Render(cells[i])
+			}
+		}
+		rows[r] = strings.Join(cells, " ")
+	}
+	return strings.Join(headers, " "), strings.Join(rules, " "), rows
+}
@@ -0,0 +1,226 @@
+package ui
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/lipgloss"
+
+	"pulse/internal/docker"
+)
+
+// changeNode is a directory or file in the tree of a container's changes. Counts
+// cover the files below a directory; directories are reported as changed whenever
+// something in them changes, so they are not counted themselves.
+type changeNode struct {
+	name     string
+	path     string
+	kind     docker.ChangeKind // empty for directories that only hold changes
+	children []*changeNode
+	counts   map[docker.ChangeKind]int
+}
+
+// changeRow is a visible line of the change tree
+type changeRow struct {
+	node  *changeNode
+	depth int
+}
+
+// buildChangeTree arranges changes by directory below a root node
+func buildChangeTree(changes []docker.Change) *changeNode {
+	root := &changeNode{name: "/", path: "/", counts: map[docker.ChangeKind]int{}}
+	nodes := map[string]*changeNode{"/": root}
+
+	var node func(p string) *changeNode
+	node = func(p string) *changeNode {
+		if n, ok := nodes[p]; ok {
+			return n
+		}
+		parent := node(path.Dir(p))
+		n := &changeNode{name: path.Base(p), path: p, counts: map[docker.ChangeKind]int{}}
+		parent.children = append(parent.children, n)
+		nodes[p] = n
+		return n
+	}
+
+	for _, c := range changes {
+		node(path.Clean("/" + c.Path)).kind = c.Kind
+	}
+
+	var finish func(n *changeNode)
+	finish = func(n *changeNode) {
+		if len(n.children) == 0 {
+			if n.kind != "" {
+				n.counts[n.kind]++
+			}
+			return
+		}
+		sort.Slice(n.children, func(i, j int) bool {
+			return n.children[i].name < n.children[j].name
+		})
+		for _, child := range n.children {
+			finish(child)
+			for kind, count := range child.counts {
+				n.counts[kind] += count
+			}
+		}
+	}
+	finish(root)
+	return root
+}
+
+// loadChanges reads the changes of the selected container. Directories the
+// container created are collapsed at first, since everything below them is new.
+func (m *Model) loadChanges() {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	changes, err := docker.ContainerChanges(ctx, m.cli, m.containers[m.selectedContainer].ID)
+	m.changesErr = err
+	m.changeTree = buildChangeTree(changes)
+	m.collapsedChanges = map[string]bool{}
+	m.selectedChange = 0
+
+	var collapse func(n *changeNode)
+	collapse = func(n *changeNode) {
+		for _, child := range n.children {
+			if child.kind == docker.ChangeAdded && len(child.children) > 0 {
+				m.collapsedChanges[child.path] = true
+				continue
+			}
+			collapse(child)
+		}
+	}
+	collapse(m.changeTree)
+}
+
+// openChanges shows the changes of the selected container
+func (m *Model) openChanges() {
+	if m.containerAgents[m.containers[m.selectedContainer].ID] != nil {
+		m.logOutput = "Changes are only available for containers on this node"
+		return
+	}
+	m.state = "changes"
+	m.logOutput = ""
+	m.loadChanges()
+}
+
+// changeRows flattens the expanded part of the change tree
+func (m Model) changeRows() []changeRow {
+	var rows []changeRow
+	var walk func(n *changeNode, depth int)
+	walk = func(n *changeNode, depth int) {
+		for _, child := range n.children {
+			rows = append(rows, changeRow{node: child, depth: depth})
+			if !m.collapsedChanges[child.path] {
+				walk(child, depth+1)
+			}
+		}
+	}
+	if m.changeTree != nil {
+		walk(m.changeTree, 0)
+	}
+	return rows
+}
+
+// toggleChange collapses or expands the selected directory
+func (m *Model) toggleChange() {
+	rows := m.changeRows()
+	if m.selectedChange >= len(rows) || len(rows[m.selectedChange].node.children) == 0 {
+		return
+	}
+	p := rows[m.selectedChange].node.path
+	m.collapsedChanges[p] = !m.collapsedChanges[p]
+}
+
+// setChangesCollapsed collapses or expands every directory of the change tree
+func (m *Model) setChangesCollapsed(collapsed bool) {
+	m.collapsedChanges = map[string]bool{}
+	if collapsed {
+		var walk func(n *changeNode)
+		walk = func(n *changeNode) {
+			for _, child := range n.children {
+				if len(child.children) > 0 {
+					m.collapsedChanges[child.path] = true
+					walk(child)
+				}
+			}
+		}
+		walk(m.changeTree)
+	}
+	m.selectedChange = 0
+}
+
+// changeCounts formats the added, changed and deleted counts of a node
+func (t Theme) changeCounts(counts map[docker.ChangeKind]int) string {
+	return t.Running.Render(fmt.Sprintf("+%d", counts[docker.ChangeAdded])) + " " +
+		t.Other.Render(fmt.Sprintf("~%d", counts[docker.ChangeChanged])) + " " +
+		t.Stopped.Render(fmt.Sprintf("-%d", counts[docker.ChangeDeleted]))
+}
+
+// changeMarker shows how a path changed
+func (t Theme) changeMarker(kind docker.ChangeKind) string {
+	switch kind {
+	case docker.ChangeAdded:
+		return t.Running.Render("A")
+	case docker.ChangeChanged:
+		return t.Other.Render("C")
+	case docker.ChangeDeleted:
+		return t.Stopped.Render("D")
+	}
+	return " "
+}
+
+// renderChanges renders the change tree of the selected container
+func (m Model) renderChanges(header string) string {
+	container := m.containers[m.selectedContainer]
+	name := strings.TrimPrefix(container.Names[0], "/")
+	rows := m.changeRows()
+
+	// Show the window of rows around the selection
+	height := max(m.viewportHeight-18, 5)
+	start := max(0, min(m.selectedChange-height/2, len(rows)-height))
+	end := min(len(rows), start+height)
+
+	changeList := ""
+	if m.changesErr != nil {
+		changeList = m.theme.Stopped.Render(fmt.Sprintf("Error: %v", m.changesErr)) + "\n"
+	} else if len(rows) == 0 {
+		changeList = m.theme.Unselected.Render("No changes: the container only writes to volumes") + "\n"
+	}
+	for i := start; i < end; i++ {
+		n := rows[i].node
+		line := strings.Repeat("  ", rows[i].depth)
+		if len(n.children) > 0 {
+			arrow := "▾"
+			if m.collapsedChanges[n.path] {
+				arrow = "▸"
+			}
+			line += fmt.Sprintf("%s %s %s/  %s", arrow, m.theme.changeMarker(n.kind), n.name, m.theme.changeCounts(n.counts))
+		} else {
+			line += fmt.Sprintf("  %s %s", m.theme.changeMarker(n.kind), n.name)
+		}
+		if i == m.selectedChange {
+			changeList += m.theme.Selected.Render("❯ "+line) + "\n"
+		} else {
+			changeList += m.theme.Unselected.Render("  "+line) + "\n"
+		}
+	}
+
+	title := fmt.Sprintf("Changes in %s since its image", name)
+	if m.changeTree != nil && m.changesErr == nil {
+		title += "  " + m.theme.changeCounts(m.changeTree.counts)
+	}
+	panels := []string{header, m.theme.Container.Render(
+		m.theme.Title.Render(title) + "\n" +
+			changeList + "\n" +
+			m.theme.Instruction.Render("A: Added • C: Changed • D: Deleted • "+m.keys.viewHelp("changes", "Back", m.keys.hint("select", "Collapse/expand"))))}
+	if m.logOutput != "" {
+		panels = append(panels, m.theme.LogPanel.Render(m.theme.Log.Render(m.logOutput)))
+	}
+	return lipgloss.JoinVertical(lipgloss.Left, panels...)
+}
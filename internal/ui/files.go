@@ -0,0 +1,365 @@
+package ui
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/charmbracelet/bubbles/viewport"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/docker/docker/client"
+
+	"pulse/internal/docker"
+)
+
+// maxViewSize bounds how much of a file is shown in the file viewer
+const maxViewSize = 1 << 20
+
+// fileTimeout bounds listing and reading files; copies run until they finish or are
+// cancelled
+const fileTimeout = 5 * time.Minute
+
+// fileOp is a file operation running in the background; Esc cancels it
+type fileOp struct {
+	desc   string
+	cancel context.CancelFunc
+	// Bytes copied so far by a download or upload
+	copied atomic.Int64
+}
+
+// dirMsg delivers a directory listing
+type dirMsg struct {
+	op        *fileOp
+	dir       string
+	entries   []docker.FileEntry
+	truncated bool
+	err       error
+}
+
+// fileContentMsg delivers the start of a text file
+type fileContentMsg struct {
+	op      *fileOp
+	entry   docker.FileEntry
+	content []byte
+	err     error
+}
+
+// copiedMsg reports the end of a download or upload
+type copiedMsg struct {
+	op     *fileOp
+	result string
+	// Whether the current directory changed and is listed again
+	reload bool
+	err    error
+}
+
+// fileTickMsg redraws the progress of a file operation
+type fileTickMsg struct {
+	op *fileOp
+}
+
+// fileTick schedules the next redraw of a file operation's progress
+func fileTick(op *fileOp) tea.Cmd {
+	return tea.Tick(250*time.Millisecond, func(time.Time) tea.Msg {
+		return fileTickMsg{op: op}
+	})
+}
+
+// startFileOp runs work in the background as the file operation, unless one is still
+// running. A timeout of 0 lets it run until it finishes or is cancelled.
+func (m *Model) startFileOp(desc string, timeout time.Duration, work func(ctx context.Context, op *fileOp) tea.Msg) tea.Cmd {
+	if m.fileOp != nil {
+		m.logOutput = fmt.Sprintf("%s is still running; press '%s' to cancel it", m.fileOp.desc, m.keys.binding("back").Help().Key)
+		return nil
+	}
+
+	var ctx context.Context
+	var cancel context.CancelFunc
+	if timeout > 0 {
+		ctx, cancel = context.WithTimeout(context.Background(), timeout)
+	} else {
+		ctx, cancel = context.WithCancel(context.Background())
+	}
+	op := &fileOp{desc: desc, cancel: cancel}
+	m.fileOp = op
+	return tea.Batch(func() tea.Msg {
+		defer cancel()
+		return work(ctx, op)
+	}, fileTick(op))
+}
+
+// finishFileOp reports whether a result belongs to the running file operation, which
+// it ends; results of cancelled operations are dropped
+func (m *Model) finishFileOp(op *fileOp) bool {
+	if op != m.fileOp {
+		return false
+	}
+	m.fileOp = nil
+	return true
+}
+
+// cancelFileOp cancels the running file operation, reporting whether there was one
+func (m *Model) cancelFileOp() bool {
+	if m.fileOp == nil {
+		return false
+	}
+	m.fileOp.cancel()
+	m.logOutput = fmt.Sprintf("Cancelled: %s", m.fileOp.desc)
+	m.fileOp = nil
+	return true
+}
+
+// openFiles starts browsing the filesystem of the selected container at its root
+func (m *Model) openFiles() tea.Cmd {
+	id := m.containers[m.selectedContainer].ID
+	if m.containerAgents[id] != nil {
+		m.logOutput = "Browsing files is only available for containers on this node"
+		return nil
+	}
+	m.state = "files"
+	m.logOutput = ""
+	m.filePath = "/"
+	m.fileEntries = nil
+	m.selectedFile = 0
+	return m.loadDir("/")
+}
+
+// loadDir lists a directory of the selected container in the background
+func (m *Model) loadDir(dir string) tea.Cmd {
+	cli, id := m.cli, m.containers[m.selectedContainer].ID
+	return m.startFileOp("Listing "+dir, fileTimeout, func(ctx context.Context, op *fileOp) tea.Msg {
+		return listDir(ctx, cli, id, dir, op)
+	})
+}
+
+// listDir lists a directory for the file operation op
+func listDir(ctx context.Context, cli *client.Client, id, dir string, op *fileOp) dirMsg {
+	entries, truncated, err := docker.ListDir(ctx, cli, id, dir)
+	return dirMsg{op: op, dir: dir, entries: entries, truncated: truncated, err: err}
+}
+
+// showDir shows a directory listed in the background
+func (m *Model) showDir(msg dirMsg) {
+	if !m.finishFileOp(msg.op) {
+		return
+	}
+	if msg.err != nil {
+		m.logOutput = fmt.Sprintf("Error: %v", msg.err)
+		return
+	}
+
+	m.filePath = msg.dir
+	m.fileEntries = msg.entries
+	m.selectedFile = 0
+	if msg.truncated {
+		m.logOutput = fmt.Sprintf("Only part of %s is listed: it holds more than %d files", msg.dir, docker.MaxDirEntries)
+	}
+}
+
+// openFileEntry enters the selected directory or shows the selected file
+func (m *Model) openFileEntry() tea.Cmd {
+	// The first row goes up to the parent directory
+	if m.filePath != "/" && m.selectedFile == 0 {
+		return m.loadDir(path.Dir(m.filePath))
+	}
+	entry := m.fileEntries[m.selectedFile-m.fileRowOffset()]
+	if entry.IsDir() {
+		return m.loadDir(entry.Path)
+	}
+
+	cli, id := m.cli, m.containers[m.selectedContainer].ID
+	return m.startFileOp("Opening "+entry.Path, fileTimeout, func(ctx context.Context, op *fileOp) tea.Msg {
+		// Symlinks are followed by asking the daemon what they resolve to
+		p := entry.Path
+		if entry.LinkTarget != "" {
+			link, err := docker.StatPath(ctx, cli, id, entry.Path)
+			if err == nil && link.LinkTarget != "" {
+				p = link.LinkTarget
+				if target, err := docker.StatPath(ctx, cli, id, p); err == nil && target.IsDir() {
+					return listDir(ctx, cli, id, p, op)
+				}
+			}
+		}
+		content, err := docker.ReadFile(ctx, cli, id, p, maxViewSize)
+		return fileContentMsg{op: op, entry: entry, content: content, err: err}
+	})
+}
+
+// showFileContent shows a file read in the background
+func (m *Model) showFileContent(msg fileContentMsg) {
+	if !m.finishFileOp(msg.op) {
+		return
+	}
+	if errors.Is(msg.err, docker.ErrNotText) {
+		m.logOutput = fmt.Sprintf("%s is binary; press '%s' to download it", msg.entry.Name, m.keys.binding("download").Help().Key)
+		return
+	}
+	if msg.err != nil {
+		m.logOutput = fmt.Sprintf("Error: %v", msg.err)
+		return
+	}
+
+	width, height := hitViewSize(m.viewportWidth, m.viewportHeight)
+	m.fileView = viewport.New(width, height)
+	m.fileView.SetContent(string(msg.content))
+	m.fileTitle = msg.entry.Path
+	if msg.entry.Size > maxViewSize {
+		m.fileTitle += fmt.Sprintf(" (first %s)", formatBytes(maxViewSize))
+	}
+	m.state = "fileContent"
+}
+
+// selectedFilePath returns the container path of the selected entry, or of the
+// current directory when the parent row is selected
+func (m Model) selectedFilePath() string {
+	if m.filePath != "/" && m.selectedFile == 0 {
+		return m.filePath
+	}
+	return m.fileEntries[m.selectedFile-m.fileRowOffset()].Path
+}
+
+// fileRowOffset is the number of rows above the entries: the parent directory row
+func (m Model) fileRowOffset() int {
+	if m.filePath == "/" {
+		return 0
+	}
+	return 1
+}
+
+// fileRows is the number of selectable rows in the file browser
+func (m Model) fileRows() int {
+	return len(m.fileEntries) + m.fileRowOffset()
+}
+
+// downloadFile copies the selected entry to the host in the background
+func (m *Model) downloadFile(dest string) tea.Cmd {
+	if dest == "" {
+		return nil
+	}
+	cli, id, p := m.cli, m.containers[m.selectedContainer].ID, m.selectedFilePath()
+	return m.startFileOp(fmt.Sprintf("Downloading %s to %s", p, dest), 0, func(ctx context.Context, op *fileOp) tea.Msg {
+		err := docker.DownloadPath(ctx, cli, id, p, dest, func(n int) { op.copied.Add(int64(n)) })
+		return copiedMsg{op: op, result: fmt.Sprintf("Downloaded %s to %s", p, dest), err: err}
+	})
+}
+
+// uploadFile copies a local file or directory into the current directory in the background
+func (m *Model) uploadFile(local string) tea.Cmd {
+	if local == "" {
+		return nil
+	}
+	cli, id, dir := m.cli, m.containers[m.selectedContainer].ID, m.filePath
+	return m.startFileOp(fmt.Sprintf("Uploading %s to %s", local, dir), 0, func(ctx context.Context, op *fileOp) tea.Msg {
+		err := docker.UploadPath(ctx, cli, id, local, dir, func(n int) { op.copied.Add(int64(n)) })
+		return copiedMsg{op: op, result: fmt.Sprintf("Uploaded %s to %s", local, dir), reload: true, err: err}
+	})
+}
+
+// finishCopy reports the end of a download or upload, listing the current directory
+// again when an upload changed it
+func (m *Model) finishCopy(msg copiedMsg) tea.Cmd {
+	if !m.finishFileOp(msg.op) {
+		return nil
+	}
+	if msg.err != nil {
+		m.logOutput = fmt.Sprintf("Error: %v", msg.err)
+		return nil
+	}
+	m.logOutput = msg.result
+	if msg.reload && m.state == "files" {
+		return m.loadDir(m.filePath)
+	}
+	return nil
+}
+
+// fileOpStatus describes the running file operation and how much it has copied
+func (m Model) fileOpStatus() string {
+	if m.fileOp == nil {
+		return ""
+	}
+	status := m.fileOp.desc
+	if copied := m.fileOp.copied.Load(); copied > 0 {
+		status += fmt.Sprintf(": %s", formatBytes(float64(copied)))
+	}
+	return status + fmt.Sprintf(" • %s: Cancel", m.keys.binding("back").Help().Key)
+}
+
+// defaultDownloadPath suggests extracting the selected entry into the working directory
+func (m Model) defaultDownloadPath() string {
+	dir, err := os.Getwd()
+	if err != nil {
+		dir = "."
+	}
+	return filepath.Join(dir, "pulse-download")
+}
+
+// renderFiles renders the entries of the current directory
+func (m Model) renderFiles(header string) string {
+	container := m.containers[m.selectedContainer]
+	name := strings.TrimPrefix(container.Names[0], "/")
+
+	var rows []string
+	if m.filePath != "/" {
+		rows = append(rows, fmt.Sprintf("%-11s %10s %-16s %s", "", "", "", ".."))
+	}
+	for _, entry := range m.fileEntries {
+		display := entry.Name
+		size := formatBytes(float64(entry.Size))
+		if entry.IsDir() {
+			display += "/"
+			size = ""
+		}
+		if entry.LinkTarget != "" {
+			display += " → " + entry.LinkTarget
+		}
+		rows = append(rows, fmt.Sprintf("%-11s %10s %-16s %s",
+			entry.Mode.String(), size, entry.ModTime.Local().Format("2006-01-02 15:04"), display))
+	}
+
+	// Show the window of rows around the selection
+	height := max(m.viewportHeight-16, 5)
+	start := max(0, min(m.selectedFile-height/2, len(rows)-height))
+	end := min(len(rows), start+height)
+
+	fileList := ""
+	if len(rows) == 0 {
+		fileList = m.theme.Unselected.Render("Empty directory") + "\n"
+	}
+	for i := start; i < end; i++ {
+		if i == m.selectedFile {
+			fileList += m.theme.Selected.Render("❯ "+rows[i]) + "\n"
+		} else {
+			fileList += m.theme.Unselected.Render("  "+rows[i]) + "\n"
+		}
+	}
+
+	panels := []string{header, m.theme.Container.Render(
+		m.theme.Title.Render(fmt.Sprintf("Files in %s: %s", name, m.filePath)) + "\n" +
+			fileList + "\n" +
+			m.theme.Instruction.Render(m.keys.viewHelp("files", "Back to containers", m.keys.hint("select", "Open"))))}
+	if status := m.fileOpStatus(); status != "" {
+		panels = append(panels, m.theme.LogPanel.Render(m.theme.Log.Render(status)))
+	} else if m.logOutput != "" {
+		panels = append(panels, m.theme.LogPanel.Render(m.theme.Log.Render(m.logOutput)))
+	}
+	return lipgloss.JoinVertical(lipgloss.Left, panels...)
+}
+
+// renderFileContent renders a text file from the container
+func (m Model) renderFileContent(header string) string {
+	scroll := fmt.Sprintf("%3.0f%%", m.fileView.ScrollPercent()*100)
+	panel := m.theme.LogPanel.Render(
+		m.theme.Title.Render(m.fileTitle) + "\n" + m.fileView.View() + "\n" +
+			m.theme.Instruction.Render(scroll+" • "+m.keys.viewHelp("fileContent", "Back to files", m.keys.joinHint("Scroll", "up", "down", "page_up", "page_down"))))
+	if status := m.fileOpStatus(); status != "" {
+		return lipgloss.JoinVertical(lipgloss.Left, header, panel, m.theme.LogPanel.Render(m.theme.Log.Render(status)))
+	}
+	return lipgloss.JoinVertical(lipgloss.Left, header, panel)
+}
@@ -0,0 +1,296 @@
+package ui
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/docker/go-units"
+
+	"pulse/internal/store"
+)
+
+// graphWindows are the windows the graph view zooms through
+var graphWindows = []time.Duration{5 * time.Minute, 15 * time.Minute, time.Hour, 6 * time.Hour, 24 * time.Hour}
+
+// sparkWidth is the width of the sparklines in the stack and container lists
+const sparkWidth = 12
+
+// sparkBlocks are the levels of a sparkline, lowest first
+var sparkBlocks = []rune("▁▂▃▄▅▆▇█")
+
+// metric extracts one value from the samples of a container. Rates are computed
+// from the counters of consecutive samples, so they take the previous sample.
+type metric struct {
+	title  string
+	format func(float64) string
+	value  func(prev *store.Sample, s store.Sample) (float64, bool)
+}
+
+// gauge builds a metric read directly from a sample
+func gauge(title string, format func(float64) string, value func(store.Sample) float64) metric {
+	return metric{title: title, format: format, value: func(_ *store.Sample, s store.Sample) (float64, bool) {
+		return value(s), true
+	}}
+}
+
+// rate builds a per-second metric from a counter; counter resets are skipped
+func rate(title string, counter func(store.Sample) uint64) metric {
+	return metric{title: title, format: formatRate, value: func(prev *store.Sample, s store.Sample) (float64, bool) {
+		if prev == nil || counter(s) < counter(*prev) {
+			return 0, false
+		}
+		seconds := s.Time.Sub(prev.Time).Seconds()
+		if seconds <= 0 {
+			return 0, false
+		}
+		return float64(counter(s)-counter(*prev)) / seconds, true
+	}}
+}
+
+// The metrics of the graph view
+var (
+	cpuMetric    = gauge("CPU", formatPercent, func(s store.Sample) float64 { return s.CPUPercent })
+	memoryMetric = gauge("Memory", formatBytes, func(s store.Sample) float64 { return float64(s.MemoryUsage) })
+	graphMetrics = []metric{
+		cpuMetric,
+		memoryMetric,
+		rate("Network in", func(s store.Sample) uint64 { return s.NetworkRx }),
+		rate("Network out", func(s store.Sample) uint64 { return s.NetworkTx }),
+		rate("Block read", func(s store.Sample) uint64 { return s.BlockRead }),
+		rate("Block write", func(s store.Sample) uint64 { return s.BlockWrite }),
+	}
+)
+
+func formatPercent(v float64) string { return fmt.Sprintf("%.1f%%", v) }
+func formatBytes(v float64) string   { return units.BytesSize(v) }
+func formatRate(v float64) string    { return units.BytesSize(v) + "/s" }
+
+// series averages a metric of one container's samples into n buckets between start
+// and end. Buckets narrower than the sampling interval repeat the last value for up
+// to hold; other buckets without samples are NaN, so gaps stay visible.
+func series(samples []store.Sample, m metric, start, end time.Time, n int, hold time.Duration) []float64 {
+	sums := make([]float64, n)
+	counts := make([]int, n)
+	bucket := end.Sub(start) / time.Duration(n)
+
+	for i, s := range samples {
+		if s.Time.Before(start) || !s.Time.Before(end) || bucket <= 0 {
+			continue
+		}
+		var prev *store.Sample
+		if i > 0 {
+			prev = &samples[i-1]
+		}
+		v, ok := m.value(prev, s)
+		if !ok {
+			continue
+		}
+		b := int(s.Time.Sub(start) / bucket)
+		sums[b] += v
+		counts[b]++
+	}
+
+	values := make([]float64, n)
+	last, held := math.NaN(), 0
+	for i := range values {
+		values[i] = math.NaN()
+		if counts[i] > 0 {
+			values[i] = sums[i] / float64(counts[i])
+			last, held = values[i], 0
+		} else if held++; time.Duration(held)*bucket < hold {
+			values[i] = last
+		}
+	}
+	return values
+}
+
+// sumSeries adds up the series of several containers; a bucket is NaN only when it
+// is NaN for every container
+func sumSeries(all [][]float64, n int) []float64 {
+	total := make([]float64, n)
+	for i := range total {
+		total[i] = math.NaN()
+		for _, values := range all {
+			if !math.IsNaN(values[i]) {
+				if math.IsNaN(total[i]) {
+					total[i] = 0
+				}
+				total[i] += values[i]
+			}
+		}
+	}
+	return total
+}
+
+// seriesMax returns the largest value of a series, at least floor
+func seriesMax(values []float64, floor float64) float64 {
+	top := floor
+	for _, v := range values {
+		if !math.IsNaN(v) && v > top {
+			top = v
+		}
+	}
+	return top
+}
+
+// sampleHold is how long a sample stands in for the buckets after it: a missed
+// sample is bridged, a stopped container is not
+func (m Model) sampleHold() time.Duration {
+	return 2 * m.cfg.History.SampleInterval
+}
+
+// shortDuration formats a window without trailing zero units, e.g. 15m or 1h
+func shortDuration(d time.Duration) string {
+	s := d.String()
+	if strings.HasSuffix(s, "m0s") {
+		s = strings.TrimSuffix(s, "0s")
+	}
+	if strings.HasSuffix(s, "h0m") {
+		s = strings.TrimSuffix(s, "0m")
+	}
+	return s
+}
+
+// sparkline draws a series with block characters, scaled to top
+func sparkline(values []float64, top float64) string {
+	var b strings.Builder
+	for _, v := range values {
+		if math.IsNaN(v) {
+			b.WriteRune(' ')
+			continue
+		}
+		level := int(v / top * float64(len(sparkBlocks)-1))
+		level = max(0, min(level, len(sparkBlocks)-1))
+		b.WriteRune(sparkBlocks[level])
+	}
+	return b.String()
+}
+
+// containerSpark draws the CPU sparkline of a container over the default graph window
+func (m Model) containerSpark(containerID string) string {
+	end := time.Now()
+	values := series(m.samples[containerID], cpuMetric, end.Add(-m.cfg.GraphWindow), end, sparkWidth, m.sampleHold())
+	return sparkline(values, seriesMax(values, 100))
+}
+
+// stackSpark draws the combined CPU sparkline of the containers of a stack
+func (m Model) stackSpark(stack string) string {
+	end := time.Now()
+	var all [][]float64
+	for _, samples := range m.samples {
+		if len(samples) > 0 && samples[0].Stack == stack {
+			all = append(all, series(samples, cpuMetric, end.Add(-m.cfg.GraphWindow), end, sparkWidth, m.sampleHold()))
+		}
+	}
+	if len(all) == 0 {
+		return strings.Repeat(" ", sparkWidth)
+	}
+	values := sumSeries(all, sparkWidth)
+	return sparkline(values, seriesMax(values, 100))
+}
+
+// brailleChart draws a series as an area chart of braille characters, each holding
+// 2x4 dots, scaled to top. Rows are returned top first.
+func brailleChart(values []float64, width, height int, top float64) []string {
+	// Dot bits of a braille cell by column and row from the top
+	bits := [2][4]rune{{0x01, 0x02, 0x04, 0x40}, {0x08, 0x10, 0x20, 0x80}}
+
+	cells := make([][]rune, height)
+	for row := range cells {
+		cells[row] = make([]rune, width)
+	}
+
+	dotsHigh := height * 4
+	for x := 0; x < width*2 && x < len(values); x++ {
+		v := values[x]
+		if math.IsNaN(v) || top <= 0 {
+			continue
+		}
+		filled := int(math.Round(v / top * float64(dotsHigh)))
+		filled = max(0, min(filled, dotsHigh))
+		if filled == 0 && v > 0 {
+			filled = 1
+		}
+		for dot := 0; dot < filled; dot++ {
+			y := dotsHigh - 1 - dot
+			cells[y/4][x/2] |= bits[x%2][y%4]
+		}
+	}
+
+	rows := make([]string, height)
+	for row, line := range cells {
+		var b strings.Builder
+		for _, c := range line {
+			b.WriteRune(0x2800 + c)
+		}
+		rows[row] = b.String()
+	}
+	return rows
+}
+
+// renderChart renders one metric of a container as a titled braille chart
+func (m Model) renderChart(samples []store.Sample, mt metric, width, height int) string {
+	end := time.Now()
+	values := series(samples, mt, end.Add(-m.graphWindow), end, width*2, m.sampleHold())
+
+	floor := 0.0
+	if mt.title == cpuMetric.title {
+		floor = 100
+	}
+	top := seriesMax(values, floor)
+	if top == 0 {
+		top = 1
+	}
+
+	current := "–"
+	for i := len(values) - 1; i >= 0; i-- {
+		if !math.IsNaN(values[i]) {
+			current = mt.format(values[i])
+			break
+		}
+	}
+
+	title := fmt.Sprintf("%s %s (max %s)", mt.title, m.theme.Running.Render(current), mt.format(top))
+	chart := m.theme.Running.Render(strings.Join(brailleChart(values, width, height, top), "\n"))
+	return m.theme.Container.Render(title + "\n" + chart)
+}
+
+// renderGraph renders the full-screen graphs of the selected container
+func (m Model) renderGraph(header string) string {
+	container := m.containers[m.selectedContainer]
+	samples := m.samples[container.ID]
+	name := strings.TrimPrefix(container.Names[0], "/")
+
+	// Two charts per row, leaving room for borders, padding and titles
+	width := max((m.viewportWidth-16)/2, 10)
+	height := max((m.viewportHeight-24)/3, 2)
+
+	var rows []string
+	for i := 0; i < len(graphMetrics); i += 2 {
+		rows = append(rows, lipgloss.JoinHorizontal(lipgloss.Top,
+			m.renderChart(samples, graphMetrics[i], width, height),
+			m.renderChart(samples, graphMetrics[i+1], width, height)))
+	}
+
+	title := m.theme.Title.Render(fmt.Sprintf("Resources: %s (last %s)", name, shortDuration(m.graphWindow)))
+	body := lipgloss.JoinVertical(lipgloss.Left, rows...)
+	if len(samples) == 0 {
+		body = m.theme.Unselected.Render("No samples yet; containers are sampled every " + m.cfg.History.SampleInterval.String())
+	}
+
+	return lipgloss.JoinVertical(lipgloss.Left, header, title, body,
+		m.theme.Instruction.Render(keyHelp(m.keys.joinHint("Zoom window", "zoom_in", "zoom_out"), m.keys.hint("back", "Back to containers"))))
+}
+
+// zoomGraph moves to the next larger or smaller graph window
+func (m *Model) zoomGraph(step int) {
+	i := 0
+	for i < len(graphWindows)-1 && graphWindows[i] < m.graphWindow {
+		i++
+	}
+	i = max(0, min(i+step, len(graphWindows)-1))
+	m.graphWindow = graphWindows[i]
+}
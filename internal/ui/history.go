@@ -0,0 +1,205 @@
+package ui
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"sync"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/docker/docker/client"
+
+	"pulse/internal/docker"
+	"pulse/internal/store"
+)
+
+// compactInterval is how often records past the retention are dropped from the store
+const compactInterval = time.Hour
+
+// unsafeDirChars matches the characters of a context name not used in directory names
+var unsafeDirChars = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// samplesMsg delivers one round of resource samples
+type samplesMsg []store.Sample
+
+// openHistory opens the store of the connected context and loads the events and
+// samples still within the retention
+func (m *Model) openHistory() error {
+	dir := filepath.Join(m.cfg.History.Dir, unsafeDirChars.ReplaceAllString(m.contextName, "_"))
+	history, err := store.Open(dir, m.cfg.History.Retention)
+	if err != nil {
+		return err
+	}
+
+	since := time.Now().Add(-m.cfg.History.Retention)
+	events, err := history.Events(since)
+	if err != nil {
+		history.Close()
+		return err
+	}
+	samples, err := history.Samples(since)
+	if err != nil {
+		history.Close()
+		return err
+	}
+
+	m.history = history
+	for _, event := range events {
+		if !m.cfg.IsHidden(event.Stack) {
+			m.events = append(m.events, event)
+		}
+	}
+	if len(m.events) > maxEvents {
+		m.events = m.events[len(m.events)-maxEvents:]
+	}
+	m.addSamples(samples)
+	return nil
+}
+
+// closeHistory closes the store, if one is open
+func (m *Model) closeHistory() {
+	if m.history != nil {
+		m.history.Close()
+		m.history = nil
+	}
+}
+
+// startSampler samples the resource usage of the stack containers in the background,
+// recording samples and container exits in the store when it is enabled
+func (m *Model) startSampler() {
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	// Stopping waits for the sampler, so a round in flight cannot write to the store
+	// once it is closed
+	m.stopSampler = func() {
+		cancel()
+		<-done
+	}
+
+	cli, history, out, interval := m.cli, m.history, m.sampleCh, m.cfg.History.SampleInterval
+	go func() {
+		defer close(done)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		lastExits := time.Now()
+		lastCompact := time.Now()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+
+			samples := sampleContainers(ctx, cli, interval)
+			if history != nil {
+				_ = history.AppendSamples(samples)
+
+				now := time.Now()
+				if exits, err := docker.ListContainerExits(ctx, cli, lastExits); err == nil {
+					for _, exit := range exits {
+						_ = history.AppendExit(exit)
+					}
+					lastExits = now
+				}
+				if now.Sub(lastCompact) >= compactInterval {
+					_ = history.Compact()
+					lastCompact = now
+				}
+			}
+
+			select {
+			case out <- samples:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+}
+
+// sampleContainers samples every running stack container on this node concurrently
+func sampleContainers(ctx context.Context, cli *client.Client, timeout time.Duration) []store.Sample {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	containers, err := docker.ListAllStackContainers(ctx, cli, false)
+	if err != nil {
+		return nil
+	}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	var samples []store.Sample
+	for _, c := range containers {
+		if c.State != "running" {
+			continue
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			stats, err := docker.GetResourceStats(ctx, cli, c.ID)
+			if err != nil {
+				return
+			}
+			summary := docker.SummarizeContainer(c)
+			mu.Lock()
+			samples = append(samples, store.Sample{
+				Stack:         summary.Stack,
+				Service:       summary.Service,
+				Container:     summary.Name,
+				ResourceStats: stats,
+			})
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+	return samples
+}
+
+// waitForSamples delivers the next round of samples as a message
+func (m Model) waitForSamples() tea.Cmd {
+	if m.sampleCh == nil {
+		return nil
+	}
+	samples := m.sampleCh
+	return func() tea.Msg {
+		return samplesMsg(<-samples)
+	}
+}
+
+// addSamples keeps the samples within the retention for each container, and forgets
+// containers that have not been sampled within it
+func (m *Model) addSamples(samples []store.Sample) {
+	if m.samples == nil {
+		m.samples = make(map[string][]store.Sample)
+	}
+	for _, sample := range samples {
+		m.samples[sample.ContainerID] = append(m.samples[sample.ContainerID], sample)
+	}
+
+	cutoff := time.Now().Add(-m.cfg.History.Retention)
+	for id, history := range m.samples {
+		i := 0
+		for i < len(history) && history[i].Time.Before(cutoff) {
+			i++
+		}
+		if i == len(history) {
+			delete(m.samples, id)
+		} else if i > 0 {
+			m.samples[id] = history[i:]
+		}
+	}
+}
+
+// recordEvent stores a timeline event, reporting a failing store once in the log output
+func (m *Model) recordEvent(event docker.Event) {
+	if m.history == nil {
+		return
+	}
+	if err := m.history.AppendEvent(event); err != nil && !m.historyFailed {
+		m.historyFailed = true
+		m.logOutput = fmt.Sprintf("Error writing history: %v", err)
+	}
+}
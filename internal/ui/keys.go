@@ -0,0 +1,270 @@
+package ui
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/key"
+)
+
+// keyAction is an action of the TUI and the keys bound to it. Actions that only apply
+// in some views list them, so different actions may share a key in different views,
+// like 's' for secrets in the stack list and for signals in the process list.
+type keyAction struct {
+	name    string
+	states  []string
+	binding key.Binding
+}
+
+// keyMap holds the active key bindings, in the order the help lists them
+type keyMap struct {
+	actions []keyAction
+}
+
+// keyPress is a pressed key, or a sequence of keys separated by spaces like "g g",
+// matched against the bindings
+type keyPress string
+
+func (k keyPress) String() string { return string(k) }
+
+// newAction builds an action bound to keys; states limit it to some views
+func newAction(name, desc string, states []string, keys ...string) keyAction {
+	return keyAction{name: name, states: states, binding: key.NewBinding(key.WithKeys(keys...), key.WithHelp(formatKeys(keys), desc))}
+}
+
+// defaultKeyMap returns the built-in bindings, which the keybindings config overrides
+// by action name
+func defaultKeyMap() keyMap {
+	stack := []string{"stack"}
+	return keyMap{actions: []keyAction{
+		newAction("up", "Move up", nil, "up"),
+		newAction("down", "Move down", nil, "down"),
+		newAction("page_up", "Page up", nil, "pgup"),
+		newAction("page_down", "Page down", nil, "pgdown"),
+		newAction("top", "Go to the top", nil, "home"),
+		newAction("bottom", "Go to the bottom", nil, "end"),
+		newAction("select", "View containers", nil, "enter"),
+		newAction("actions", "Action menu", stack, "a"),
+		newAction("restart", "Restart stack", []string{"actionMenu"}, "r"),
+		newAction("kill", "Kill stack", []string{"actionMenu"}, "k"),
+		newAction("logs", "Stack logs", []string{"actionMenu"}, "l"),
+		newAction("secrets", "Secrets & configs", stack, "s"),
+		newAction("create_config", "Create config", []string{"secrets"}, "c"),
+		newAction("create_secret", "Create secret", []string{"secrets"}, "n"),
+		newAction("rotate", "Rotate secret", []string{"secrets"}, "t"),
+		newAction("images", "Images", stack, "i"),
+		newAction("delete", "Remove image", []string{"images"}, "d"),
+		newAction("prune", "Prune", []string{"images", "diskUsage"}, "p"),
+		newAction("prune_all", "Prune unused images", []string{"images"}, "P"),
+		newAction("disk_usage", "Disk usage", stack, "u"),
+		newAction("health", "Stack health", stack, "e"),
+		newAction("timeline", "Event timeline", stack, "v"),
+		newAction("filter", "Filter events", []string{"timeline"}, "f"),
+		newAction("event_type", "Cycle event type", []string{"timeline"}, "t"),
+		newAction("log_hits", "Log trigger hits", stack, "h"),
+		newAction("filter_stacks", "Filter stacks", stack, "/"),
+		newAction("search", "Find a container", []string{"stack", "containerList"}, "ctrl+f"),
+		newAction("sort", "Sort by", stack, "o"),
+		newAction("reverse_sort", "Reverse sort", stack, "O"),
+		newAction("contexts", "Switch host", stack, "x"),
+		newAction("graph", "Resource graphs", []string{"containerList"}, "g"),
+		newAction("zoom_in", "Zoom in", []string{"graph"}, "+"),
+		newAction("zoom_out", "Zoom out", []string{"graph"}, "-"),
+		newAction("processes", "Processes", []string{"containerList"}, "o"),
+		newAction("signal", "Send a signal", []string{"processes"}, "s"),
+		newAction("files", "Files", []string{"containerList"}, "f"),
+		newAction("download", "Download", []string{"files", "fileContent"}, "d"),
+		newAction("upload", "Upload", []string{"files"}, "u"),
+		newAction("changes", "Changes", []string{"containerList"}, "c"),
+		newAction("expand_all", "Expand all", []string{"changes"}, "+"),
+		newAction("collapse_all", "Collapse all", []string{"changes"}, "-"),
+		newAction("reload", "Reload", []string{"changes"}, "r"),
+		newAction("back", "Back/Escape", nil, "esc", "backspace", "b"),
+		newAction("quit", "Quit application", nil, "q"),
+	}}
+}
+
+// vimKeyMap moves the actions vim users expect elsewhere on to other keys: hjkl
+// navigate, gg/G jump to the ends and ctrl+d/ctrl+u page
+func vimKeyMap() keyMap {
+	k := defaultKeyMap()
+	k.rebind("up", "up", "k")
+	k.rebind("down", "down", "j")
+	k.rebind("back", "esc", "backspace", "b", "h")
+	k.rebind("select", "enter", "l")
+	k.rebind("page_up", "pgup", "ctrl+u")
+	k.rebind("page_down", "pgdown", "ctrl+d")
+	k.rebind("top", "home", "g g")
+	k.rebind("bottom", "end", "G")
+	k.rebind("kill", "K")
+	k.rebind("logs", "L")
+	k.rebind("log_hits", "H")
+	k.rebind("graph", "m")
+	return k
+}
+
+// keyPresets are the key maps the keymap config can start from
+var keyPresets = map[string]func() keyMap{
+	"default": defaultKeyMap,
+	"vim":     vimKeyMap,
+}
+
+// newKeyMap starts from a preset and applies the keybindings config, returning a
+// message about an unknown preset or actions
+func newKeyMap(preset string, bindings map[string]string) (keyMap, string) {
+	var problems []string
+	newPreset, ok := keyPresets[preset]
+	if !ok {
+		problems = append(problems, fmt.Sprintf("unknown keymap %q, using the default keys", preset))
+		newPreset = defaultKeyMap
+	}
+	k := newPreset()
+
+	var unknown []string
+	for name, keys := range bindings {
+		if !k.rebind(name, splitKeys(keys)...) {
+			unknown = append(unknown, name)
+		}
+	}
+	if len(unknown) > 0 {
+		slices.Sort(unknown)
+		problems = append(problems, "unknown keybinding actions: "+strings.Join(unknown, ", "))
+	}
+
+	if len(problems) > 0 {
+		return k, "Keys config: " + strings.Join(problems, "; ")
+	}
+	return k, ""
+}
+
+// splitKeys reads the keys of an action from the config: one key, or several
+// separated by commas, e.g. "k,up"
+func splitKeys(keys string) []string {
+	if len(keys) <= 1 {
+		return []string{keys}
+	}
+	var split []string
+	for _, k := range strings.Split(keys, ",") {
+		if k = strings.TrimSpace(k); k != "" {
+			split = append(split, k)
+		}
+	}
+	return split
+}
+
+// rebind replaces the keys of an action, reporting whether the action exists
+func (k *keyMap) rebind(name string, keys ...string) bool {
+	for i := range k.actions {
+		if k.actions[i].name == name {
+			k.actions[i].binding.SetKeys(keys...)
+			k.actions[i].binding.SetHelp(formatKeys(keys), k.actions[i].binding.Help().Desc)
+			return true
+		}
+	}
+	return false
+}
+
+// binding returns the binding of an action
+func (k keyMap) binding(name string) key.Binding {
+	for _, a := range k.actions {
+		if a.name == name {
+			return a.binding
+		}
+	}
+	return key.NewBinding(key.WithDisabled())
+}
+
+// action resolves a key press to an action in a view. Actions of the view win over
+// the ones available everywhere, so rebinding e.g. kill to 'k' in the vim key map
+// still kills in the action menu while 'k' moves up elsewhere.
+func (k keyMap) action(press keyPress, state string) string {
+	global := ""
+	for _, a := range k.actions {
+		if !key.Matches(press, a.binding) {
+			continue
+		}
+		if a.states == nil {
+			if global == "" {
+				global = a.name
+			}
+		} else if slices.Contains(a.states, state) {
+			return a.name
+		}
+	}
+	return global
+}
+
+// isPrefix reports whether the press starts a key sequence such as "g g"
+func (k keyMap) isPrefix(press keyPress) bool {
+	for _, a := range k.actions {
+		for _, bound := range a.binding.Keys() {
+			if strings.HasPrefix(bound, string(press)+" ") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// help returns the bindings of the actions available in a view, for the help panel
+func (k keyMap) help(state string) []key.Binding {
+	var bindings []key.Binding
+	for _, a := range k.actions {
+		if slices.Contains(a.states, state) && a.binding.Enabled() {
+			bindings = append(bindings, a.binding)
+		}
+	}
+	return bindings
+}
+
+// hint returns the binding of an action described for a particular view, e.g. back
+// as "Back to files"
+func (k keyMap) hint(name, desc string) key.Binding {
+	b := k.binding(name)
+	b.SetHelp(b.Help().Key, desc)
+	return b
+}
+
+// joinHint combines the bindings of several actions into one hint, e.g. "↑/↓: Navigate"
+func (k keyMap) joinHint(desc string, names ...string) key.Binding {
+	var keys, shown []string
+	for _, name := range names {
+		if b := k.binding(name); b.Enabled() {
+			keys = append(keys, b.Keys()...)
+			shown = append(shown, b.Help().Key)
+		}
+	}
+	return key.NewBinding(key.WithKeys(keys...), key.WithHelp(strings.Join(shown, "/"), desc))
+}
+
+// viewHelp renders the hints of a view: the leading ones such as navigation, the
+// actions of the view, then back described for the view
+func (k keyMap) viewHelp(state, back string, leading ...key.Binding) string {
+	bindings := append(leading, k.help(state)...)
+	return keyHelp(append(bindings, k.hint("back", back))...)
+}
+
+// formatKeys shows keys the way the help lists them, e.g. "↑/k" or "gg"
+func formatKeys(keys []string) string {
+	names := map[string]string{"up": "↑", "down": "↓", "left": "←", "right": "→", " ": "space"}
+	formatted := make([]string, 0, len(keys))
+	for _, k := range keys {
+		if name, ok := names[k]; ok {
+			k = name
+		}
+		formatted = append(formatted, strings.ReplaceAll(k, " ", ""))
+	}
+	return strings.Join(formatted, "/")
+}
+
+// keyHelp renders bindings as one line of hints, e.g. "g: Resource graphs • o: Processes"
+func keyHelp(bindings ...key.Binding) string {
+	hints := make([]string, 0, len(bindings))
+	for _, b := range bindings {
+		if b.Enabled() {
+			hints = append(hints, fmt.Sprintf("%s: %s", b.Help().Key, b.Help().Desc))
+		}
+	}
+	return strings.Join(hints, " • ")
+}
@@ -0,0 +1,183 @@
+package ui
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestKeyMapAction(t *testing.T) {
+	tests := []struct {
+		name     string
+		preset   string
+		bindings map[string]string
+		press    keyPress
+		state    string
+		want     string
+	}{
+		{name: "global action", preset: "default", press: "down", state: "stack", want: "down"},
+		{name: "view action", preset: "default", press: "s", state: "stack", want: "secrets"},
+		{name: "same key in another view", preset: "default", press: "s", state: "processes", want: "signal"},
+		{name: "action of another view", preset: "default", press: "i", state: "containerList", want: ""},
+		{name: "unbound key", preset: "default", press: "z", state: "stack", want: ""},
+		{name: "vim moves with j", preset: "vim", press: "j", state: "stack", want: "down"},
+		{name: "vim goes back with h", preset: "vim", press: "h", state: "containerList", want: "back"},
+		{name: "vim moves log hits to H", preset: "vim", press: "H", state: "stack", want: "log_hits"},
+		{name: "vim jumps to the top with gg", preset: "vim", press: "g g", state: "stack", want: "top"},
+		{name: "view action wins over a global one", preset: "vim", bindings: map[string]string{"kill": "k"}, press: "k", state: "actionMenu", want: "kill"},
+		{name: "global action outside the view", preset: "vim", bindings: map[string]string{"kill": "k"}, press: "k", state: "stack", want: "up"},
+		{name: "vim kill", preset: "vim", press: "K", state: "actionMenu", want: "kill"},
+		{name: "rebound action", preset: "default", bindings: map[string]string{"quit": "ctrl+q"}, press: "ctrl+q", state: "stack", want: "quit"},
+		{name: "old key of a rebound action", preset: "default", bindings: map[string]string{"quit": "ctrl+q"}, press: "q", state: "stack", want: ""},
+		{name: "several keys", preset: "default", bindings: map[string]string{"images": "i, I"}, press: "I", state: "stack", want: "images"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			keys, problems := newKeyMap(tt.preset, tt.bindings)
+			if problems != "" {
+				t.Fatalf("newKeyMap() problems = %q", problems)
+			}
+			if got := keys.action(tt.press, tt.state); got != tt.want {
+				t.Errorf("action(%q, %q) = %q, want %q", tt.press, tt.state, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestKeyMapIsPrefix(t *testing.T) {
+	tests := []struct {
+		name   string
+		preset string
+		press  keyPress
+		want   bool
+	}{
+		{name: "vim g starts gg", preset: "vim", press: "g", want: true},
+		{name: "vim gg is complete", preset: "vim", press: "g g", want: false},
+		{name: "vim other key", preset: "vim", press: "j", want: false},
+		{name: "no sequences by default", preset: "default", press: "g", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			keys, _ := newKeyMap(tt.preset, nil)
+			if got := keys.isPrefix(tt.press); got != tt.want {
+				t.Errorf("isPrefix(%q) = %v, want %v", tt.press, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewKeyMapProblems(t *testing.T) {
+	tests := []struct {
+		name     string
+		preset   string
+		bindings map[string]string
+		want     string
+	}{
+		{name: "valid", preset: "vim", bindings: map[string]string{"quit": "Q"}, want: ""},
+		{name: "unknown preset", preset: "emacs", want: `Keys config: unknown keymap "emacs", using the default keys`},
+		{
+			name:     "unknown actions",
+			preset:   "default",
+			bindings: map[string]string{"zap": "z", "quit": "Q", "fly": "f"},
+			want:     "Keys config: unknown keybinding actions: fly, zap",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, got := newKeyMap(tt.preset, tt.bindings); got != tt.want {
+				t.Errorf("newKeyMap() problems = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSplitKeys(t *testing.T) {
+	tests := []struct {
+		keys string
+		want []string
+	}{
+		{keys: ",", want: []string{","}},
+		{keys: "k", want: []string{"k"}},
+		{keys: "k,up", want: []string{"k", "up"}},
+		{keys: " k , up ,", want: []string{"k", "up"}},
+		{keys: "g g", want: []string{"g g"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.keys, func(t *testing.T) {
+			if got := splitKeys(tt.keys); !slices.Equal(got, tt.want) {
+				t.Errorf("splitKeys(%q) = %q, want %q", tt.keys, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestKeyHints(t *testing.T) {
+	keys, _ := newKeyMap("default", nil)
+	vim, _ := newKeyMap("vim", nil)
+
+	tests := []struct {
+		name string
+		got  func() string
+		want string
+	}{
+		{
+			name: "hint keeps the keys",
+			got:  func() string { return keyHelp(keys.hint("back", "Back to files")) },
+			want: "esc/backspace/b: Back to files",
+		},
+		{
+			name: "hint leaves the binding alone",
+			got: func() string {
+				keys.hint("back", "Back to files")
+				return keyHelp(keys.binding("back"))
+			},
+			want: "esc/backspace/b: Back/Escape",
+		},
+		{
+			name: "joined hint",
+			got:  func() string { return keyHelp(vim.joinHint("Navigate", "up", "down")) },
+			want: "↑/k/↓/j: Navigate",
+		},
+		{
+			name: "joined hint skips unknown actions",
+			got:  func() string { return keyHelp(keys.joinHint("Jump", "top", "nothing", "bottom")) },
+			want: "home/end: Jump",
+		},
+		{
+			name: "key sequences",
+			got:  func() string { return keyHelp(vim.binding("top")) },
+			want: "home/gg: Go to the top",
+		},
+		{
+			name: "unknown action is left out",
+			got:  func() string { return keyHelp(keys.binding("nothing"), keys.binding("quit")) },
+			want: "q: Quit application",
+		},
+		{
+			name: "view help",
+			got: func() string {
+				return vim.viewHelp("files", "Back to containers", vim.hint("select", "Open"))
+			},
+			want: "enter/l: Open • d: Download • u: Upload • esc/backspace/b/h: Back to containers",
+		},
+		{
+			name: "view help follows rebinding",
+			got: func() string {
+				rebound, _ := newKeyMap("default", map[string]string{"zoom_in": "=", "back": "esc"})
+				return rebound.viewHelp("graph", "Back")
+			},
+			want: "=: Zoom in • -: Zoom out • esc: Back",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.got(); got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,214 @@
+package ui
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/bubbles/viewport"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+
+	"pulse/internal/alerts"
+	"pulse/internal/docker"
+)
+
+// maxLogHits bounds the hits kept for the log hits screen
+const maxLogHits = 200
+
+// hitLogWindow is how much log history around a hit is loaded when jumping to it
+const hitLogWindow = 2 * time.Minute
+
+// startLogWatcher follows the logs covered by the log triggers in the background
+func (m *Model) startLogWatcher() {
+	ctx, cancel := context.WithCancel(context.Background())
+	m.stopWatcher = cancel
+	go alerts.NewLogWatcher(m.cli, m.cfg, m.logTriggers).Run(ctx, m.hitCh)
+}
+
+// waitForHit delivers the next log trigger hit as a message
+func (m Model) waitForHit() tea.Cmd {
+	if m.hitCh == nil {
+		return nil
+	}
+	hits := m.hitCh
+	return func() tea.Msg {
+		return logHitMsg(<-hits)
+	}
+}
+
+// addLogHit records a hit, newest first, and notifies about it unless throttled
+func (m Model) addLogHit(hit alerts.LogHit) (tea.Model, tea.Cmd) {
+	m.logHits = append([]alerts.LogHit{hit}, m.logHits...)
+	if len(m.logHits) > maxLogHits {
+		m.logHits = m.logHits[:maxLogHits]
+	}
+	// Keep the same hit selected as new ones arrive
+	if m.state == "logHits" && len(m.logHits) > 1 {
+		m.selectedHit = min(m.selectedHit+1, len(m.logHits)-1)
+	}
+
+	if hit.Throttled {
+		return m, m.waitForHit()
+	}
+	if m.state == "stack" {
+		m.logOutput = alerts.Summary(hit.Alert())
+	}
+	if len(m.notifiers) == 0 {
+		return m, m.waitForHit()
+	}
+
+	notifiers := m.notifiers
+	notify := func() tea.Msg {
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		return notifiedMsg{err: alerts.Notify(ctx, notifiers, []alerts.Alert{hit.Alert()})}
+	}
+	return m, tea.Batch(m.waitForHit(), notify)
+}
+
+// openHitLogs loads the service logs around a hit into the log viewport, scrolled
+// to and highlighting the matching line
+func (m *Model) openHitLogs(hit alerts.LogHit) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	service, err := docker.FindStackService(ctx, m.cli, hit.Stack, hit.Service)
+	if err != nil {
+		m.logOutput = fmt.Sprintf("Error finding service: %v", err)
+		return
+	}
+
+	logs, err := docker.ServiceLogs(ctx, m.cli, service, docker.LogOptions{
+		Tail:       -1,
+		Since:      strconv.FormatInt(hit.Time.Add(-hitLogWindow).Unix(), 10),
+		Until:      strconv.FormatInt(hit.Time.Add(hitLogWindow).Unix(), 10),
+		Timestamps: true,
+	})
+	if err != nil {
+		m.logOutput = fmt.Sprintf("Error loading logs: %v", err)
+		return
+	}
+
+	lines := strings.Split(strings.TrimRight(logs, "\n"), "\n")
+	target := findHitLine(lines, hit)
+	if target >= 0 {
+		lines[target] = m.theme.HitLine.Render(lines[target])
+	}
+
+	width, height := hitViewSize(m.viewportWidth, m.viewportHeight)
+	m.hitView = viewport.New(width, height)
+	m.hitView.SetContent(strings.Join(lines, "\n"))
+	if target >= 0 {
+		m.hitView.SetYOffset(target - height/2)
+	} else {
+		m.logOutput = "The matching line is no longer in the service logs"
+	}
+	m.hitTitle = fmt.Sprintf("%s around %s (%s)", hit.Service, hit.Time.Local().Format("15:04:05"), hit.Trigger)
+	m.state = "hitLogs"
+}
+
+// findHitLine returns the index of the line that produced the hit: of the lines with
+// the same text, the one logged closest to when the hit was seen
+func findHitLine(lines []string, hit alerts.LogHit) int {
+	best, bestDistance := -1, time.Duration(-1)
+	for i, line := range lines {
+		stamp, text, ok := strings.Cut(line, " ")
+		if !ok || text != hit.Line {
+			continue
+		}
+		logged, err := time.Parse(time.RFC3339Nano, stamp)
+		if err != nil {
+			continue
+		}
+
+		distance := hit.Time.Sub(logged)
+		if distance < 0 {
+			distance = -distance
+		}
+		if best < 0 || distance < bestDistance {
+			best, bestDistance = i, distance
+		}
+	}
+	return best
+}
+
+// hitViewSize returns the size of the log viewport for a terminal size
+func hitViewSize(width, height int) (int, int) {
+	return max(width-8, 20), max(height-10, 5)
+}
+
+// renderLogHitsPanel renders the latest log trigger hits next to the stack list
+func (m Model) renderLogHitsPanel() string {
+	hitText := m.theme.Title.Render("Log Triggers") + "\n"
+	if len(m.logHits) == 0 {
+		hitText += m.theme.Unselected.Render("No matches yet") + "\n"
+	}
+	for _, hit := range m.logHits[:min(3, len(m.logHits))] {
+		hitText += m.theme.Other.Render("● "+hit.Service) + "\n" +
+			fmt.Sprintf("  %s\n", truncate(hit.Line, m.theme.HelpPanel.GetWidth()-8))
+	}
+	hitText += m.theme.Instruction.Render(fmt.Sprintf("Press '%s' for all hits", m.keys.binding("log_hits").Help().Key))
+	return m.theme.HelpPanel.Render(hitText)
+}
+
+// renderLogHits renders every recorded hit, newest first, with the context of the selected one
+func (m Model) renderLogHits(header string) string {
+	hitList := ""
+	if len(m.logHits) == 0 {
+		hitList = m.theme.Unselected.Render("No log lines have matched a trigger yet")
+	}
+
+	lineWidth := m.viewportWidth - 50
+	for i, hit := range m.logHits {
+		line := fmt.Sprintf("%-8s %-20s %-24s %s",
+			hit.Time.Local().Format("15:04:05"), truncate(hit.Trigger, 20), truncate(hit.Service, 24), truncate(hit.Line, lineWidth))
+		if i == m.selectedHit {
+			hitList += m.theme.Selected.Render("❯ "+line) + "\n"
+		} else {
+			hitList += m.theme.Unselected.Render("  "+line) + "\n"
+		}
+	}
+
+	panels := []string{header, m.theme.StackPanel.Width(m.viewportWidth - 4).Render(
+		m.theme.Title.Render(fmt.Sprintf("Log Trigger Hits (%d)", len(m.logHits))) + "\n" + hitList)}
+
+	if len(m.logHits) > 0 {
+		hit := m.logHits[m.selectedHit]
+		context := strings.Join(hit.Before, "\n")
+		if context != "" {
+			context += "\n"
+		}
+		context += m.theme.HitLine.Render(hit.Line)
+		if len(hit.After) > 0 {
+			context += "\n" + strings.Join(hit.After, "\n")
+		}
+		panels = append(panels, m.theme.LogPanel.Width(m.viewportWidth-4).Render(context))
+	}
+
+	panels = append(panels, m.theme.Instruction.Render(keyHelp(m.keys.joinHint("Navigate", "up", "down"), m.keys.hint("select", "Jump to line in logs"), m.keys.hint("back", "Back"))))
+	return lipgloss.JoinVertical(lipgloss.Left, panels...)
+}
+
+// renderHitLogs renders the log viewport opened from a hit
+func (m Model) renderHitLogs(header string) string {
+	scroll := fmt.Sprintf("%3.0f%%", m.hitView.ScrollPercent()*100)
+	panel := m.theme.LogPanel.Render(
+		m.theme.Title.Render("Logs: "+m.hitTitle) + "\n" + m.hitView.View() + "\n" +
+			m.theme.Instruction.Render(scroll+" • "+keyHelp(m.keys.joinHint("Scroll", "up", "down", "page_up", "page_down"), m.keys.hint("back", "Back to hits"))))
+	return lipgloss.JoinVertical(lipgloss.Left, header, panel)
+}
+
+// truncate shortens s to at most width characters
+func truncate(s string, width int) string {
+	if width <= 1 {
+		return ""
+	}
+	runes := []rune(s)
+	if len(runes) <= width {
+		return s
+	}
+	return string(runes[:width-1]) + "…"
+}
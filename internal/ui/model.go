@@ -4,26 +4,67 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"slices"
+	"sort"
+	"strings"
+	"time"
 
+	"github.com/charmbracelet/bubbles/textinput"
+	"github.com/charmbracelet/bubbles/viewport"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/docker/docker/api/types"
 	"github.com/docker/docker/client"
+	"github.com/docker/go-units"
 
+	"pulse/internal/agent"
+	"pulse/internal/alerts"
+	"pulse/internal/config"
 	"pulse/internal/docker"
+	"pulse/internal/store"
 )
 
+// refreshMsg triggers the periodic refresh of the stack list
+type refreshMsg time.Time
+
+// logHitMsg delivers a line matched by a log trigger
+type logHitMsg alerts.LogHit
+
+// notifiedMsg reports the outcome of sending a notification
+type notifiedMsg struct {
+	err error
+}
+
+// alertsMsg carries the result of an alert check run in the background
+type alertsMsg struct {
+	transitions []alerts.Alert
+	active      []alerts.Alert
+	err         error
+}
+
 // Model represents the application state
 type Model struct {
 	stacks        []string
 	selectedStack int
-	cli           *client.Client
-	state         string
-	logOutput     string
-	containers    []types.Container
-	debug         bool
+	// Stack opened in the action menu, health view or container list. It is kept by
+	// name so a refresh changing the stack list cannot swap it for another.
+	currentStack string
+	cli          *client.Client
+	state        string
+	logOutput    string
+	containers   []types.Container
+	debug        bool
+	cfg          config.Config
+
+	// Active key bindings, and the start of a key sequence being typed
+	keys      keyMap
+	keyPrefix string
+
+	// Styles of the configured colour theme
+	theme Theme
 
 	// New fields for enhanced information
-	stackStats     map[string]StackStats
+	stackStats     map[string]docker.StackStats
+	stackHealth    map[string]docker.StackHealth
 	viewportWidth  int
 	viewportHeight int
 	activeServices int
@@ -31,90 +72,287 @@ type Model struct {
 
 	// Add selected container tracking
 	selectedContainer int
-}
 
-// StackStats holds statistics for a stack
-type StackStats struct {
-	Running     int
-	Stopped     int
-	Other       int
-	TotalMemory string
-	TotalCPU    string
+	// Secrets and configs screen
+	resources        []docker.SwarmResource
+	selectedResource int
+
+	// Images screen
+	images        []docker.ImageInfo
+	selectedImage int
+	imageLayers   []docker.ImageLayer
+
+	// Disk usage dashboard
+	diskUsage       docker.DiskUsageSummary
+	diskUsageErr    error
+	diskUsageAt     time.Time
+	selectedSection int
+
+	// Docker contexts and the one currently connected
+	contexts        []docker.Context
+	selectedContext int
+	contextName     string
+
+	// Agents on other swarm nodes, and the node and agent of each listed container
+	agentPool       *agent.Pool
+	agents          []*agent.Client
+	agentsReachable int
+	localNode       string
+	containerNodes  map[string]string
+	containerAgents map[string]*agent.Client
+
+	// Alert rules engine, the alerts currently firing and the last check's error
+	alerts         *alerts.Engine
+	activeAlerts   []alerts.Alert
+	alertsErr      error
+	alertsChecking bool
+	notifiers      []alerts.Notifier
+
+	// Log triggers, the hits they found and the log viewport a hit jumps into
+	logTriggers []alerts.LogTrigger
+	logHits     []alerts.LogHit
+	selectedHit int
+	hitCh       chan alerts.LogHit
+	stopWatcher context.CancelFunc
+	hitView     viewport.Model
+	hitTitle    string
+
+	// Event timeline, recorded since startup or backfilled from events_since
+	events        []docker.Event
+	eventCh       chan docker.Event
+	stopEvents    context.CancelFunc
+	selectedEvent int
+	eventFilter   eventFilter
+
+	// On-disk history and the resource samples of each container, by container ID
+	history       *store.Store
+	historyFailed bool
+	samples       map[string][]store.Sample
+	sampleCh      chan []store.Sample
+	stopSampler   func()
+	graphWindow   time.Duration
+
+	// Processes of the selected container and the signal menu; processesLoading is
+	// set while they are listed in the background
+	processes        []docker.Process
+	processesErr     error
+	processesLoading bool
+	selectedProcess  int
+	selectedSignal   int
+
+	// Filesystem browser of the selected container
+	filePath     string
+	fileEntries  []docker.FileEntry
+	selectedFile int
+	fileView     viewport.Model
+	fileTitle    string
+	fileOp       *fileOp
+
+	// Changes of the selected container relative to its image
+	changeTree       *changeNode
+	changesErr       error
+	collapsedChanges map[string]bool
+	selectedChange   int
+
+	// Every visible stack and its containers; stacks holds the ones matching stackFilter.
+	// refreshing is set while a background refresh collects them.
+	refreshing      bool
+	refreshErr      string // status shown for the last failed refresh
+	allStacks       []string
+	stackContainers []types.Container
+	stackFilter     string
+	stackSort       config.StackSort
+
+	// Swarm replacements of each task's container, for the restarts column
+	containerRestarts map[string]int
+
+	// Global container search
+	searchReturn   string
+	selectedResult int
+
+	// Free-form prompt used by actions that need user input
+	input       textinput.Model
+	inputAction string
+	inputReturn string
 }
 
 // NewModel creates and initializes a new model
-func NewModel(cli *client.Client, debug bool) Model {
-	stacks, err := docker.ListStacks(context.Background(), cli)
-	if err != nil {
-		log.Fatalf("Error listing stacks: %v", err)
+func NewModel(cli *client.Client, cfg config.Config) Model {
+	// The first snapshot is collected before the UI starts; later ones in the background
+	localNode := ""
+	agentPool, agentErr := newAgentPool(cfg)
+	if agentPool != nil {
+		localNode, _ = docker.NodeName(context.Background(), cli)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), refreshTimeout)
+	snapshot := collectStacks(ctx, cli, cfg, agentPool, localNode)
+	cancel()
+	if snapshot.err != nil {
+		log.Fatalf("Error listing stacks: %v", snapshot.err)
 	}
 
-	// Get initial stack statistics
-	stackStats := make(map[string]StackStats)
-	var activeServices, totalServices int
+	input := textinput.New()
+	input.CharLimit = 256
 
-	for _, stack := range stacks {
-		containers, err := docker.ListContainers(context.Background(), cli, stack)
-		if err != nil {
-			log.Printf("Error getting containers for stack %s: %v", stack, err)
-			continue
-		}
+	keys, logOutput := newKeyMap(cfg.Keymap, cfg.Keybindings)
+	theme, themeProblem := loadTheme(cfg.Theme, cfg.Themes)
+	if themeProblem != "" {
+		logOutput = themeProblem
+	}
 
-		stats := StackStats{}
-		for _, c := range containers {
-			totalServices++
-			switch c.State {
-			case "running":
-				stats.Running++
-				activeServices++
-			case "exited", "stopped":
-				stats.Stopped++
-			default:
-				stats.Other++
-			}
-		}
-		stackStats[stack] = stats
+	contextName := "default"
+	if cfg.Context != "" {
+		contextName = cfg.Context
+	} else if cfg.DockerHost != "" {
+		contextName = cfg.DockerHost
 	}
 
-	return Model{
-		stacks:            stacks,
+	m := Model{
 		selectedStack:     0,
 		cli:               cli,
 		state:             "stack",
-		debug:             debug,
-		stackStats:        stackStats,
-		activeServices:    activeServices,
-		totalServices:     totalServices,
+		debug:             cfg.Debug,
+		cfg:               cfg,
+		keys:              keys,
+		theme:             theme,
+		logOutput:         logOutput,
 		viewportWidth:     100, // Default, will be updated
 		viewportHeight:    30,  // Default, will be updated
 		selectedContainer: 0,   // Initialize selected container
+		input:             input,
+		contextName:       contextName,
+		eventCh:           make(chan docker.Event, 64),
+		sampleCh:          make(chan []store.Sample, 1),
+		graphWindow:       cfg.GraphWindow,
+		stackSort:         cfg.StackSort,
+		localNode:         localNode,
+		agentPool:         agentPool,
+		diskUsageAt:       time.Now(), // Measured by Init
 	}
+	if agentErr != nil {
+		m.logOutput = fmt.Sprintf("Agents disabled: %v", agentErr)
+	}
+	if !slices.Contains(stackSortKeys, m.stackSort.Key) {
+		m.logOutput = fmt.Sprintf("Unknown stack_sort key %q in config; sorting by name", m.stackSort.Key)
+		m.stackSort = config.StackSort{Key: config.DefaultStackSort}
+	}
+	if unknown := unknownContainerColumns(cfg.ContainerColumns); len(unknown) > 0 {
+		m.logOutput = fmt.Sprintf("Unknown container_columns in config: %s", strings.Join(unknown, ", "))
+	}
+	if cfg.History.Enabled {
+		if err := m.openHistory(); err != nil {
+			m.logOutput = fmt.Sprintf("History disabled: %v", err)
+		}
+	}
+	m.startSampler()
+	m.startEventWatcher()
+
+	if len(cfg.AlertRules) > 0 || len(cfg.LogTriggers) > 0 {
+		if err := m.setupAlerts(); err != nil {
+			m.logOutput = fmt.Sprintf("Alerts disabled: %v", err)
+		}
+	}
+
+	m.applySnapshot(snapshot)
+
+	return m
+}
+
+// WithStatus shows a message in the status line, e.g. a problem found while starting
+func (m Model) WithStatus(text string) Model {
+	m.logOutput = text
+	return m
 }
 
 // Update handles UI state updates based on messages
 func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	var cmd tea.Cmd
 	switch msg := msg.(type) {
 	case tea.KeyMsg:
-		switch msg.String() {
-		case "q":
+		// Prompts capture every key until submitted or cancelled
+		if m.state == "input" {
+			return m.updateInput(msg)
+		}
+		if m.state == "stackFilter" {
+			return m.updateStackFilter(msg)
+		}
+		if m.state == "search" {
+			return m.updateSearch(msg)
+		}
+
+		// Keys of a sequence such as "g g" are collected until it is complete
+		press := keyPress(msg.String())
+		if m.keyPrefix != "" {
+			press = keyPress(m.keyPrefix + " " + msg.String())
+			m.keyPrefix = ""
+		}
+		if m.keys.isPrefix(press) {
+			m.keyPrefix = string(press)
+			return m, nil
+		}
+
+		action := m.keys.action(press, m.state)
+		switch action {
+		case "quit":
+			m.shutdown()
 			return m, tea.Quit
-		case "enter":
+		case "select":
 			if m.state == "stack" {
-				m.state = "containerList"
-				m.selectedContainer = 0 // Reset selected container when entering container list
-				fmt.Println("len(m.stacks)", len(m.stacks))
-				if len(m.stacks) > 0 {
-					containers, err := docker.ListContainers(context.Background(), m.cli, m.stacks[m.selectedStack])
+				if m.openStack("containerList") {
+					m.loadStackContainers()
+				}
+			} else if m.state == "logHits" && len(m.logHits) > 0 {
+				m.openHitLogs(m.logHits[m.selectedHit])
+			} else if m.state == "signal" {
+				cmd = m.signalContainer()
+			} else if m.state == "files" && m.fileRows() > 0 {
+				cmd = m.openFileEntry()
+			} else if m.state == "changes" {
+				m.toggleChange()
+			} else if m.state == "secrets" && len(m.resources) > 0 {
+				resource := m.resources[m.selectedResource]
+				if resource.Kind == "config" {
+					content, err := docker.ViewConfigContent(context.Background(), m.cli, resource.ID)
 					if err != nil {
-						m.logOutput = fmt.Sprintf("Error listing containers: %v", err)
+						m.logOutput = fmt.Sprintf("Error retrieving config content: %v", err)
 					} else {
-						m.containers = containers
+						m.logOutput = content
+						m.state = "resourceContent"
 					}
+				} else {
+					m.logOutput = "Secret values cannot be read back from the swarm"
+				}
+			} else if m.state == "images" && len(m.images) > 0 {
+				img := m.images[m.selectedImage]
+				layers, err := docker.ImageHistory(context.Background(), m.cli, img.ID)
+				if err != nil {
+					m.logOutput = fmt.Sprintf("Error retrieving image history: %v", err)
+				} else {
+					m.imageLayers = layers
+					m.state = "imageHistory"
+				}
+			} else if m.state == "contexts" && len(m.contexts) > 0 {
+				cmd = m.switchContext(m.contexts[m.selectedContext])
+			} else if m.state == "diskUsage" {
+				// Jump to the screen managing the selected section
+				switch m.selectedSection {
+				case 0:
+					m.state = "images"
+					m.selectedImage = 0
+					m.logOutput = ""
+					m.loadImages()
+				case 1:
+					m.state = "stack"
+					m.logOutput = ""
+					cmd = m.refresh()
+				default:
+					m.logOutput = fmt.Sprintf("%s have no screen of their own; press '%s' to prune %s",
+						m.diskUsage.Sections()[m.selectedSection].Name, m.keys.binding("prune").Help().Key, pruneTargets[m.selectedSection])
 				}
 			} else if m.state == "containerList" && len(m.containers) > 0 {
 				// View logs for the selected container
 				m.state = "containerLogs"
-				logs, err := docker.ViewContainerLogs(context.Background(), m.cli, m.containers[m.selectedContainer].ID)
+				logs, err := m.containerLogs(m.containers[m.selectedContainer].ID)
 				if err != nil {
 					m.logOutput = fmt.Sprintf("Error retrieving container logs: %v", err)
 					m.state = "containerList" // Return to container list on error
@@ -122,25 +360,232 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 					m.logOutput = logs
 				}
 			}
-		case "a":
+		case "actions":
 			if m.state == "stack" {
-				m.state = "actionMenu"
+				m.openStack("actionMenu")
+			}
+		case "secrets":
+			if m.state == "stack" {
+				m.state = "secrets"
+				m.selectedResource = 0
+				m.logOutput = ""
+				m.loadResources()
+			}
+		case "signal":
+			if m.state == "processes" {
+				m.state = "signal"
+				m.selectedSignal = 0
+			}
+		case "sort":
+			if m.state == "stack" {
+				m.cycleStackSort()
+			}
+		case "processes":
+			if m.state == "containerList" && len(m.containers) > 0 {
+				m.state = "processes"
+				m.selectedProcess = 0
+				m.logOutput = ""
+				m.processes, m.processesErr = nil, nil
+				cmd = m.loadProcesses()
+			}
+		case "images":
+			if m.state == "stack" {
+				m.state = "images"
+				m.selectedImage = 0
+				m.logOutput = ""
+				m.loadImages()
+			}
+		case "delete":
+			if m.state == "images" && len(m.images) > 0 {
+				img := m.images[m.selectedImage]
+				if err := docker.RemoveImage(context.Background(), m.cli, img.ID, false); err != nil {
+					m.logOutput = fmt.Sprintf("Error: %v", err)
+				} else {
+					m.logOutput = fmt.Sprintf("Removed image %s (%s)", shortImageID(img.ID), units.BytesSize(float64(img.Size)))
+				}
+				m.loadImages()
+			}
+		case "download":
+			if (m.state == "files" && m.fileRows() > 0) || m.state == "fileContent" {
+				m.startInput("download", fmt.Sprintf("Download %s to (a .tar file or a directory): ", m.selectedFilePath()))
+				m.input.SetValue(m.defaultDownloadPath())
+			}
+		case "health":
+			if m.state == "stack" {
+				m.openStack("health")
+			}
+		case "log_hits":
+			if m.state == "stack" && len(m.logTriggers) > 0 {
+				m.state = "logHits"
+				m.selectedHit = 0
+			}
+		case "graph":
+			if m.state == "containerList" && len(m.containers) > 0 {
+				m.state = "graph"
+			}
+		case "zoom_in":
+			if m.state == "graph" {
+				m.zoomGraph(1)
+			}
+		case "zoom_out":
+			if m.state == "graph" {
+				m.zoomGraph(-1)
+			}
+		case "expand_all":
+			if m.state == "changes" {
+				m.setChangesCollapsed(false)
+			}
+		case "collapse_all":
+			if m.state == "changes" {
+				m.setChangesCollapsed(true)
+			}
+		case "timeline":
+			if m.state == "stack" {
+				m.state = "timeline"
+				m.selectedEvent = max(len(m.filteredEvents())-1, 0)
+			}
+		case "filter":
+			if m.state == "timeline" {
+				filter := m.eventFilter.String()
+				m.startInput("eventFilter", "Filter (stack=NAME service=NAME type=container|health|service|task): ")
+				m.input.SetValue(filter)
+			}
+		case "files":
+			if m.state == "containerList" && len(m.containers) > 0 {
+				cmd = m.openFiles()
+			}
+		case "reverse_sort":
+			if m.state == "stack" {
+				m.reverseStackSort()
+			}
+		case "filter_stacks":
+			if m.state == "stack" {
+				m.startStackFilter()
+			}
+		case "search":
+			if m.state == "stack" || m.state == "containerList" {
+				m.startSearch()
+			}
+		case "page_up":
+			if m.state == "hitLogs" {
+				m.hitView.HalfViewUp()
+			} else if m.state == "fileContent" {
+				m.fileView.HalfViewUp()
+			} else {
+				m.moveSelection(-m.viewportHeight / 2)
+			}
+		case "page_down":
+			if m.state == "hitLogs" {
+				m.hitView.HalfViewDown()
+			} else if m.state == "fileContent" {
+				m.fileView.HalfViewDown()
+			} else {
+				m.moveSelection(m.viewportHeight / 2)
+			}
+		case "top":
+			if m.state == "hitLogs" {
+				m.hitView.GotoTop()
+			} else if m.state == "fileContent" {
+				m.fileView.GotoTop()
+			} else if _, n := m.selection(); n > 0 {
+				m.moveSelection(-n)
+			}
+		case "bottom":
+			if m.state == "hitLogs" {
+				m.hitView.GotoBottom()
+			} else if m.state == "fileContent" {
+				m.fileView.GotoBottom()
+			} else if _, n := m.selection(); n > 0 {
+				m.moveSelection(n)
+			}
+		case "contexts":
+			if m.state == "stack" {
+				contexts, err := docker.ListContexts(m.cfg.Hosts)
+				if err != nil {
+					m.logOutput = fmt.Sprintf("Error listing contexts: %v", err)
+				} else {
+					m.contexts = contexts
+					m.selectedContext = 0
+					for i, c := range contexts {
+						if c.Name == m.contextName {
+							m.selectedContext = i
+						}
+					}
+					m.state = "contexts"
+				}
+			}
+		case "disk_usage":
+			if m.state == "stack" {
+				m.state = "diskUsage"
+				m.selectedSection = 0
+				m.logOutput = ""
+				cmd = m.loadDiskUsage(true)
+			}
+		case "upload":
+			if m.state == "files" {
+				m.startInput("upload", fmt.Sprintf("Local file or directory to upload to %s: ", m.filePath))
+			}
+		case "prune", "prune_all":
+			if m.state == "diskUsage" {
+				section := m.diskUsage.Sections()[m.selectedSection]
+				m.startInput("pruneSection", fmt.Sprintf("Prune %s? Up to %s reclaimable (y/N): ",
+					pruneTargets[m.selectedSection], units.BytesSize(float64(section.Reclaimable))))
+			} else if m.state == "images" && action == "prune_all" {
+				unused, kept := 0, 0
+				for _, img := range m.images {
+					if img.Unused() {
+						unused++
+					} else if len(img.Containers) == 0 {
+						kept++
+					}
+				}
+				m.startInput("pruneImages", fmt.Sprintf("Remove %d image(s) no container or service uses, keeping %d used only by services? (y/N): ", unused, kept))
+			} else if m.state == "images" {
+				m.pruneImages(false)
+			}
+		case "create_config":
+			if m.state == "secrets" {
+				m.startInput("createConfig", "Config name and file path (name path): ")
+			}
+		case "changes":
+			if m.state == "containerList" && len(m.containers) > 0 {
+				m.openChanges()
+			}
+		case "create_secret":
+			if m.state == "secrets" {
+				m.startInput("createSecret", "Secret name and file path (name path): ")
+			}
+		case "event_type":
+			if m.state == "timeline" {
+				m.cycleEventType()
+			}
+		case "rotate":
+			if m.state == "secrets" && len(m.resources) > 0 {
+				if m.resources[m.selectedResource].Kind == "secret" {
+					m.startInput("rotateSecret", "File with the new secret value: ")
+				} else {
+					m.logOutput = "Only secrets can be rotated"
+				}
 			}
 		case "up":
-			if m.state == "stack" && m.selectedStack > 0 {
-				m.selectedStack--
-			} else if m.state == "containerList" && m.selectedContainer > 0 {
-				m.selectedContainer--
+			if m.state == "hitLogs" {
+				m.hitView.LineUp(1)
+			} else if m.state == "fileContent" {
+				m.fileView.LineUp(1)
+			} else {
+				m.moveSelection(-1)
 			}
 		case "down":
-			if m.state == "stack" && m.selectedStack < len(m.stacks)-1 {
-				m.selectedStack++
-			} else if m.state == "containerList" && m.selectedContainer < len(m.containers)-1 {
-				m.selectedContainer++
+			if m.state == "hitLogs" {
+				m.hitView.LineDown(1)
+			} else if m.state == "fileContent" {
+				m.fileView.LineDown(1)
+			} else {
+				m.moveSelection(1)
 			}
-		case "r":
+		case "restart":
 			if m.state == "actionMenu" {
-				selectedStack := m.stacks[m.selectedStack]
+				selectedStack := m.currentStack
 				err := docker.RestartStack(context.Background(), m.cli, selectedStack)
 				if err != nil {
 					m.logOutput = fmt.Sprintf("Error restarting stack: %v", err)
@@ -149,9 +594,13 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				}
 				m.state = "stack"
 			}
-		case "k":
+		case "reload":
+			if m.state == "changes" {
+				m.loadChanges()
+			}
+		case "kill":
 			if m.state == "actionMenu" {
-				selectedStack := m.stacks[m.selectedStack]
+				selectedStack := m.currentStack
 				err := docker.KillStack(context.Background(), m.cli, selectedStack)
 				if err != nil {
 					m.logOutput = fmt.Sprintf("Error killing stack: %v", err)
@@ -161,14 +610,12 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				m.state = "stack"
 
 				// Update stats after kill operation
-				stacks, _ := docker.ListStacks(context.Background(), m.cli)
-				m.stacks = stacks
-				m.updateStackStats()
+				cmd = m.refresh()
 			}
-		case "l":
+		case "logs":
 			if m.state == "actionMenu" {
-				selectedStack := m.stacks[m.selectedStack]
-				logs, err := docker.ViewStackLogs(context.Background(), m.cli, selectedStack)
+				selectedStack := m.currentStack
+				logs, err := docker.ViewStackLogs(context.Background(), m.cli, selectedStack, m.cfg.LogTail)
 				if err != nil {
 					m.logOutput = fmt.Sprintf("Error retrieving logs: %v", err)
 				} else {
@@ -176,61 +623,552 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				}
 				m.state = "stack"
 			}
-		case "escape", "backspace", "b":
-			// Multiple keys for going back for better UX
+		case "back":
 			switch m.state {
 			case "containerLogs":
 				m.state = "containerList"
 				m.logOutput = "" // Clear log output when going back
+			case "graph", "processes", "changes":
+				m.state = "containerList"
+				m.logOutput = ""
+			case "files":
+				// Esc cancels a running file operation before leaving
+				if !m.cancelFileOp() {
+					m.state = "containerList"
+					m.logOutput = ""
+				}
+			case "fileContent":
+				if !m.cancelFileOp() {
+					m.state = "files"
+				}
+			case "signal":
+				m.state = "processes"
+			case "stack":
+				if m.stackFilter != "" {
+					m.stackFilter = ""
+					m.applyStackFilter()
+				}
 			case "containerList":
 				m.state = "stack"
 				// Refresh stack stats when returning to stack view
-				m.updateStackStats()
+				cmd = m.refresh()
 			case "actionMenu":
 				m.state = "stack"
+			case "secrets":
+				m.state = "stack"
+				m.logOutput = ""
+			case "resourceContent":
+				m.state = "secrets"
+				m.logOutput = ""
+			case "images":
+				m.state = "stack"
+				m.logOutput = ""
+			case "imageHistory":
+				m.state = "images"
+			case "diskUsage":
+				m.state = "stack"
+				m.logOutput = ""
+			case "contexts":
+				m.state = "stack"
+			case "health", "timeline":
+				m.state = "stack"
+			case "logHits":
+				m.state = "stack"
+			case "hitLogs":
+				m.state = "logHits"
 			}
 		}
+	case refreshMsg:
+		// Leave the screen alone while the user is typing into a prompt
+		if m.state != "input" {
+			cmd = m.refresh()
+		}
+		if m.state == "processes" {
+			cmd = tea.Batch(cmd, m.loadProcesses())
+		}
+		cmd = tea.Batch(cmd, m.loadDiskUsage(false))
+		if m.alerts != nil && !m.alertsChecking {
+			m.alertsChecking = true
+			return m, tea.Batch(m.refreshTick(), cmd, m.checkAlerts())
+		}
+		return m, tea.Batch(m.refreshTick(), cmd)
+	case refreshedMsg:
+		m.refreshing = false
+		// A snapshot of the daemon switched away from is replaced by a fresh one
+		if msg.cli != m.cli {
+			return m, m.refresh()
+		}
+		if m.state != "input" {
+			m.applySnapshot(stackSnapshot(msg))
+		}
+	case processesMsg:
+		m.showProcesses(msg)
+	case dirMsg:
+		m.showDir(msg)
+	case fileContentMsg:
+		m.showFileContent(msg)
+	case copiedMsg:
+		cmd = m.finishCopy(msg)
+	case fileTickMsg:
+		if msg.op == m.fileOp {
+			cmd = fileTick(msg.op)
+		}
+	case diskUsageMsg:
+		if msg.cli == m.cli {
+			m.diskUsage, m.diskUsageErr = msg.usage, msg.err
+		}
+	case logHitMsg:
+		return m.addLogHit(alerts.LogHit(msg))
+	case eventMsg:
+		return m.addEvent(docker.Event(msg))
+	case samplesMsg:
+		m.addSamples(msg)
+		return m, m.waitForSamples()
+	case notifiedMsg:
+		if msg.err != nil && m.state != "input" {
+			m.logOutput = fmt.Sprintf("Error sending notification: %v", msg.err)
+		}
+	case alertsMsg:
+		m.alertsChecking = false
+		m.activeAlerts = msg.active
+		m.alertsErr = msg.err
+		if len(msg.transitions) > 0 && m.state != "input" {
+			m.logOutput = alerts.Summary(msg.transitions[len(msg.transitions)-1])
+		}
 	case tea.WindowSizeMsg:
 		// Save window dimensions for responsive layout
 		m.viewportWidth = msg.Width
 		m.viewportHeight = msg.Height
 
 		// Update header width to match viewport width
-		headerStyle = headerStyle.Width(msg.Width)
+		m.theme.Header = m.theme.Header.Width(msg.Width)
+		m.hitView.Width, m.hitView.Height = hitViewSize(msg.Width, msg.Height)
+		m.fileView.Width, m.fileView.Height = hitViewSize(msg.Width, msg.Height)
 	}
-	return m, nil
+	return m, cmd
 }
 
 // Init initializes the model
 func (m Model) Init() tea.Cmd {
+	return tea.Batch(m.refreshTick(), m.checkAlerts(), m.waitForHit(), m.waitForEvent(), m.waitForSamples(), m.loadDiskUsage(true))
+}
+
+// setupAlerts builds the alert engine, the notifiers and the log triggers from the
+// config and starts following logs
+func (m *Model) setupAlerts() error {
+	rules, err := alerts.NewRules(m.cfg.AlertRules)
+	if err != nil {
+		return err
+	}
+	notifiers, err := alerts.NewNotifiers(m.cfg.AlertNotifiers)
+	if err != nil {
+		return err
+	}
+	triggers, err := alerts.NewLogTriggers(m.cfg.LogTriggers)
+	if err != nil {
+		return err
+	}
+
+	m.notifiers = notifiers
+	if len(rules) > 0 {
+		m.alerts = alerts.NewEngine(rules, notifiers)
+	}
+	if len(triggers) > 0 {
+		m.logTriggers = triggers
+		m.hitCh = make(chan alerts.LogHit, 16)
+		m.startLogWatcher()
+	}
 	return nil
 }
 
-// Helper method to update stack statistics
-func (m *Model) updateStackStats() {
-	m.stackStats = make(map[string]StackStats)
-	m.activeServices = 0
-	m.totalServices = 0
+// checkAlerts evaluates the alert rules in the background; sampling resource usage
+// takes a while, so it must not block the UI
+func (m Model) checkAlerts() tea.Cmd {
+	if m.alerts == nil {
+		return nil
+	}
+	engine, cli := m.alerts, m.cli
+	return func() tea.Msg {
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
 
-	for _, stack := range m.stacks {
-		containers, err := docker.ListContainers(context.Background(), m.cli, stack)
-		if err != nil {
-			continue
+		transitions, err := engine.Check(ctx, cli)
+		return alertsMsg{transitions: transitions, active: engine.Active(), err: err}
+	}
+}
+
+// refreshTick schedules the next automatic refresh, if enabled
+func (m Model) refreshTick() tea.Cmd {
+	if m.cfg.RefreshInterval <= 0 {
+		return nil
+	}
+	return tea.Tick(m.cfg.RefreshInterval, func(t time.Time) tea.Msg {
+		return refreshMsg(t)
+	})
+}
+
+// openStack opens a view of the selected stack, reporting whether there was one
+func (m *Model) openStack(state string) bool {
+	if m.selectedStack >= len(m.stacks) {
+		return false
+	}
+	m.currentStack = m.stacks[m.selectedStack]
+	m.state = state
+	return true
+}
+
+// closeMissingStack returns to the stack list when the stack open in the action menu,
+// health view or container list has been removed
+func (m *Model) closeMissingStack() {
+	switch m.state {
+	case "actionMenu", "health", "containerList":
+		if !slices.Contains(m.allStacks, m.currentStack) {
+			m.state = "stack"
+			m.logOutput = fmt.Sprintf("Stack %s is no longer running", m.currentStack)
 		}
+	}
+}
+
+// selection returns the selected index of the list shown in the current view and
+// the length of the list, or nil for views without a list
+func (m *Model) selection() (*int, int) {
+	switch m.state {
+	case "stack":
+		return &m.selectedStack, len(m.stacks)
+	case "containerList":
+		return &m.selectedContainer, len(m.containers)
+	case "secrets":
+		return &m.selectedResource, len(m.resources)
+	case "images":
+		return &m.selectedImage, len(m.images)
+	case "diskUsage":
+		return &m.selectedSection, len(m.diskUsage.Sections())
+	case "contexts":
+		return &m.selectedContext, len(m.contexts)
+	case "logHits":
+		return &m.selectedHit, len(m.logHits)
+	case "timeline":
+		return &m.selectedEvent, len(m.filteredEvents())
+	case "processes":
+		return &m.selectedProcess, len(m.processes)
+	case "signal":
+		return &m.selectedSignal, len(docker.Signals)
+	case "files":
+		return &m.selectedFile, m.fileRows()
+	case "changes":
+		return &m.selectedChange, len(m.changeRows())
+	}
+	return nil, 0
+}
+
+// moveSelection moves the selection of the current list by delta, staying inside it
+func (m *Model) moveSelection(delta int) {
+	if selected, n := m.selection(); selected != nil && n > 0 {
+		*selected = max(0, min(*selected+delta, n-1))
+	}
+}
 
-		stats := StackStats{}
-		for _, c := range containers {
-			m.totalServices++
-			switch c.State {
-			case "running":
-				stats.Running++
-				m.activeServices++
-			case "exited", "stopped":
-				stats.Stopped++
-			default:
-				stats.Other++
+// loadStackContainers lists the containers of the open stack. With agents they come
+// from the last refresh, since asking every node would stall the UI.
+func (m *Model) loadStackContainers() {
+	m.selectedContainer = 0 // Reset selected container when entering container list
+	if len(m.agents) > 0 {
+		m.containers = nil
+		for _, c := range m.stackContainers {
+			if c.Labels["com.docker.stack.namespace"] == m.currentStack {
+				m.containers = append(m.containers, c)
 			}
 		}
-		m.stackStats[stack] = stats
+	} else {
+		containers, err := docker.ListContainers(context.Background(), m.cli, m.currentStack)
+		if err != nil {
+			m.logOutput = fmt.Sprintf("Error listing containers: %v", err)
+		} else {
+			m.containers = containers
+		}
+	}
+	sort.SliceStable(m.containers, func(i, j int) bool {
+		return m.containers[i].Names[0] < m.containers[j].Names[0]
+	})
+
+	if slices.Contains(m.containerColumnNames(), "restarts") {
+		m.containerRestarts, _ = docker.SlotRestarts(context.Background(), m.cli)
+	}
+}
+
+// shutdown stops the background work before quitting and closes the history store
+// once the sampler can no longer write to it, so no record is left half written
+func (m *Model) shutdown() {
+	m.cancelFileOp()
+	if m.stopWatcher != nil {
+		m.stopWatcher()
+	}
+	m.stopEvents()
+	m.stopSampler()
+	m.closeHistory()
+}
+
+// switchContext connects to another Docker daemon and reloads every view from it
+func (m *Model) switchContext(c docker.Context) tea.Cmd {
+	cli, err := docker.NewContextClient(c)
+	if err != nil {
+		m.logOutput = fmt.Sprintf("Error connecting to %s: %v", c.Name, err)
+		return nil
+	}
+
+	// Keep the current connection if the new daemon is unreachable
+	if _, err := cli.Ping(context.Background()); err != nil {
+		_ = cli.Close()
+		m.logOutput = fmt.Sprintf("Error connecting to %s: %v", c.Name, err)
+		return nil
+	}
+
+	_ = m.cli.Close()
+	m.cli = cli
+	m.contextName = c.Name
+
+	// Drop everything loaded from the previous daemon
+	m.cancelFileOp()
+	m.containers = nil
+	m.selectedContainer = 0
+	m.resources = nil
+	m.selectedResource = 0
+	m.images = nil
+	m.imageLayers = nil
+	m.selectedImage = 0
+	m.selectedStack = 0
+	m.stacks = nil
+	m.allStacks = nil
+	m.stackContainers = nil
+	m.stackFilter = ""
+	m.diskUsage, m.diskUsageErr = docker.DiskUsageSummary{}, nil
+	m.diskUsageAt = time.Time{}
+	m.activeAlerts = nil
+	m.alertsErr = nil
+	if m.alerts != nil {
+		m.alerts.Reset()
+	}
+	if m.stopWatcher != nil {
+		m.stopWatcher()
+		m.startLogWatcher()
+	}
+	m.stopEvents()
+	m.events = nil
+	m.selectedEvent = 0
+	m.samples = nil
+	m.stopSampler()
+	if m.history != nil {
+		m.closeHistory()
+		if err := m.openHistory(); err != nil {
+			m.logOutput = fmt.Sprintf("History disabled: %v", err)
+		}
+	}
+	m.startSampler()
+	m.startEventWatcher()
+
+	m.state = "stack"
+	m.logOutput = fmt.Sprintf("Switched to %s", c.Name)
+	return m.refresh()
+}
+
+// containerLogs fetches container logs through the agent of the container's node, if any
+func (m Model) containerLogs(containerID string) (string, error) {
+	if a := m.containerAgents[containerID]; a != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		return a.Logs(ctx, containerID, m.cfg.LogTail)
+	}
+	return docker.ViewContainerLogs(context.Background(), m.cli, containerID, m.cfg.LogTail)
+}
+
+// pruneImages removes dangling images, or with all every image no container or
+// service uses
+func (m *Model) pruneImages(all bool) {
+	result, err := docker.PruneImages(context.Background(), m.cli, all)
+	if err != nil {
+		m.logOutput = fmt.Sprintf("Error after pruning %d image(s): %v", result.Deleted, err)
+	} else {
+		kind := "dangling"
+		if all {
+			kind = "unused"
+		}
+		m.logOutput = fmt.Sprintf("Pruned %d %s image(s), freed %s", result.Deleted, kind, units.BytesSize(float64(result.SpaceReclaimed)))
+	}
+	m.loadImages()
+}
+
+// pruneTargets describes what pruning each disk usage section removes
+var pruneTargets = []string{"dangling images", "stopped containers", "unused anonymous volumes", "unused build cache"}
+
+// pruneSection runs the prune action matching the selected disk usage section
+func (m *Model) pruneSection() tea.Cmd {
+	ctx := context.Background()
+
+	var result docker.PruneResult
+	var err error
+	switch m.selectedSection {
+	case 0:
+		result, err = docker.PruneImages(ctx, m.cli, false)
+	case 1:
+		result, err = docker.PruneContainers(ctx, m.cli)
+	case 2:
+		result, err = docker.PruneVolumes(ctx, m.cli)
+	case 3:
+		result, err = docker.PruneBuildCache(ctx, m.cli)
+	}
+
+	if err != nil {
+		m.logOutput = fmt.Sprintf("Error: %v", err)
+	} else {
+		m.logOutput = fmt.Sprintf("Pruned %d item(s) from %s, freed %s",
+			result.Deleted, m.diskUsage.Sections()[m.selectedSection].Name, units.BytesSize(float64(result.SpaceReclaimed)))
+	}
+	return m.loadDiskUsage(true)
+}
+
+// loadResources refreshes the secrets and configs shown on the secrets screen
+func (m *Model) loadResources() {
+	secrets, err := docker.ListSecrets(context.Background(), m.cli)
+	if err != nil {
+		m.logOutput = fmt.Sprintf("Error listing secrets: %v", err)
+		return
+	}
+
+	configs, err := docker.ListConfigs(context.Background(), m.cli)
+	if err != nil {
+		m.logOutput = fmt.Sprintf("Error listing configs: %v", err)
+		return
+	}
+
+	m.resources = append(secrets, configs...)
+	if m.selectedResource >= len(m.resources) {
+		m.selectedResource = 0
+	}
+}
+
+// loadImages refreshes the images shown on the images screen
+func (m *Model) loadImages() {
+	images, err := docker.ListImages(context.Background(), m.cli)
+	if err != nil {
+		m.logOutput = fmt.Sprintf("Error listing images: %v", err)
+		return
+	}
+
+	m.images = images
+	if m.selectedImage >= len(m.images) {
+		m.selectedImage = 0
+	}
+}
+
+// startInput switches to the prompt, remembering the action to run on submit
+func (m *Model) startInput(action, prompt string) {
+	m.inputAction = action
+	m.inputReturn = m.state
+	m.input.Prompt = prompt
+	m.input.SetValue("")
+	m.input.Focus()
+	m.state = "input"
+}
+
+// updateInput feeds keys to the prompt and runs the pending action on enter
+func (m Model) updateInput(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.Type {
+	case tea.KeyEsc:
+		m.input.Blur()
+		m.state = m.inputReturn
+		return m, nil
+	case tea.KeyEnter:
+		m.input.Blur()
+		m.state = m.inputReturn
+		return m, m.runInputAction(strings.TrimSpace(m.input.Value()))
+	}
+
+	var cmd tea.Cmd
+	m.input, cmd = m.input.Update(msg)
+	return m, cmd
+}
+
+// rotationSummary describes which services a secret rotation updated and, when it
+// failed, which still use the old secret
+func rotationSummary(name string, rotation docker.SecretRotation, err error) string {
+	if rotation.NewName == "" {
+		return fmt.Sprintf("Error rotating secret %s: %v", name, err)
+	}
+
+	updated := "no service uses it"
+	if len(rotation.Updated) > 0 {
+		updated = "updated " + strings.Join(rotation.Updated, ", ")
+	}
+	if err == nil {
+		return fmt.Sprintf("Rotated %s to %s and %s", name, rotation.NewName, updated)
+	}
+
+	summary := fmt.Sprintf("Error rotating secret %s: %v", name, err)
+	if len(rotation.Updated) > 0 {
+		summary += fmt.Sprintf("\nUsing %s: %s", rotation.NewName, strings.Join(rotation.Updated, ", "))
+	}
+	if len(rotation.Failed) > 0 {
+		summary += fmt.Sprintf("\nStill using %s: %s", name, strings.Join(rotation.Failed, ", "))
+	}
+	if rotation.Removed {
+		summary += fmt.Sprintf("\nRemoved %s again since no service uses it", rotation.NewName)
+	}
+	return summary
+}
+
+// confirmed reports whether the answer to a y/N prompt is yes
+func confirmed(answer string) bool {
+	answer = strings.ToLower(answer)
+	return answer == "y" || answer == "yes"
+}
+
+// runInputAction performs the action the prompt was opened for, returning any work
+// left to do in the background
+func (m *Model) runInputAction(value string) tea.Cmd {
+	ctx := context.Background()
+
+	switch m.inputAction {
+	case "createConfig", "createSecret":
+		fields := strings.Fields(value)
+		if len(fields) != 2 {
+			m.logOutput = "Expected a name and a file path separated by a space"
+			return nil
+		}
+
+		var err error
+		if m.inputAction == "createConfig" {
+			_, err = docker.CreateConfigFromFile(ctx, m.cli, fields[0], fields[1])
+		} else {
+			_, err = docker.CreateSecretFromFile(ctx, m.cli, fields[0], fields[1])
+		}
+		if err != nil {
+			m.logOutput = fmt.Sprintf("Error: %v", err)
+			return nil
+		}
+		m.loadResources()
+		m.logOutput = fmt.Sprintf("Created %s", fields[0])
+	case "rotateSecret":
+		if value == "" || len(m.resources) == 0 {
+			return nil
+		}
+		secret := m.resources[m.selectedResource]
+		rotation, err := docker.RotateSecret(ctx, m.cli, secret.ID, value)
+		m.loadResources()
+		m.logOutput = rotationSummary(secret.Name, rotation, err)
+	case "pruneImages":
+		if confirmed(value) {
+			m.pruneImages(true)
+		}
+	case "pruneSection":
+		if confirmed(value) {
+			return m.pruneSection()
+		}
+	case "eventFilter":
+		m.setEventFilter(value)
+	case "download":
+		return m.downloadFile(value)
+	case "upload":
+		return m.uploadFile(value)
 	}
+	return nil
 }
@@ -0,0 +1,49 @@
+package ui
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"pulse/internal/config"
+	"pulse/internal/store"
+)
+
+func TestQuitClosesHistory(t *testing.T) {
+	tests := []struct {
+		name  string
+		state string
+	}{
+		{name: "stack list", state: "stack"},
+		{name: "process list", state: "processes"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			history, err := store.Open(t.TempDir(), time.Hour)
+			if err != nil {
+				t.Fatalf("Open() error = %v", err)
+			}
+
+			m := Model{state: tt.state, keys: defaultKeyMap(), history: history, stopEvents: func() {}}
+			m.cfg.History = config.History{Enabled: true, SampleInterval: time.Hour}
+			m.startSampler()
+
+			updated, cmd := m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("q")})
+			if cmd == nil {
+				t.Fatal("Update() returned no command, want tea.Quit")
+			}
+			if _, ok := cmd().(tea.QuitMsg); !ok {
+				t.Errorf("Update() command = %T, want tea.Quit", cmd())
+			}
+			if updated.(Model).history != nil {
+				t.Error("history store still open after quitting")
+			}
+			if err := history.AppendSamples([]store.Sample{{Stack: "shop"}}); !errors.Is(err, store.ErrClosed) {
+				t.Errorf("AppendSamples() after quitting error = %v, want ErrClosed", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,160 @@
+package ui
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+
+	"pulse/internal/docker"
+)
+
+// processesMsg delivers the processes of a container listed in the background
+type processesMsg struct {
+	id        string
+	processes []docker.Process
+	err       error
+}
+
+// loadProcesses lists the processes of the selected container in the background,
+// through the agent of the container's node if there is one; a listing still running
+// is not started twice
+func (m *Model) loadProcesses() tea.Cmd {
+	if m.processesLoading {
+		return nil
+	}
+	m.processesLoading = true
+	id := m.containers[m.selectedContainer].ID
+	cli, a := m.cli, m.containerAgents[id]
+	return func() tea.Msg {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+
+		var processes []docker.Process
+		var err error
+		if a != nil {
+			processes, err = a.Top(ctx, id)
+		} else {
+			processes, err = docker.ListProcesses(ctx, cli, id)
+		}
+		return processesMsg{id: id, processes: processes, err: err}
+	}
+}
+
+// showProcesses shows listed processes, busiest first, if their container is still open
+func (m *Model) showProcesses(msg processesMsg) {
+	m.processesLoading = false
+	if (m.state != "processes" && m.state != "signal") || m.selectedContainer >= len(m.containers) ||
+		m.containers[m.selectedContainer].ID != msg.id {
+		return
+	}
+	m.processesErr = msg.err
+	if msg.err != nil {
+		return
+	}
+
+	processes := msg.processes
+	cpu := func(p docker.Process) float64 {
+		v, _ := strconv.ParseFloat(p.CPU, 64)
+		return v
+	}
+	sort.SliceStable(processes, func(i, j int) bool {
+		return cpu(processes[i]) > cpu(processes[j])
+	})
+
+	m.processes = processes
+	if m.selectedProcess >= len(m.processes) {
+		m.selectedProcess = max(len(m.processes)-1, 0)
+	}
+}
+
+// signalContainer sends the selected signal to the selected container and lists its
+// processes again
+func (m *Model) signalContainer() tea.Cmd {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	container := m.containers[m.selectedContainer]
+	signal := docker.Signals[m.selectedSignal]
+	var err error
+	if a := m.containerAgents[container.ID]; a != nil {
+		err = a.Signal(ctx, container.ID, signal)
+	} else {
+		err = docker.SignalContainer(ctx, m.cli, container.ID, signal)
+	}
+
+	name := strings.TrimPrefix(container.Names[0], "/")
+	if err != nil {
+		m.logOutput = fmt.Sprintf("Error: %v", err)
+	} else {
+		m.logOutput = fmt.Sprintf("Sent %s to %s", signal, name)
+	}
+	m.state = "processes"
+	return m.loadProcesses()
+}
+
+// renderProcesses renders the process list of the selected container
+func (m Model) renderProcesses(header string) string {
+	container := m.containers[m.selectedContainer]
+	name := strings.TrimPrefix(container.Names[0], "/")
+
+	commandWidth := max(m.viewportWidth-60, 20)
+	processList := m.theme.Title.Render(fmt.Sprintf("%-8s %-12s %6s %6s %10s %s", "PID", "USER", "CPU%", "MEM%", "RSS", "COMMAND")) + "\n"
+	if m.processesErr != nil {
+		processList += m.theme.Stopped.Render(fmt.Sprintf("Error: %v", m.processesErr)) + "\n"
+	} else if len(m.processes) == 0 && m.processesLoading {
+		processList += m.theme.Unselected.Render("Loading processes...") + "\n"
+	} else if len(m.processes) == 0 {
+		processList += m.theme.Unselected.Render("No processes running") + "\n"
+	}
+	for i, p := range m.processes {
+		rss := p.RSS
+		if kb, err := strconv.ParseFloat(p.RSS, 64); err == nil {
+			rss = formatBytes(kb * 1024)
+		}
+		line := fmt.Sprintf("%-8s %-12s %6s %6s %10s %s",
+			p.PID, truncate(p.User, 12), p.CPU, p.Memory, rss, truncate(p.Command, commandWidth))
+		if i == m.selectedProcess {
+			processList += m.theme.Selected.Render("❯ "+line) + "\n"
+		} else {
+			processList += m.theme.Unselected.Render("  "+line) + "\n"
+		}
+	}
+
+	panels := []string{header, m.theme.Container.Render(
+		m.theme.Title.Render(fmt.Sprintf("Processes in %s", name)) + "\n" +
+			processList + "\n" +
+			m.theme.Instruction.Render(fmt.Sprintf("Refreshed every %s • %s", m.cfg.RefreshInterval, m.keys.viewHelp("processes", "Back", m.keys.joinHint("Navigate", "up", "down")))))}
+	if m.logOutput != "" {
+		panels = append(panels, m.theme.LogPanel.Render(m.theme.Log.Render(m.logOutput)))
+	}
+	return lipgloss.JoinVertical(lipgloss.Left, panels...)
+}
+
+// renderSignalMenu renders the signals that can be sent to the selected container
+func (m Model) renderSignalMenu(header string) string {
+	container := m.containers[m.selectedContainer]
+	name := strings.TrimPrefix(container.Names[0], "/")
+
+	signalList := ""
+	for i, signal := range docker.Signals {
+		if i == m.selectedSignal {
+			signalList += m.theme.Selected.Render("❯ "+signal) + "\n"
+		} else {
+			signalList += m.theme.Unselected.Render("  "+signal) + "\n"
+		}
+	}
+
+	panel := m.theme.ActionMenu.Width(m.viewportWidth / 2).Align(lipgloss.Left).Render(
+		m.theme.Title.Render(fmt.Sprintf("Send a signal to %s", name)) + "\n" +
+			signalList + "\n" +
+			m.theme.Instruction.Render("The signal goes to the container's main process • "+keyHelp(m.keys.hint("select", "Send"), m.keys.hint("back", "Cancel"))))
+
+	centeredPanel := lipgloss.Place(m.viewportWidth, m.viewportHeight-2, lipgloss.Center, lipgloss.Center, panel)
+	return lipgloss.JoinVertical(lipgloss.Left, header, centeredPanel)
+}
@@ -0,0 +1,203 @@
+package ui
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/client"
+
+	"pulse/internal/agent"
+	"pulse/internal/certs"
+	"pulse/internal/config"
+	"pulse/internal/docker"
+)
+
+// refreshTimeout bounds a background refresh, so an unreachable daemon or agent only
+// delays the next one
+const refreshTimeout = 30 * time.Second
+
+// diskUsageInterval is how often the disk usage is measured while it is shown; the
+// daemon walks every volume and layer to measure it
+const diskUsageInterval = time.Minute
+
+// stackSnapshot is the state of every visible stack, collected away from Update
+type stackSnapshot struct {
+	// cli is the daemon the snapshot was collected from; snapshots of a daemon the
+	// user has since switched away from are dropped
+	cli        *client.Client
+	stacks     []string
+	stats      map[string]docker.StackStats
+	containers []types.Container
+	health     map[string]docker.StackHealth
+
+	// Agents, and the node and agent of each container
+	agents          []*agent.Client
+	agentsReachable int
+	containerNodes  map[string]string
+	containerAgents map[string]*agent.Client
+
+	err error
+}
+
+// refreshedMsg delivers a snapshot collected in the background
+type refreshedMsg stackSnapshot
+
+// refresh collects the stacks in the background; a refresh still running is not
+// started twice
+func (m *Model) refresh() tea.Cmd {
+	if m.refreshing {
+		return nil
+	}
+	m.refreshing = true
+	cli, cfg, pool, localNode := m.cli, m.cfg, m.agentPool, m.localNode
+	return func() tea.Msg {
+		ctx, cancel := context.WithTimeout(context.Background(), refreshTimeout)
+		defer cancel()
+		return refreshedMsg(collectStacks(ctx, cli, cfg, pool, localNode))
+	}
+}
+
+// diskUsageMsg delivers disk usage measured in the background
+type diskUsageMsg struct {
+	cli   *client.Client
+	usage docker.DiskUsageSummary
+	err   error
+}
+
+// loadDiskUsage measures the disk usage in the background. Unless forced it is left
+// alone while the last measurement is recent or nothing shows it.
+func (m *Model) loadDiskUsage(force bool) tea.Cmd {
+	if !force && (time.Since(m.diskUsageAt) < diskUsageInterval || (m.state != "stack" && m.state != "diskUsage")) {
+		return nil
+	}
+	m.diskUsageAt = time.Now()
+	cli := m.cli
+	return func() tea.Msg {
+		ctx, cancel := context.WithTimeout(context.Background(), refreshTimeout)
+		defer cancel()
+		usage, err := docker.GetDiskUsage(ctx, cli)
+		return diskUsageMsg{cli: cli, usage: usage, err: err}
+	}
+}
+
+// collectStacks lists the visible stacks and their containers, statistics and health,
+// cluster-wide when there is a pool of agents
+func collectStacks(ctx context.Context, cli *client.Client, cfg config.Config, pool *agent.Pool, localNode string) stackSnapshot {
+	s := stackSnapshot{cli: cli, stats: make(map[string]docker.StackStats)}
+	stacks, err := docker.ListStacks(ctx, cli)
+	if err != nil {
+		s.err = err
+		return s
+	}
+	s.stacks = cfg.VisibleStacks(stacks)
+
+	if pool != nil {
+		discoverCtx, cancel := context.WithTimeout(ctx, 2*time.Second)
+		s.agents = pool.Clients(agent.Discover(discoverCtx, cfg.Agents, cfg.AgentService, cfg.AgentPort))
+		cancel()
+	}
+	if len(s.agents) > 0 {
+		s.collectCluster(ctx, cli, localNode)
+	} else if s.containers, err = docker.ListAllStackContainers(ctx, cli, false); err != nil {
+		// Without containers every stack would look stopped
+		s.err = err
+		return s
+	}
+
+	byStack := make(map[string][]types.Container)
+	for _, c := range s.containers {
+		stack := c.Labels["com.docker.stack.namespace"]
+		byStack[stack] = append(byStack[stack], c)
+	}
+	for _, stack := range s.stacks {
+		s.stats[stack] = docker.CountContainers(stack, byStack[stack])
+	}
+
+	s.health, _ = docker.ListStackHealth(ctx, cli, s.containers)
+	return s
+}
+
+// newAgentPool sets up the clients of the configured agents, or returns nil when
+// there are none
+func newAgentPool(cfg config.Config) (*agent.Pool, error) {
+	if !cfg.HasAgents() {
+		return nil, nil
+	}
+	var tlsConfig *tls.Config
+	if cfg.AgentTLS.Enabled() {
+		var err error
+		tlsConfig, err = certs.ClientTLSConfig(cfg.AgentTLS.CA, cfg.AgentTLS.Cert, cfg.AgentTLS.Key)
+		if err != nil {
+			return nil, fmt.Errorf("error setting up agent TLS: %v", err)
+		}
+	}
+	return agent.NewPool(cfg.AgentToken, tlsConfig), nil
+}
+
+// collectCluster merges the stack containers reported by every agent with the ones
+// visible to the local daemon, remembering which node each container runs on
+func (s *stackSnapshot) collectCluster(ctx context.Context, cli *client.Client, localNode string) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	nodeContainers, errs := agent.CollectContainers(ctx, s.agents)
+	s.agentsReachable = len(s.agents) - len(errs)
+
+	s.containerNodes = make(map[string]string)
+	s.containerAgents = make(map[string]*agent.Client)
+	for _, c := range nodeContainers {
+		s.containerNodes[c.ID] = c.Node
+		s.containerAgents[c.ID] = c.Agent
+		s.containers = append(s.containers, c.Container)
+	}
+
+	// The local node may not run an agent, so its containers are added directly
+	local, err := docker.ListAllStackContainers(ctx, cli, false)
+	if err == nil {
+		for _, c := range local {
+			if _, ok := s.containerNodes[c.ID]; !ok {
+				s.containerNodes[c.ID] = localNode
+				s.containers = append(s.containers, c)
+			}
+		}
+	}
+}
+
+// applySnapshot shows a collected snapshot, keeping the current selection. A failed
+// refresh leaves the last stacks on screen and says why until a refresh succeeds.
+func (m *Model) applySnapshot(s stackSnapshot) {
+	if s.err != nil {
+		m.refreshErr = fmt.Sprintf("Error refreshing stacks: %v", s.err)
+		m.logOutput = m.refreshErr
+		return
+	}
+	if m.refreshErr != "" {
+		if m.logOutput == m.refreshErr {
+			m.logOutput = ""
+		}
+		m.refreshErr = ""
+	}
+
+	m.allStacks = s.stacks
+	m.stackStats = s.stats
+	m.stackContainers = s.containers
+	m.stackHealth = s.health
+	m.agents = s.agents
+	m.agentsReachable = s.agentsReachable
+	m.containerNodes = s.containerNodes
+	m.containerAgents = s.containerAgents
+
+	m.activeServices, m.totalServices = 0, 0
+	for _, stats := range s.stats {
+		m.totalServices += stats.Total()
+		m.activeServices += stats.Running
+	}
+
+	m.sortStacks()
+	m.applyStackFilter()
+	m.closeMissingStack()
+}
@@ -0,0 +1,58 @@
+package ui
+
+import (
+	"errors"
+	"testing"
+
+	"pulse/internal/docker"
+)
+
+func TestApplySnapshotErrors(t *testing.T) {
+	good := stackSnapshot{stacks: []string{"shop"}, stats: map[string]docker.StackStats{"shop": {Running: 2}}}
+	failed := stackSnapshot{err: errors.New("daemon unreachable")}
+
+	tests := []struct {
+		name       string
+		snapshots  []stackSnapshot
+		wantStatus string
+		wantStacks int
+	}{
+		{name: "refreshed", snapshots: []stackSnapshot{good}, wantStatus: "", wantStacks: 1},
+		{
+			name:       "failure keeps the last stacks",
+			snapshots:  []stackSnapshot{good, failed},
+			wantStatus: "Error refreshing stacks: daemon unreachable",
+			wantStacks: 1,
+		},
+		{name: "recovery clears the error", snapshots: []stackSnapshot{good, failed, good}, wantStatus: "", wantStacks: 1},
+		{
+			name:       "failure on the first refresh",
+			snapshots:  []stackSnapshot{failed},
+			wantStatus: "Error refreshing stacks: daemon unreachable",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := Model{}
+			for _, s := range tt.snapshots {
+				m.applySnapshot(s)
+			}
+			if m.logOutput != tt.wantStatus {
+				t.Errorf("status = %q, want %q", m.logOutput, tt.wantStatus)
+			}
+			if len(m.allStacks) != tt.wantStacks {
+				t.Errorf("%d stacks shown, want %d", len(m.allStacks), tt.wantStacks)
+			}
+		})
+	}
+
+	// A message shown after the failure is not cleared by the recovery
+	m := Model{}
+	m.applySnapshot(failed)
+	m.logOutput = "Restarted shop"
+	m.applySnapshot(good)
+	if m.logOutput != "Restarted shop" {
+		t.Errorf("status = %q, want the newer message kept", m.logOutput)
+	}
+}
@@ -0,0 +1,291 @@
+package ui
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/docker/docker/api/types"
+)
+
+// maxSearchResults bounds the containers listed by the global search
+const maxSearchResults = 50
+
+// fuzzyScore reports whether the letters of pattern appear in order in text, ignoring
+// case, and scores the match: consecutive letters and letters starting a word score higher
+func fuzzyScore(pattern, text string) (int, bool) {
+	p := []rune(strings.ToLower(pattern))
+	t := []rune(strings.ToLower(text))
+
+	score, pi, prev := 0, 0, -2
+	for ti := 0; ti < len(t) && pi < len(p); ti++ {
+		if t[ti] != p[pi] {
+			continue
+		}
+		score++
+		if ti == prev+1 {
+			score += 2
+		}
+		if ti == 0 || strings.ContainsRune("-_./:= ", t[ti-1]) {
+			score += 3
+		}
+		prev = ti
+		pi++
+	}
+	return score, pi == len(p)
+}
+
+// fuzzyMatch scores a query against several fields. Every word of the query has to
+// match one of the fields; the best field counts for each word.
+func fuzzyMatch(query string, fields []string) (int, bool) {
+	total := 0
+	for _, word := range strings.Fields(query) {
+		best, found := 0, false
+		for _, field := range fields {
+			if score, ok := fuzzyScore(word, field); ok && (!found || score > best) {
+				best, found = score, true
+			}
+		}
+		if !found {
+			return 0, false
+		}
+		total += best
+	}
+	return total, true
+}
+
+// containerFields are the texts a container is searched by: its name, stack, service,
+// image without digest and its own labels. The com.docker labels are left out since
+// they repeat the stack and service and would match almost any query.
+func containerFields(c types.Container) []string {
+	image, _, _ := strings.Cut(c.Image, "@")
+	fields := []string{
+		strings.TrimPrefix(c.Names[0], "/"),
+		c.Labels["com.docker.stack.namespace"],
+		c.Labels["com.docker.swarm.service.name"],
+		image,
+	}
+	for k, v := range c.Labels {
+		if !strings.HasPrefix(k, "com.docker.") {
+			fields = append(fields, k+"="+v)
+		}
+	}
+	return fields
+}
+
+// stackFields are the texts a stack is filtered by: its name and the fields of its
+// services and containers
+func (m Model) stackFields(stack string) []string {
+	fields := []string{stack}
+	if health, ok := m.stackHealth[stack]; ok {
+		for _, service := range health.Services {
+			fields = append(fields, service.Service)
+		}
+	}
+	for _, c := range m.stackContainers {
+		if c.Labels["com.docker.stack.namespace"] == stack {
+			fields = append(fields, containerFields(c)...)
+		}
+	}
+	return fields
+}
+
+// applyStackFilter narrows the stack list to the stacks matching the filter, keeping
+// the selected stack when it still matches
+func (m *Model) applyStackFilter() {
+	selected := ""
+	if m.selectedStack < len(m.stacks) {
+		selected = m.stacks[m.selectedStack]
+	}
+
+	m.stacks = nil
+	for _, stack := range m.allStacks {
+		if _, ok := fuzzyMatch(m.stackFilter, m.stackFields(stack)); ok {
+			m.stacks = append(m.stacks, stack)
+		}
+	}
+
+	m.selectedStack = 0
+	for i, stack := range m.stacks {
+		if stack == selected {
+			m.selectedStack = i
+		}
+	}
+}
+
+// startStackFilter opens the filter box under the stack list
+func (m *Model) startStackFilter() {
+	m.input.Prompt = "/"
+	m.input.SetValue(m.stackFilter)
+	m.input.CursorEnd()
+	m.input.Focus()
+	m.state = "stackFilter"
+}
+
+// updateStackFilter narrows the stack list as the filter is typed. Enter keeps the
+// filter, Esc clears it, and the arrows move through the narrowed list.
+func (m Model) updateStackFilter(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.Type {
+	case tea.KeyEsc:
+		m.input.Blur()
+		m.state = "stack"
+		m.stackFilter = ""
+		m.applyStackFilter()
+		return m, nil
+	case tea.KeyEnter:
+		m.input.Blur()
+		m.state = "stack"
+		return m, nil
+	case tea.KeyUp:
+		if m.selectedStack > 0 {
+			m.selectedStack--
+		}
+		return m, nil
+	case tea.KeyDown:
+		if m.selectedStack < len(m.stacks)-1 {
+			m.selectedStack++
+		}
+		return m, nil
+	}
+
+	var cmd tea.Cmd
+	m.input, cmd = m.input.Update(msg)
+	m.stackFilter = strings.TrimSpace(m.input.Value())
+	m.applyStackFilter()
+	return m, cmd
+}
+
+// searchResult is a container found by the global search
+type searchResult struct {
+	container types.Container
+	score     int
+}
+
+// startSearch opens the global container search
+func (m *Model) startSearch() {
+	m.searchReturn = m.state
+	m.selectedResult = 0
+	m.input.Prompt = "Find container: "
+	m.input.SetValue("")
+	m.input.Focus()
+	m.state = "search"
+}
+
+// searchResults ranks the containers of every stack against the search query
+func (m Model) searchResults() []searchResult {
+	query := strings.TrimSpace(m.input.Value())
+	var results []searchResult
+	for _, c := range m.stackContainers {
+		if !slices.Contains(m.allStacks, c.Labels["com.docker.stack.namespace"]) {
+			continue
+		}
+		if score, ok := fuzzyMatch(query, containerFields(c)); ok {
+			results = append(results, searchResult{container: c, score: score})
+		}
+	}
+
+	sort.SliceStable(results, func(i, j int) bool {
+		if results[i].score != results[j].score {
+			return results[i].score > results[j].score
+		}
+		return results[i].container.Names[0] < results[j].container.Names[0]
+	})
+	if len(results) > maxSearchResults {
+		results = results[:maxSearchResults]
+	}
+	return results
+}
+
+// updateSearch refines the search as the query is typed; Enter jumps to the selected
+// container and Esc goes back to where the search was opened
+func (m Model) updateSearch(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.Type {
+	case tea.KeyEsc:
+		m.input.Blur()
+		m.state = m.searchReturn
+		return m, nil
+	case tea.KeyEnter:
+		m.input.Blur()
+		m.state = m.searchReturn
+		if results := m.searchResults(); m.selectedResult < len(results) {
+			m.jumpToContainer(results[m.selectedResult].container)
+		}
+		return m, nil
+	case tea.KeyUp:
+		if m.selectedResult > 0 {
+			m.selectedResult--
+		}
+		return m, nil
+	case tea.KeyDown:
+		if m.selectedResult < len(m.searchResults())-1 {
+			m.selectedResult++
+		}
+		return m, nil
+	}
+
+	var cmd tea.Cmd
+	m.input, cmd = m.input.Update(msg)
+	m.selectedResult = 0
+	return m, cmd
+}
+
+// jumpToContainer opens the container list of a container's stack with it selected.
+// A stack filter hiding the stack is cleared.
+func (m *Model) jumpToContainer(c types.Container) {
+	stack := c.Labels["com.docker.stack.namespace"]
+	index := slices.Index(m.stacks, stack)
+	if index < 0 && m.stackFilter != "" {
+		m.stackFilter = ""
+		m.applyStackFilter()
+		index = slices.Index(m.stacks, stack)
+	}
+	if index < 0 {
+		m.logOutput = fmt.Sprintf("Stack %s is no longer running", stack)
+		return
+	}
+
+	m.selectedStack = index
+	m.openStack("containerList")
+	m.loadStackContainers()
+	for i, container := range m.containers {
+		if container.ID == c.ID {
+			m.selectedContainer = i
+		}
+	}
+}
+
+// renderSearch renders the global search box and its results
+func (m Model) renderSearch(header string) string {
+	results := m.searchResults()
+	height := max(m.viewportHeight-14, 5)
+	start := max(0, min(m.selectedResult-height/2, len(results)-height))
+	end := min(len(results), start+height)
+
+	resultList := ""
+	if len(results) == 0 {
+		resultList = m.theme.Unselected.Render("No matching containers") + "\n"
+	}
+	for i := start; i < end; i++ {
+		c := results[i].container
+		image, _, _ := strings.Cut(c.Image, "@")
+		line := fmt.Sprintf("%-40s %-20s %-10s %s",
+			truncate(strings.TrimPrefix(c.Names[0], "/"), 40),
+			truncate(c.Labels["com.docker.stack.namespace"], 20),
+			c.State, truncate(image, 40))
+		if i == m.selectedResult {
+			resultList += m.theme.Selected.Render("❯ "+line) + "\n"
+		} else {
+			resultList += m.theme.Unselected.Render("  "+line) + "\n"
+		}
+	}
+
+	panel := m.theme.Container.Render(
+		m.theme.Title.Render("Search containers in every stack") + "\n" +
+			"  " + m.input.View() + "\n\n" +
+			resultList + "\n" +
+			m.theme.Instruction.Render("Matches name, stack, service, image and labels • ↑/↓: Navigate • Enter: Jump to container • Esc: Cancel"))
+	return lipgloss.JoinVertical(lipgloss.Left, header, panel)
+}
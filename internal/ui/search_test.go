@@ -0,0 +1,79 @@
+package ui
+
+import "testing"
+
+func TestFuzzyScore(t *testing.T) {
+	tests := []struct {
+		name          string
+		pattern, text string
+		want          int
+		wantOK        bool
+	}{
+		{name: "empty pattern", pattern: "", text: "shop_web", want: 0, wantOK: true},
+		{name: "word start and consecutive letters", pattern: "web", text: "shop_web", want: 10, wantOK: true},
+		{name: "ignores case", pattern: "WEB", text: "shop_web", want: 10, wantOK: true},
+		{name: "initials", pattern: "sw", text: "shop_web", want: 8, wantOK: true},
+		{name: "consecutive", pattern: "ab", text: "abc", want: 7, wantOK: true},
+		{name: "gap", pattern: "ac", text: "abc", want: 5, wantOK: true},
+		{name: "inside a word", pattern: "eb", text: "shop_web", want: 4, wantOK: true},
+		{name: "letters out of order", pattern: "bw", text: "shop_web", wantOK: false},
+		{name: "missing letter", pattern: "webx", text: "shop_web", wantOK: false},
+		{name: "empty text", pattern: "w", text: "", wantOK: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := fuzzyScore(tt.pattern, tt.text)
+			if ok != tt.wantOK || (ok && got != tt.want) {
+				t.Errorf("fuzzyScore(%q, %q) = %d, %v, want %d, %v", tt.pattern, tt.text, got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestFuzzyScoreRanking(t *testing.T) {
+	// The first text of each pair should score higher for the pattern
+	tests := []struct {
+		pattern       string
+		better, worse string
+	}{
+		{pattern: "web", better: "shop_web", worse: "awebx"},
+		{pattern: "api", better: "shop_api", worse: "rapid"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.pattern+" "+tt.better, func(t *testing.T) {
+			better, ok1 := fuzzyScore(tt.pattern, tt.better)
+			worse, ok2 := fuzzyScore(tt.pattern, tt.worse)
+			if !ok1 || !ok2 || better <= worse {
+				t.Errorf("fuzzyScore(%q): %q = %d, %q = %d, want the first higher", tt.pattern, tt.better, better, tt.worse, worse)
+			}
+		})
+	}
+}
+
+func TestFuzzyMatch(t *testing.T) {
+	tests := []struct {
+		name   string
+		query  string
+		fields []string
+		want   int
+		wantOK bool
+	}{
+		{name: "empty query matches anything", query: "", fields: []string{"shop_web"}, want: 0, wantOK: true},
+		{name: "no fields", query: "web", fields: nil, wantOK: false},
+		{name: "best field counts", query: "web", fields: []string{"awebx", "shop_web"}, want: 10, wantOK: true},
+		{name: "words add up", query: "shop web", fields: []string{"shop_web", "nginx"}, want: 23, wantOK: true},
+		{name: "words may match different fields", query: "nginx web", fields: []string{"shop_web", "nginx:1.27"}, want: 26, wantOK: true},
+		{name: "every word has to match", query: "web redis", fields: []string{"shop_web", "nginx"}, wantOK: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := fuzzyMatch(tt.query, tt.fields)
+			if ok != tt.wantOK || (ok && got != tt.want) {
+				t.Errorf("fuzzyMatch(%q, %q) = %d, %v, want %d, %v", tt.query, tt.fields, got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
@@ -0,0 +1,134 @@
+package ui
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"time"
+
+	"pulse/internal/docker"
+)
+
+// stackSortKeys are the keys the stack list can be sorted by, in the order 'o' cycles
+// through them
+var stackSortKeys = []string{"name", "running", "cpu", "memory", "health", "age"}
+
+// stackSortDescending is the direction each key starts in when cycled to: the busiest,
+// least healthy and oldest stacks come first
+var stackSortDescending = map[string]bool{
+	"running": true,
+	"cpu":     true,
+	"memory":  true,
+	"health":  true,
+	"age":     true,
+}
+
+// healthRank orders health states from healthy to unhealthy; stacks without a known
+// health rank lowest
+var healthRank = map[docker.HealthState]int{
+	docker.HealthHealthy:    1,
+	docker.HealthConverging: 2,
+	docker.HealthDegraded:   3,
+	docker.HealthUnhealthy:  4,
+}
+
+// stackUsage sums the latest CPU and memory samples of a stack's containers. Samples
+// older than sampleHold belong to containers that have stopped.
+func (m Model) stackUsage(stack string) (cpu, memory float64) {
+	now := time.Now()
+	for _, samples := range m.samples {
+		if len(samples) == 0 || samples[0].Stack != stack {
+			continue
+		}
+		latest := samples[len(samples)-1]
+		if now.Sub(latest.Time) <= m.sampleHold() {
+			cpu += latest.CPUPercent
+			memory += float64(latest.MemoryUsage)
+		}
+	}
+	return cpu, memory
+}
+
+// stackAge is how long the oldest container of a stack has existed
+func (m Model) stackAge(stack string) time.Duration {
+	var oldest int64
+	for _, c := range m.stackContainers {
+		if c.Labels["com.docker.stack.namespace"] == stack && (oldest == 0 || c.Created < oldest) {
+			oldest = c.Created
+		}
+	}
+	if oldest == 0 {
+		return 0
+	}
+	return time.Since(time.Unix(oldest, 0))
+}
+
+// stackSortValue is the value a stack is sorted by for keys other than name
+func (m Model) stackSortValue(stack string) float64 {
+	switch m.stackSort.Key {
+	case "running":
+		return float64(m.stackStats[stack].Running)
+	case "cpu":
+		cpu, _ := m.stackUsage(stack)
+		return cpu
+	case "memory":
+		_, memory := m.stackUsage(stack)
+		return memory
+	case "health":
+		return float64(healthRank[m.stackHealth[stack].State])
+	case "age":
+		return m.stackAge(stack).Seconds()
+	}
+	return 0
+}
+
+// sortStacks orders every visible stack by the sort key. Stacks with equal values
+// are ordered by name, so the list does not shuffle between refreshes.
+func (m *Model) sortStacks() {
+	values := make(map[string]float64, len(m.allStacks))
+	for _, stack := range m.allStacks {
+		values[stack] = m.stackSortValue(stack)
+	}
+
+	sort.SliceStable(m.allStacks, func(i, j int) bool {
+		a, b := m.allStacks[i], m.allStacks[j]
+		if m.stackSort.Key == "name" {
+			if m.stackSort.Descending {
+				return a > b
+			}
+			return a < b
+		}
+		if values[a] != values[b] {
+			if m.stackSort.Descending {
+				return values[a] > values[b]
+			}
+			return values[a] < values[b]
+		}
+		return a < b
+	})
+}
+
+// cycleStackSort sorts the stack list by the next sort key
+func (m *Model) cycleStackSort() {
+	next := (slices.Index(stackSortKeys, m.stackSort.Key) + 1) % len(stackSortKeys)
+	m.stackSort.Key = stackSortKeys[next]
+	m.stackSort.Descending = stackSortDescending[m.stackSort.Key]
+	m.sortStacks()
+	m.applyStackFilter()
+}
+
+// reverseStackSort flips the direction of the stack list
+func (m *Model) reverseStackSort() {
+	m.stackSort.Descending = !m.stackSort.Descending
+	m.sortStacks()
+	m.applyStackFilter()
+}
+
+// stackSortLabel describes the order of the stack list, e.g. "cpu ↓"
+func (m Model) stackSortLabel() string {
+	arrow := "↑"
+	if m.stackSort.Descending {
+		arrow = "↓"
+	}
+	return fmt.Sprintf("%s %s", m.stackSort.Key, arrow)
+}
@@ -1,40 +1,243 @@
 package ui
 
 import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
 	"github.com/charmbracelet/lipgloss"
+
+	"pulse/internal/config"
+	"pulse/internal/docker"
 )
 
-var (
-	// Vibrant color palette
-	colorPrimary    = lipgloss.Color("#FF5F87") // Vibrant pink
-	colorSecondary  = lipgloss.Color("#5FAFFF") // Bright blue
-	colorAccent     = lipgloss.Color("#FFAF00") // Bold orange
-	colorSuccess    = lipgloss.Color("#50FA7B") // Neon green
-	colorDanger     = lipgloss.Color("#FF5555") // Bright red
-	colorWarning    = lipgloss.Color("#F1FA8C") // Vibrant yellow
-	colorBackground = lipgloss.Color("#282A36") // Dark background
-	colorText       = lipgloss.Color("#F8F8F2") // Light text
-	colorSubtext    = lipgloss.Color("#BFBFBF") // Grey text
-	colorHighlight  = lipgloss.Color("#BD93F9") // Purple highlight
-
-	// Updated styles with more vibrant colors
-	titleStyle       = lipgloss.NewStyle().Foreground(colorHighlight).Bold(true).Padding(1, 2)
-	selectedStyle    = lipgloss.NewStyle().Foreground(colorPrimary).Bold(true).PaddingLeft(2)
-	unselectedStyle  = lipgloss.NewStyle().Foreground(colorText).PaddingLeft(2)
-	logStyle         = lipgloss.NewStyle().Padding(1, 2).Background(colorBackground).Foreground(colorText)
-	instructionStyle = lipgloss.NewStyle().Foreground(colorSubtext).Padding(1, 2)
-	debugStyle       = lipgloss.NewStyle().Foreground(colorDanger)
-
-	// Redesigned UI components with vibrant borders and backgrounds
-	headerStyle     = lipgloss.NewStyle().Foreground(colorText).Background(colorPrimary).Bold(true).Padding(0, 1).Width(100)
-	stackPanelStyle = lipgloss.NewStyle().Border(lipgloss.RoundedBorder()).BorderForeground(colorSecondary).Padding(1, 2).Background(colorBackground)
-	containerStyle  = lipgloss.NewStyle().Border(lipgloss.RoundedBorder()).BorderForeground(colorSuccess).Padding(1, 2).Background(colorBackground)
-	logPanelStyle   = lipgloss.NewStyle().Border(lipgloss.RoundedBorder()).BorderForeground(colorAccent).Padding(1, 2).Background(colorBackground)
-	helpPanelStyle  = lipgloss.NewStyle().Border(lipgloss.RoundedBorder()).BorderForeground(colorHighlight).Padding(1, 2).Background(colorBackground)
-	actionMenuStyle = lipgloss.NewStyle().Padding(1, 2).Border(lipgloss.RoundedBorder()).BorderForeground(colorPrimary).Background(colorBackground).Foreground(colorText)
+// builtinThemes are the palettes selectable with the theme setting
+var builtinThemes = map[string]config.Palette{
+	// Vibrant colours on a dark background
+	"dark": {
+		Primary:    "#FF5F87", // Vibrant pink
+		Secondary:  "#5FAFFF", // Bright blue
+		Accent:     "#FFAF00", // Bold orange
+		Success:    "#50FA7B", // Neon green
+		Danger:     "#FF5555", // Bright red
+		Warning:    "#F1FA8C", // Vibrant yellow
+		Background: "#282A36", // Dark background
+		Text:       "#F8F8F2", // Light text
+		Subtext:    "#BFBFBF", // Grey text
+		Highlight:  "#BD93F9", // Purple highlight
+	},
+	// Darker shades that stay readable on a light terminal
+	"light": {
+		Primary:    "#C2185B",
+		Secondary:  "#0969DA",
+		Accent:     "#BC4C00",
+		Success:    "#1A7F37",
+		Danger:     "#CF222E",
+		Warning:    "#9A6700",
+		Background: "#FAFAFA",
+		Text:       "#1F2328",
+		Subtext:    "#57606A",
+		Highlight:  "#8250DF",
+	},
+	// Saturated colours on black for low vision and washed-out screens
+	"high-contrast": {
+		Primary:    "#FFFF00",
+		Secondary:  "#00FFFF",
+		Accent:     "#FF8000",
+		Success:    "#00FF00",
+		Danger:     "#FF0000",
+		Warning:    "#FFFF00",
+		Background: "#000000",
+		Text:       "#FFFFFF",
+		Subtext:    "#E0E0E0",
+		Highlight:  "#FFFFFF",
+	},
+	// No colours at all; emphasis comes from bold and reverse video
+	"monochrome": {},
+}
+
+// Theme holds every style of the TUI. The model keeps one, built from a palette, and
+// View sizes a copy of it to the terminal on each render.
+type Theme struct {
+	Name string
+
+	Title       lipgloss.Style
+	Selected    lipgloss.Style
+	Unselected  lipgloss.Style
+	Log         lipgloss.Style
+	Instruction lipgloss.Style
+	Debug       lipgloss.Style
+
+	// Panels
+	Header     lipgloss.Style
+	StackPanel lipgloss.Style
+	Container  lipgloss.Style
+	LogPanel   lipgloss.Style
+	HelpPanel  lipgloss.Style
+	AlertPanel lipgloss.Style
+	ActionMenu lipgloss.Style
 
 	// Status indicators
-	statusRunning = lipgloss.NewStyle().Foreground(colorSuccess).Bold(true)
-	statusStopped = lipgloss.NewStyle().Foreground(colorDanger).Bold(true)
-	statusOther   = lipgloss.NewStyle().Foreground(colorWarning).Bold(true)
-)
+	Running lipgloss.Style
+	Stopped lipgloss.Style
+	Other   lipgloss.Style
+
+	// Health badges, the empty part of usage bars and log lines matched by a trigger
+	Health   map[docker.HealthState]lipgloss.Style
+	BarEmpty lipgloss.Style
+	HitLine  lipgloss.Style
+}
+
+// loadTheme builds the named theme from the built-in and configured palettes. NO_COLOR
+// forces the monochrome theme. Unknown names fall back to the default theme with a
+// message for the user.
+func loadTheme(name string, custom map[string]config.Palette) (Theme, string) {
+	if os.Getenv("NO_COLOR") != "" {
+		return newTheme("monochrome", builtinThemes["monochrome"]), ""
+	}
+
+	palette, ok := custom[name]
+	if ok {
+		base, found := builtinThemes[palette.Base]
+		if palette.Base == "" {
+			base, found = builtinThemes[config.DefaultTheme], true
+		}
+		if !found {
+			return newTheme(config.DefaultTheme, builtinThemes[config.DefaultTheme]),
+				fmt.Sprintf("Theme %s is based on unknown theme %q; using %s", name, palette.Base, config.DefaultTheme)
+		}
+		return newTheme(name, overlay(base, palette)), ""
+	}
+
+	palette, ok = builtinThemes[name]
+	if !ok {
+		return newTheme(config.DefaultTheme, builtinThemes[config.DefaultTheme]),
+			fmt.Sprintf("Unknown theme %q; available: %s", name, strings.Join(themeNames(custom), ", "))
+	}
+	return newTheme(name, palette), ""
+}
+
+// themeNames lists the built-in and configured themes
+func themeNames(custom map[string]config.Palette) []string {
+	var names []string
+	for name := range builtinThemes {
+		names = append(names, name)
+	}
+	for name := range custom {
+		if _, ok := builtinThemes[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// overlay replaces the colours of base that p sets
+func overlay(base, p config.Palette) config.Palette {
+	pick := func(custom, fallback string) string {
+		if custom != "" {
+			return custom
+		}
+		return fallback
+	}
+	return config.Palette{
+		Primary:    pick(p.Primary, base.Primary),
+		Secondary:  pick(p.Secondary, base.Secondary),
+		Accent:     pick(p.Accent, base.Accent),
+		Success:    pick(p.Success, base.Success),
+		Danger:     pick(p.Danger, base.Danger),
+		Warning:    pick(p.Warning, base.Warning),
+		Background: pick(p.Background, base.Background),
+		Text:       pick(p.Text, base.Text),
+		Subtext:    pick(p.Subtext, base.Subtext),
+		Highlight:  pick(p.Highlight, base.Highlight),
+	}
+}
+
+// color turns a palette entry into a lipgloss colour; empty means the terminal's own
+func color(c string) lipgloss.TerminalColor {
+	if c == "" {
+		return lipgloss.NoColor{}
+	}
+	return lipgloss.Color(c)
+}
+
+// newTheme builds the styles of a palette. Without a background colour the header,
+// badges and matched log lines fall back to reverse video so they still stand out.
+func newTheme(name string, p config.Palette) Theme {
+	primary, secondary, accent := color(p.Primary), color(p.Secondary), color(p.Accent)
+	success, danger, warning := color(p.Success), color(p.Danger), color(p.Warning)
+	background, text, subtext, highlight := color(p.Background), color(p.Text), color(p.Subtext), color(p.Highlight)
+	reverse := p.Background == ""
+
+	border := func(c lipgloss.TerminalColor) lipgloss.Style {
+		return lipgloss.NewStyle().Border(lipgloss.RoundedBorder()).BorderForeground(c).Padding(1, 2).Background(background)
+	}
+	badge := func(fg, bg lipgloss.TerminalColor) lipgloss.Style {
+		return lipgloss.NewStyle().Foreground(fg).Background(bg).Bold(true).Padding(0, 1).Reverse(reverse)
+	}
+
+	return Theme{
+		Name:        name,
+		Title:       lipgloss.NewStyle().Foreground(highlight).Bold(true).Padding(1, 2),
+		Selected:    lipgloss.NewStyle().Foreground(primary).Bold(true).PaddingLeft(2),
+		Unselected:  lipgloss.NewStyle().Foreground(text).PaddingLeft(2),
+		Log:         lipgloss.NewStyle().Padding(1, 2).Background(background).Foreground(text),
+		Instruction: lipgloss.NewStyle().Foreground(subtext).Padding(1, 2).Faint(reverse),
+		Debug:       lipgloss.NewStyle().Foreground(danger),
+
+		Header:     lipgloss.NewStyle().Foreground(text).Background(primary).Bold(true).Padding(0, 1).Width(100).Reverse(reverse),
+		StackPanel: border(secondary),
+		Container:  border(success),
+		LogPanel:   border(accent),
+		HelpPanel:  border(highlight),
+		AlertPanel: border(danger),
+		ActionMenu: border(primary).Foreground(text),
+
+		Running: lipgloss.NewStyle().Foreground(success).Bold(true),
+		Stopped: lipgloss.NewStyle().Foreground(danger).Bold(true),
+		Other:   lipgloss.NewStyle().Foreground(warning).Bold(true),
+
+		Health: map[docker.HealthState]lipgloss.Style{
+			docker.HealthHealthy:    badge(background, success),
+			docker.HealthConverging: badge(background, secondary),
+			docker.HealthDegraded:   badge(background, warning),
+			docker.HealthUnhealthy:  badge(text, danger),
+		},
+		BarEmpty: lipgloss.NewStyle().Foreground(subtext),
+		HitLine:  lipgloss.NewStyle().Foreground(background).Background(warning).Reverse(reverse),
+	}
+}
+
+// sized returns the theme with its panels fitted to the terminal width
+func (t Theme) sized(width int) Theme {
+	stackWidth := width * 2 / 3
+	helpWidth := width - stackWidth - 4 // Account for borders and padding
+
+	// Ensure minimal widths for readability
+	if stackWidth < 40 {
+		stackWidth = 40
+	}
+	if helpWidth < 30 {
+		helpWidth = 30
+	}
+
+	// Make panels fill available space
+	t.StackPanel = t.StackPanel.Width(stackWidth)
+	t.HelpPanel = t.HelpPanel.Width(helpWidth)
+	t.AlertPanel = t.AlertPanel.Width(helpWidth)
+	t.LogPanel = t.LogPanel.Width(width - 4) // Account for borders and padding
+	t.Container = t.Container.Width(width - 4)
+	t.Header = t.Header.Width(width)
+	return t
+}
+
+// healthBadge renders a health state as a coloured badge
+func (t Theme) healthBadge(state docker.HealthState) string {
+	style, ok := t.Health[state]
+	if !ok {
+		return t.Other.Render("unknown")
+	}
+	return style.Render(string(state))
+}
@@ -0,0 +1,257 @@
+package ui
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+
+	"pulse/internal/docker"
+)
+
+// maxEvents bounds the events kept for the timeline
+const maxEvents = 2000
+
+// eventRetryInterval is how long to wait before reconnecting a failed event stream
+const eventRetryInterval = 5 * time.Second
+
+// eventMsg delivers an event for the timeline
+type eventMsg docker.Event
+
+// eventFilter narrows the timeline down; empty fields match everything
+type eventFilter struct {
+	stack   string
+	service string
+	typ     docker.EventType
+}
+
+// matches reports whether an event passes the filter. Services may be given with or
+// without their stack prefix.
+func (f eventFilter) matches(event docker.Event) bool {
+	if f.stack != "" && event.Stack != f.stack {
+		return false
+	}
+	if f.service != "" && event.Service != f.service && event.Service != event.Stack+"_"+f.service {
+		return false
+	}
+	return f.typ == "" || event.Type == f.typ
+}
+
+// String formats the filter the way the filter prompt accepts it
+func (f eventFilter) String() string {
+	var parts []string
+	if f.stack != "" {
+		parts = append(parts, "stack="+f.stack)
+	}
+	if f.service != "" {
+		parts = append(parts, "service="+f.service)
+	}
+	if f.typ != "" {
+		parts = append(parts, "type="+string(f.typ))
+	}
+	return strings.Join(parts, " ")
+}
+
+// parseEventFilter reads a filter from space-separated key=value pairs
+func parseEventFilter(value string) (eventFilter, error) {
+	var f eventFilter
+	for _, field := range strings.Fields(value) {
+		key, val, ok := strings.Cut(field, "=")
+		if !ok {
+			return eventFilter{}, fmt.Errorf("expected key=value, got %q", field)
+		}
+		switch key {
+		case "stack":
+			f.stack = val
+		case "service":
+			f.service = val
+		case "type":
+			if !slices.Contains(docker.EventTypes, docker.EventType(val)) {
+				return eventFilter{}, fmt.Errorf("unknown event type %q", val)
+			}
+			f.typ = docker.EventType(val)
+		default:
+			return eventFilter{}, fmt.Errorf("unknown filter %q, use stack, service or type", key)
+		}
+	}
+	return f, nil
+}
+
+// startEventWatcher records events in the background, reconnecting when the stream fails
+func (m *Model) startEventWatcher() {
+	ctx, cancel := context.WithCancel(context.Background())
+	m.stopEvents = cancel
+
+	cli, events := m.cli, m.eventCh
+	var since time.Time
+	if m.cfg.EventsSince > 0 {
+		since = time.Now().Add(-m.cfg.EventsSince)
+	}
+
+	go func() {
+		for {
+			started := time.Now()
+			last, _ := docker.WatchEvents(ctx, cli, since, events)
+			// Watch again from the newest event seen, so nothing that happens while
+			// reconnecting is lost; addEvent drops the events seen twice
+			if !last.IsZero() {
+				since = last
+			} else if since.IsZero() {
+				since = started
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(eventRetryInterval):
+			}
+		}
+	}()
+}
+
+// waitForEvent delivers the next event as a message
+func (m Model) waitForEvent() tea.Cmd {
+	if m.eventCh == nil {
+		return nil
+	}
+	events := m.eventCh
+	return func() tea.Msg {
+		return eventMsg(<-events)
+	}
+}
+
+// addEvent inserts an event in chronological order; backfilled events can arrive late
+func (m Model) addEvent(event docker.Event) (tea.Model, tea.Cmd) {
+	if m.cfg.IsHidden(event.Stack) {
+		return m, m.waitForEvent()
+	}
+
+	// Keep following the newest event when it is selected
+	visible := m.filteredEvents()
+	following := len(visible) == 0 || m.selectedEvent >= len(visible)-1
+
+	i := sort.Search(len(m.events), func(i int) bool {
+		return m.events[i].Time.After(event.Time)
+	})
+	// Backfilled events may already have been loaded from the history, and events are
+	// sent again after reconnecting
+	for j := i - 1; j >= 0 && m.events[j].Time.Equal(event.Time); j-- {
+		if sameEvent(m.events[j], event) {
+			return m, m.waitForEvent()
+		}
+	}
+	m.recordEvent(event)
+	m.events = slices.Insert(m.events, i, event)
+	if len(m.events) > maxEvents {
+		m.events = m.events[len(m.events)-maxEvents:]
+	}
+
+	if following {
+		m.selectedEvent = max(len(m.filteredEvents())-1, 0)
+	}
+	return m, m.waitForEvent()
+}
+
+// sameEvent reports whether two events describe the same occurrence
+func sameEvent(a, b docker.Event) bool {
+	return a.Time.Equal(b.Time) && a.Type == b.Type && a.Action == b.Action &&
+		a.Subject == b.Subject && a.Message == b.Message
+}
+
+// filteredEvents returns the events that pass the timeline filter, oldest first
+func (m Model) filteredEvents() []docker.Event {
+	var events []docker.Event
+	for _, event := range m.events {
+		if m.eventFilter.matches(event) {
+			events = append(events, event)
+		}
+	}
+	return events
+}
+
+// cycleEventType switches the type filter to the next event type, then back to all
+func (m *Model) cycleEventType() {
+	i := slices.Index(docker.EventTypes, m.eventFilter.typ)
+	if i == len(docker.EventTypes)-1 {
+		m.eventFilter.typ = ""
+	} else {
+		m.eventFilter.typ = docker.EventTypes[i+1]
+	}
+	m.selectedEvent = max(len(m.filteredEvents())-1, 0)
+}
+
+// setEventFilter applies a filter typed into the prompt
+func (m *Model) setEventFilter(value string) {
+	f, err := parseEventFilter(value)
+	if err != nil {
+		m.logOutput = fmt.Sprintf("Invalid filter: %v", err)
+		return
+	}
+	m.eventFilter = f
+	m.selectedEvent = max(len(m.filteredEvents())-1, 0)
+}
+
+// eventLevelStyle colours events by how worrying they are
+func (t Theme) eventLevelStyle(level docker.EventLevel) lipgloss.Style {
+	switch level {
+	case docker.EventOK:
+		return t.Running
+	case docker.EventWarning:
+		return t.Other
+	case docker.EventError:
+		return t.Stopped
+	default:
+		return t.Unselected.PaddingLeft(0)
+	}
+}
+
+// renderTimeline renders the window of filtered events around the selection
+func (m Model) renderTimeline(header string) string {
+	events := m.filteredEvents()
+
+	rows := max(m.viewportHeight-14, 5)
+	start := max(0, min(m.selectedEvent-rows/2, len(events)-rows))
+	end := min(len(events), start+rows)
+
+	eventList := ""
+	if len(events) == 0 {
+		eventList = m.theme.Unselected.Render("No events recorded yet") + "\n"
+	}
+	lastDay := ""
+	if start > 0 {
+		lastDay = events[start-1].Time.Local().Format("Mon 2 Jan")
+	}
+	for i := start; i < end; i++ {
+		event := events[i]
+		local := event.Time.Local()
+		if day := local.Format("Mon 2 Jan"); day != lastDay {
+			eventList += m.theme.Title.Padding(0, 2).Render(day) + "\n"
+			lastDay = day
+		}
+
+		marker := m.theme.eventLevelStyle(event.Level).Render("●")
+		line := fmt.Sprintf("%s %s %-9s %-14s %-30s %s", local.Format("15:04:05"), marker,
+			event.Type, truncate(event.Action, 14), truncate(event.Subject, 30), event.Message)
+		if i == m.selectedEvent {
+			eventList += m.theme.Selected.Render("❯ "+line) + "\n"
+		} else {
+			eventList += m.theme.Unselected.Render("  "+line) + "\n"
+		}
+	}
+
+	title := fmt.Sprintf("Event Timeline (%d of %d)", len(events), len(m.events))
+	if filter := m.eventFilter.String(); filter != "" {
+		title += " • " + filter
+	}
+	panel := m.theme.StackPanel.Width(m.viewportWidth - 4).Render(
+		m.theme.Title.Render(title) + "\n" +
+			eventList + "\n" +
+			m.theme.Instruction.Render(m.keys.viewHelp("timeline", "Back", m.keys.joinHint("Scroll", "up", "down", "page_up", "page_down"))))
+
+	return lipgloss.JoinVertical(lipgloss.Left, header, panel)
+}
@@ -2,36 +2,30 @@ package ui
 
 import (
 	"fmt"
+	"sort"
 	"strings"
+	"time"
 
+	"github.com/charmbracelet/bubbles/key"
 	"github.com/charmbracelet/lipgloss"
+	"github.com/docker/go-units"
+
+	"pulse/internal/docker"
 )
 
 // View renders the UI based on current state
 func (m Model) View() string {
-	// Set dynamic widths based on viewport
-	stackWidth := m.viewportWidth * 2 / 3
-	helpWidth := m.viewportWidth - stackWidth - 4 // Account for borders and padding
-
-	// Ensure minimal widths for readability
-	if stackWidth < 40 {
-		stackWidth = 40
-	}
-	if helpWidth < 30 {
-		helpWidth = 30
-	}
-
-	// Make panels fill available space
-	stackPanelStyle = stackPanelStyle.Width(stackWidth)
-	helpPanelStyle = helpPanelStyle.Width(helpWidth)
-	logPanelStyle = logPanelStyle.Width(m.viewportWidth - 4) // Account for borders and padding
-	containerStyle = containerStyle.Width(m.viewportWidth - 4)
-	headerStyle = headerStyle.Width(m.viewportWidth)
+	// Fit the panels to the terminal; the sized theme is a copy local to this render
+	m.theme = m.theme.sized(m.viewportWidth)
 
 	// Application header - now full width
-	header := headerStyle.Render(fmt.Sprintf("DOCKER STACK MANAGER | Active: %d/%d services", m.activeServices, m.totalServices))
+	headerText := fmt.Sprintf("DOCKER STACK MANAGER | Host: %s | Active: %d/%d services", m.contextName, m.activeServices, m.totalServices)
+	if len(m.agents) > 0 {
+		headerText += fmt.Sprintf(" | Agents: %d/%d", m.agentsReachable, len(m.agents))
+	}
+	header := m.theme.Header.Render(headerText)
 
-	if m.state == "stack" {
+	if m.state == "stack" || m.state == "stackFilter" {
 		return m.renderStackView(header)
 	} else if m.state == "actionMenu" {
 		return m.renderActionMenu(header)
@@ -39,6 +33,42 @@ func (m Model) View() string {
 		return m.renderContainerList(header)
 	} else if m.state == "containerLogs" {
 		return m.renderContainerLogs(header)
+	} else if m.state == "secrets" {
+		return m.renderSecrets(header)
+	} else if m.state == "resourceContent" {
+		return m.renderResourceContent(header)
+	} else if m.state == "images" {
+		return m.renderImages(header)
+	} else if m.state == "imageHistory" {
+		return m.renderImageHistory(header)
+	} else if m.state == "diskUsage" {
+		return m.renderDiskUsage(header)
+	} else if m.state == "changes" {
+		return m.renderChanges(header)
+	} else if m.state == "files" {
+		return m.renderFiles(header)
+	} else if m.state == "fileContent" {
+		return m.renderFileContent(header)
+	} else if m.state == "processes" {
+		return m.renderProcesses(header)
+	} else if m.state == "signal" {
+		return m.renderSignalMenu(header)
+	} else if m.state == "graph" {
+		return m.renderGraph(header)
+	} else if m.state == "timeline" {
+		return m.renderTimeline(header)
+	} else if m.state == "health" {
+		return m.renderStackHealth(header)
+	} else if m.state == "logHits" {
+		return m.renderLogHits(header)
+	} else if m.state == "hitLogs" {
+		return m.renderHitLogs(header)
+	} else if m.state == "contexts" {
+		return m.renderContexts(header)
+	} else if m.state == "search" {
+		return m.renderSearch(header)
+	} else if m.state == "input" {
+		return m.renderInput(header)
 	}
 
 	return "Unknown state"
@@ -51,31 +81,59 @@ func (m Model) renderStackView(header string) string {
 	for i, stack := range m.stacks {
 		stats := m.stackStats[stack]
 		statusInfo := fmt.Sprintf("[%s %d • %s %d • %s %d]",
-			statusRunning.Render("●"), stats.Running,
-			statusStopped.Render("●"), stats.Stopped,
-			statusOther.Render("●"), stats.Other)
+			m.theme.Running.Render("●"), stats.Running,
+			m.theme.Stopped.Render("●"), stats.Stopped,
+			m.theme.Other.Render("●"), stats.Other)
+		if health, ok := m.stackHealth[stack]; ok {
+			statusInfo += " " + m.theme.healthBadge(health.State)
+		}
+		statusInfo += " " + m.theme.Running.Render(m.stackSpark(stack))
 
 		if i == m.selectedStack {
-			stackList += selectedStyle.Render(fmt.Sprintf("❯ %s %s\n", stack, statusInfo))
+			stackList += m.theme.Selected.Render(fmt.Sprintf("❯ %s %s\n", stack, statusInfo))
 		} else {
-			stackList += unselectedStyle.Render(fmt.Sprintf("  %s %s\n", stack, statusInfo))
+			stackList += m.theme.Unselected.Render(fmt.Sprintf("  %s %s\n", stack, statusInfo))
+		}
+	}
+
+	if len(m.stacks) == 0 && m.stackFilter != "" {
+		stackList += m.theme.Unselected.Render("No stacks match the filter") + "\n"
+	}
+
+	// Show the filter being typed, or the one narrowing the list
+	if m.state == "stackFilter" {
+		stackList += "\n  " + m.input.View() + "\n"
+	} else if m.stackFilter != "" {
+		stackList += "\n" + m.theme.Instruction.Render(fmt.Sprintf("Filter: %s (%d of %d stacks) • %s", m.stackFilter, len(m.stacks), len(m.allStacks), keyHelp(m.keys.hint("back", "Clear"))))
+	}
+
+	// Explain why the selected stack is not healthy
+	if m.selectedStack < len(m.stacks) {
+		if reason := m.healthReason(m.stacks[m.selectedStack]); reason != "" {
+			stackList += "\n" + m.theme.Instruction.Render(fmt.Sprintf("%s • Press '%s' for details", reason, m.keys.binding("health").Help().Key))
 		}
 	}
 
-	// Help panel with vibrant controls
-	helpText := titleStyle.Render("Keyboard Controls") + "\n\n" +
-		fmt.Sprintf("%s Navigate stacks\n", selectedStyle.Render("↑/↓")) +
-		fmt.Sprintf("%s View containers\n", selectedStyle.Render("Enter")) +
-		fmt.Sprintf("%s Action menu\n", selectedStyle.Render("A")) +
-		fmt.Sprintf("%s Back/Escape\n", selectedStyle.Render("Esc/B")) +
-		fmt.Sprintf("%s Quit application", selectedStyle.Render("Q"))
-	helpPanel := helpPanelStyle.Render(helpText)
+	// Help panel generated from the active key bindings
+	up, down := m.keys.binding("up"), m.keys.binding("down")
+	helpBindings := []key.Binding{
+		key.NewBinding(key.WithHelp(up.Help().Key+"/"+down.Help().Key, "Navigate stacks")),
+		m.keys.binding("select"),
+	}
+	helpBindings = append(helpBindings, m.keys.help("stack")...)
+	helpBindings = append(helpBindings, m.keys.binding("back"), m.keys.binding("quit"))
+	helpLines := make([]string, 0, len(helpBindings))
+	for _, b := range helpBindings {
+		helpLines = append(helpLines, fmt.Sprintf("%s %s", m.theme.Selected.Render(b.Help().Key), b.Help().Desc))
+	}
+	helpText := m.theme.Title.Render("Keyboard Controls") + "\n\n" + strings.Join(helpLines, "\n")
+	helpPanel := m.theme.HelpPanel.Render(helpText)
 
 	// Stack panel with title
-	stackPanel := stackPanelStyle.Render(
-		titleStyle.Render("Docker Stacks") + "\n" +
+	stackPanel := m.theme.StackPanel.Render(
+		m.theme.Title.Render(fmt.Sprintf("Docker Stacks (by %s)", m.stackSortLabel())) + "\n" +
 			stackList + "\n" +
-			instructionStyle.Render("Press 'A' for actions, 'Enter' to view containers"))
+			m.theme.Instruction.Render(keyHelp(m.keys.binding("actions"), m.keys.binding("select"))))
 
 	// Log output panel
 	logPanel := ""
@@ -91,13 +149,20 @@ func (m Model) renderStackView(header string) string {
 			m.logOutput = strings.Join(logOutputLines, "\n")
 		}
 
-		logPanel = logPanelStyle.Render(
-			titleStyle.Render("Output Log") + "\n" +
-				logStyle.Render(m.logOutput))
+		logPanel = m.theme.LogPanel.Render(
+			m.theme.Title.Render("Output Log") + "\n" +
+				m.theme.Log.Render(m.logOutput))
 	}
 
 	// Combine panels
-	topRow := lipgloss.JoinHorizontal(lipgloss.Top, stackPanel, helpPanel)
+	sidePanels := lipgloss.JoinVertical(lipgloss.Left, helpPanel, m.renderHealthPanel())
+	if m.alerts != nil {
+		sidePanels = lipgloss.JoinVertical(lipgloss.Left, sidePanels, m.renderAlertPanel())
+	}
+	if len(m.logTriggers) > 0 {
+		sidePanels = lipgloss.JoinVertical(lipgloss.Left, sidePanels, m.renderLogHitsPanel())
+	}
+	topRow := lipgloss.JoinHorizontal(lipgloss.Top, stackPanel, sidePanels)
 	view := lipgloss.JoinVertical(lipgloss.Left, header, topRow)
 
 	if m.logOutput != "" {
@@ -105,7 +170,7 @@ func (m Model) renderStackView(header string) string {
 	}
 
 	if m.debug {
-		debugView := debugStyle.Render(fmt.Sprintf("\nDEBUG:\nstate: %s\nselectedStack: %d\nviewport: %dx%d",
+		debugView := m.theme.Debug.Render(fmt.Sprintf("\nDEBUG:\nstate: %s\nselectedStack: %d\nviewport: %dx%d",
 			m.state, m.selectedStack, m.viewportWidth, m.viewportHeight))
 		view = lipgloss.JoinVertical(lipgloss.Left, view, debugView)
 	}
@@ -115,20 +180,19 @@ func (m Model) renderStackView(header string) string {
 
 // renderActionMenu renders the action menu for a stack
 func (m Model) renderActionMenu(header string) string {
-	selectedStack := m.stacks[m.selectedStack]
+	selectedStack := m.currentStack
 
 	// More vibrant action menu
-	actionTitle := titleStyle.Render(fmt.Sprintf("Actions for Stack: %s", selectedStack))
+	actionTitle := m.theme.Title.Render(fmt.Sprintf("Actions for Stack: %s", selectedStack))
 
-	actionOptions := "\n\n" +
-		selectedStyle.Render("[R]") + " Restart Stack\n" +
-		selectedStyle.Render("[K]") + " Kill Stack\n" +
-		selectedStyle.Render("[L]") + " View Logs\n" +
-		selectedStyle.Render("[Esc/B]") + " Back to Stack List"
+	actionOptions := "\n"
+	for _, b := range append(m.keys.help("actionMenu"), m.keys.hint("back", "Back to Stack List")) {
+		actionOptions += "\n" + m.theme.Selected.Render("["+b.Help().Key+"]") + " " + b.Help().Desc
+	}
 
 	// Make action menu responsive
-	actionMenuStyle = actionMenuStyle.Width(m.viewportWidth / 2).Align(lipgloss.Center)
-	actionPanel := actionMenuStyle.Render(actionTitle + actionOptions)
+	m.theme.ActionMenu = m.theme.ActionMenu.Width(m.viewportWidth / 2).Align(lipgloss.Center)
+	actionPanel := m.theme.ActionMenu.Render(actionTitle + actionOptions)
 
 	// Center the action menu in the screen
 	centeredPanel := lipgloss.Place(
@@ -143,61 +207,30 @@ func (m Model) renderActionMenu(header string) string {
 
 // renderContainerList renders the container list view
 func (m Model) renderContainerList(header string) string {
-	selectedStack := m.stacks[m.selectedStack]
+	selectedStack := m.currentStack
 	containerList := ""
 
 	if len(m.containers) == 0 {
-		containerList = unselectedStyle.Render("No containers found for this stack")
+		containerList = m.theme.Unselected.Render("No containers found for this stack")
 	} else {
-		// Header for container list with vibrant styling
-		containerList += titleStyle.Render(fmt.Sprintf("%-20s %-15s %-12s %-20s\n", "NAME", "STATUS", "ID", "IMAGE"))
-		containerList += fmt.Sprintf("%-20s %-15s %-12s %-20s\n",
-			strings.Repeat("━", 18),
-			strings.Repeat("━", 12),
-			strings.Repeat("━", 10),
-			strings.Repeat("━", 18))
-
-		for i, container := range m.containers {
-			name := strings.TrimPrefix(container.Names[0], "/")
-			if len(name) > 18 {
-				name = name[:15] + "..."
-			}
-
-			image := container.Image
-			if len(image) > 18 {
-				image = image[:15] + "..."
-			}
-
-			shortID := container.ID[:10]
-
-			status := container.State
-			var styledStatus string
-			switch status {
-			case "running":
-				styledStatus = statusRunning.Render(status)
-			case "exited", "stopped":
-				styledStatus = statusStopped.Render(status)
-			default:
-				styledStatus = statusOther.Render(status)
-			}
+		headerRow, ruleRow, rows := m.renderContainerRows()
+		containerList += m.theme.Title.Render(headerRow+"\n") + ruleRow + "\n"
 
+		for i, row := range rows {
 			// Show selection indicator for the current container
-			prefix := "  "
 			if i == m.selectedContainer {
-				prefix = "❯ "
-				containerList += selectedStyle.Render(fmt.Sprintf("%s%-20s %-15s %-12s %-20s\n",
-					prefix, name, styledStatus, shortID, image))
+				containerList += m.theme.Selected.Render("❯ " + row + "\n")
 			} else {
-				containerList += unselectedStyle.Render(fmt.Sprintf("%s%-20s %-15s %-12s %-20s\n",
-					prefix, name, styledStatus, shortID, image))
+				containerList += m.theme.Unselected.Render("  " + row + "\n")
 			}
 		}
 	}
 
-	containerPanel := containerStyle.Render(
-		titleStyle.Render(fmt.Sprintf("Containers in %s", selectedStack)) + "\n" +
+	containerPanel := m.theme.Container.Render(
+		m.theme.Title.Render(fmt.Sprintf("Containers in %s", selectedStack)) + "\n" +
 			containerList + "\n" +
-			instructionStyle.Render("Press Enter to view container logs, Esc/B to go back"))
+			m.theme.Instruction.Render(fmt.Sprintf("%s: Logs • %s • %s: Back",
+				m.keys.binding("select").Help().Key, keyHelp(m.keys.help("containerList")...), m.keys.binding("back").Help().Key)))
 
 	return lipgloss.JoinVertical(lipgloss.Left, header, containerPanel)
 }
@@ -207,7 +240,7 @@ func (m Model) renderContainerLogs(header string) string {
 	// New container logs view
 	if len(m.containers) == 0 {
 		return lipgloss.JoinVertical(lipgloss.Left, header,
-			logPanelStyle.Render(unselectedStyle.Render("No container selected")))
+			m.theme.LogPanel.Render(m.theme.Unselected.Render("No container selected")))
 	}
 
 	container := m.containers[m.selectedContainer]
@@ -226,10 +259,398 @@ func (m Model) renderContainerLogs(header string) string {
 		m.logOutput = strings.Join(logLines, "\n")
 	}
 
-	logPanel := logPanelStyle.Height(logViewHeight).Render(
-		titleStyle.Render(fmt.Sprintf("Logs: %s (%s)", containerName, container.ID[:10])) + "\n" +
-			logStyle.Render(m.logOutput) + "\n" +
-			instructionStyle.Render("Press Esc/B to go back to container list"))
+	logPanel := m.theme.LogPanel.Height(logViewHeight).Render(
+		m.theme.Title.Render(fmt.Sprintf("Logs: %s (%s)", containerName, container.ID[:10])) + "\n" +
+			m.theme.Log.Render(m.logOutput) + "\n" +
+			m.theme.Instruction.Render(keyHelp(m.keys.hint("back", "Back to containers"))))
+
+	return lipgloss.JoinVertical(lipgloss.Left, header, logPanel)
+}
+
+// renderSecrets renders the swarm secrets and configs screen
+func (m Model) renderSecrets(header string) string {
+	resourceList := ""
+
+	if len(m.resources) == 0 {
+		resourceList = m.theme.Unselected.Render("No secrets or configs found")
+	} else {
+		resourceList += m.theme.Title.Render(fmt.Sprintf("%-8s %-28s %-17s %-30s\n", "KIND", "NAME", "CREATED", "USED BY"))
+
+		for i, resource := range m.resources {
+			name := resource.Name
+			if len(name) > 26 {
+				name = name[:23] + "..."
+			}
+
+			services := strings.Join(resource.Services, ", ")
+			if services == "" {
+				services = "-"
+			}
+
+			kind := m.theme.Other.Render(fmt.Sprintf("%-8s", resource.Kind))
+			if resource.Kind == "secret" {
+				kind = m.theme.Stopped.Render(fmt.Sprintf("%-8s", resource.Kind))
+			}
+
+			line := fmt.Sprintf("%s %-28s %-17s %-30s\n", kind, name, resource.CreatedAt.Format("2006-01-02 15:04"), services)
+			if i == m.selectedResource {
+				resourceList += m.theme.Selected.Render("❯ " + line)
+			} else {
+				resourceList += m.theme.Unselected.Render("  " + line)
+			}
+		}
+
+		if labels := m.resources[m.selectedResource].Labels; len(labels) > 0 {
+			keys := make([]string, 0, len(labels))
+			for k := range labels {
+				keys = append(keys, k)
+			}
+			sort.Strings(keys)
+
+			resourceList += "\n" + m.theme.Title.Render("Labels") + "\n"
+			for _, k := range keys {
+				resourceList += m.theme.Unselected.Render(fmt.Sprintf("%s=%s\n", k, labels[k]))
+			}
+		}
+	}
+
+	panel := m.theme.Container.Render(
+		m.theme.Title.Render("Secrets & Configs") + "\n" +
+			resourceList + "\n" +
+			m.theme.Instruction.Render(m.keys.viewHelp("secrets", "Back", m.keys.hint("select", "View config"))))
+
+	view := lipgloss.JoinVertical(lipgloss.Left, header, panel)
+	if m.logOutput != "" {
+		view = lipgloss.JoinVertical(lipgloss.Left, view, m.theme.LogPanel.Render(m.theme.Log.Render(m.logOutput)))
+	}
+	return view
+}
+
+// renderResourceContent renders the content of the selected config
+func (m Model) renderResourceContent(header string) string {
+	resource := m.resources[m.selectedResource]
+
+	logPanel := m.theme.LogPanel.Render(
+		m.theme.Title.Render(fmt.Sprintf("Config: %s", resource.Name)) + "\n" +
+			m.theme.Log.Render(m.logOutput) + "\n" +
+			m.theme.Instruction.Render(keyHelp(m.keys.hint("back", "Back to secrets & configs"))))
 
 	return lipgloss.JoinVertical(lipgloss.Left, header, logPanel)
 }
+
+// renderImages renders the local images screen
+func (m Model) renderImages(header string) string {
+	imageList := ""
+
+	if len(m.images) == 0 {
+		imageList = m.theme.Unselected.Render("No images found")
+	} else {
+		var totalSize int64
+		for _, img := range m.images {
+			totalSize += img.Size
+		}
+
+		imageList += m.theme.Title.Render(fmt.Sprintf("%-36s %-14s %-10s %-17s %-24s\n", "TAG", "ID", "SIZE", "CREATED", "USED BY"))
+
+		for i, img := range m.images {
+			tag := "<none>"
+			if len(img.Tags) > 0 {
+				tag = img.Tags[0]
+			}
+			if len(tag) > 34 {
+				tag = tag[:31] + "..."
+			}
+
+			users := append(append([]string{}, img.Services...), img.Containers...)
+			usedBy := strings.Join(users, ", ")
+			if usedBy == "" {
+				usedBy = "-"
+			}
+			if len(usedBy) > 24 {
+				usedBy = usedBy[:21] + "..."
+			}
+
+			styledTag := fmt.Sprintf("%-36s", tag)
+			if img.Dangling() {
+				styledTag = m.theme.Other.Render(styledTag)
+			}
+
+			line := fmt.Sprintf("%s %-14s %-10s %-17s %-24s\n",
+				styledTag, shortImageID(img.ID), units.BytesSize(float64(img.Size)),
+				img.CreatedAt.Format("2006-01-02 15:04"), usedBy)
+			if i == m.selectedImage {
+				imageList += m.theme.Selected.Render("❯ " + line)
+			} else {
+				imageList += m.theme.Unselected.Render("  " + line)
+			}
+		}
+
+		img := m.images[m.selectedImage]
+		imageList += "\n" + m.theme.Title.Render("Details") + "\n"
+		imageList += m.theme.Unselected.Render(fmt.Sprintf("Tags: %s\n", strings.Join(img.Tags, ", ")))
+		for _, digest := range img.Digests {
+			imageList += m.theme.Unselected.Render(fmt.Sprintf("Digest: %s\n", digest))
+		}
+		imageList += m.theme.Unselected.Render(fmt.Sprintf("Total: %d image(s), %s\n", len(m.images), units.BytesSize(float64(totalSize))))
+	}
+
+	panel := m.theme.Container.Render(
+		m.theme.Title.Render("Images") + "\n" +
+			imageList + "\n" +
+			m.theme.Instruction.Render(m.keys.viewHelp("images", "Back", m.keys.hint("select", "Layer history"))))
+
+	view := lipgloss.JoinVertical(lipgloss.Left, header, panel)
+	if m.logOutput != "" {
+		view = lipgloss.JoinVertical(lipgloss.Left, view, m.theme.LogPanel.Render(m.theme.Log.Render(m.logOutput)))
+	}
+	return view
+}
+
+// renderImageHistory renders the layer history of the selected image
+func (m Model) renderImageHistory(header string) string {
+	img := m.images[m.selectedImage]
+	name := shortImageID(img.ID)
+	if len(img.Tags) > 0 {
+		name = img.Tags[0]
+	}
+
+	createdByWidth := m.viewportWidth - 50
+	if createdByWidth < 20 {
+		createdByWidth = 20
+	}
+
+	layerList := m.theme.Title.Render(fmt.Sprintf("%-10s %-17s %s\n", "SIZE", "CREATED", "CREATED BY"))
+	for _, layer := range m.imageLayers {
+		createdBy := strings.Join(strings.Fields(layer.CreatedBy), " ")
+		if len(createdBy) > createdByWidth {
+			createdBy = createdBy[:createdByWidth-3] + "..."
+		}
+
+		size := units.BytesSize(float64(layer.Size))
+		if layer.Size > 0 {
+			size = m.theme.Other.Render(fmt.Sprintf("%-10s", size))
+		} else {
+			size = fmt.Sprintf("%-10s", size)
+		}
+
+		layerList += m.theme.Unselected.Render(fmt.Sprintf("%s %-17s %s\n",
+			size, layer.CreatedAt.Format("2006-01-02 15:04"), createdBy))
+	}
+
+	panel := m.theme.Container.Render(
+		m.theme.Title.Render(fmt.Sprintf("Layer history: %s", name)) + "\n" +
+			layerList + "\n" +
+			m.theme.Instruction.Render(keyHelp(m.keys.hint("back", "Back to images"))))
+
+	return lipgloss.JoinVertical(lipgloss.Left, header, panel)
+}
+
+// renderHealthPanel renders the compact system health panel shown next to the stack list
+func (m Model) renderHealthPanel() string {
+	if m.diskUsageErr != nil {
+		return m.theme.HelpPanel.Render(m.theme.Title.Render("System Health") + "\n" +
+			m.theme.Stopped.Render("Disk usage unavailable"))
+	}
+
+	barWidth := m.theme.HelpPanel.GetWidth() - 28
+	if barWidth < 5 {
+		barWidth = 5
+	}
+
+	total := m.diskUsage.Total()
+	healthText := m.theme.Title.Render("System Health") + "\n"
+	for _, section := range m.diskUsage.Sections() {
+		healthText += fmt.Sprintf("%-11s %s %s\n",
+			section.Name, m.theme.renderBar(section.Size, total, barWidth), units.BytesSize(float64(section.Size)))
+	}
+	healthText += m.theme.Instruction.Render(fmt.Sprintf("Press '%s' for details", m.keys.binding("disk_usage").Help().Key))
+
+	return m.theme.HelpPanel.Render(healthText)
+}
+
+// healthReason returns the first reason the stack is not healthy, if any
+func (m Model) healthReason(stack string) string {
+	health, ok := m.stackHealth[stack]
+	if !ok || health.State == docker.HealthHealthy {
+		return ""
+	}
+	for _, service := range health.Services {
+		if service.State == health.State && len(service.Reasons) > 0 {
+			return fmt.Sprintf("%s: %s", service.Service, service.Reasons[0])
+		}
+	}
+	return ""
+}
+
+// renderStackHealth renders the health of each service of the selected stack and the reasons for it
+func (m Model) renderStackHealth(header string) string {
+	stack := m.currentStack
+	health, ok := m.stackHealth[stack]
+
+	healthText := ""
+	if !ok {
+		healthText = m.theme.Unselected.Render("Health is unavailable for this stack") + "\n"
+	}
+	for _, service := range health.Services {
+		healthText += fmt.Sprintf("  %s %s\n", m.theme.healthBadge(service.State), service.Service)
+		for _, reason := range service.Reasons {
+			healthText += m.theme.Unselected.Render("    "+reason) + "\n"
+		}
+	}
+
+	title := fmt.Sprintf("Health: %s", stack)
+	if ok {
+		title += " " + m.theme.healthBadge(health.State)
+	}
+	panel := m.theme.StackPanel.Render(
+		m.theme.Title.Render(title) + "\n" +
+			healthText + "\n" +
+			m.theme.Instruction.Render(fmt.Sprintf("A stack is as healthy as its worst service. %d or more failed tasks in %s count as a restart loop. %s",
+				docker.RestartLoopFailures, docker.RestartLoopWindow, keyHelp(m.keys.hint("back", "Back")))))
+
+	return lipgloss.JoinVertical(lipgloss.Left, header, panel)
+}
+
+// renderAlertPanel renders the alerts currently firing, oldest first
+func (m Model) renderAlertPanel() string {
+	alertText := m.theme.Title.Render("Alerts") + "\n"
+
+	if len(m.activeAlerts) == 0 {
+		alertText += m.theme.Running.Render("All clear")
+	}
+	for _, alert := range m.activeAlerts {
+		since := time.Since(alert.Since).Round(time.Second)
+		alertText += m.theme.Stopped.Render("● "+alert.Subject) + "\n" +
+			fmt.Sprintf("  %s (%s, %s)\n", alert.Message, alert.Rule, since)
+	}
+	if m.alertsErr != nil {
+		alertText += "\n" + m.theme.Other.Render(fmt.Sprintf("Check incomplete: %v", m.alertsErr))
+	}
+
+	style := m.theme.HelpPanel
+	if len(m.activeAlerts) > 0 {
+		style = m.theme.AlertPanel
+	}
+	return style.Render(alertText)
+}
+
+// renderDiskUsage renders the disk usage dashboard
+func (m Model) renderDiskUsage(header string) string {
+	usageList := ""
+
+	if m.diskUsageErr != nil {
+		usageList = m.theme.Stopped.Render(fmt.Sprintf("Error getting disk usage: %v", m.diskUsageErr))
+	} else {
+		barWidth := m.viewportWidth - 90
+		if barWidth < 10 {
+			barWidth = 10
+		}
+
+		usageList += m.theme.Title.Render(fmt.Sprintf("%-13s %-8s %-8s %-10s %-18s %s\n", "TYPE", "TOTAL", "ACTIVE", "SIZE", "RECLAIMABLE", "USAGE"))
+
+		total := m.diskUsage.Total()
+		for i, section := range m.diskUsage.Sections() {
+			reclaimable := units.BytesSize(float64(section.Reclaimable))
+			if section.Size > 0 {
+				reclaimable = fmt.Sprintf("%s (%d%%)", reclaimable, section.Reclaimable*100/section.Size)
+			}
+
+			line := fmt.Sprintf("%-13s %-8d %-8d %-10s %-18s %s\n",
+				section.Name, section.Count, section.Active,
+				units.BytesSize(float64(section.Size)), reclaimable,
+				m.theme.renderBar(section.Size, total, barWidth))
+			if i == m.selectedSection {
+				usageList += m.theme.Selected.Render("❯ " + line)
+			} else {
+				usageList += m.theme.Unselected.Render("  " + line)
+			}
+		}
+
+		usageList += "\n" + m.theme.Unselected.Render(fmt.Sprintf("Total: %s", units.BytesSize(float64(total))))
+	}
+
+	panel := m.theme.Container.Render(
+		m.theme.Title.Render("Disk Usage") + "\n" +
+			usageList + "\n" +
+			m.theme.Instruction.Render(m.keys.viewHelp("diskUsage", "Back", m.keys.hint("select", "Open section"))))
+
+	view := lipgloss.JoinVertical(lipgloss.Left, header, panel)
+	if m.logOutput != "" {
+		view = lipgloss.JoinVertical(lipgloss.Left, view, m.theme.LogPanel.Render(m.theme.Log.Render(m.logOutput)))
+	}
+	return view
+}
+
+// renderContexts renders the Docker context switcher
+func (m Model) renderContexts(header string) string {
+	contextList := m.theme.Title.Render(fmt.Sprintf("%-20s %-8s %-40s %s\n", "NAME", "SOURCE", "HOST", "DESCRIPTION"))
+
+	for i, c := range m.contexts {
+		host := c.Host
+		if host == "" {
+			host = "(from environment)"
+		}
+
+		name := c.Name
+		if name == m.contextName {
+			name += " *"
+		}
+
+		line := fmt.Sprintf("%-20s %-8s %-40s %s\n", name, c.Source, host, c.Description)
+		if i == m.selectedContext {
+			contextList += m.theme.Selected.Render("❯ " + line)
+		} else {
+			contextList += m.theme.Unselected.Render("  " + line)
+		}
+	}
+
+	panel := m.theme.Container.Render(
+		m.theme.Title.Render("Docker Hosts") + "\n" +
+			contextList + "\n" +
+			m.theme.Instruction.Render(keyHelp(m.keys.hint("select", "Connect"), m.keys.hint("back", "Back"))))
+
+	view := lipgloss.JoinVertical(lipgloss.Left, header, panel)
+	if m.logOutput != "" {
+		view = lipgloss.JoinVertical(lipgloss.Left, view, m.theme.LogPanel.Render(m.theme.Log.Render(m.logOutput)))
+	}
+	return view
+}
+
+// renderBar renders a horizontal bar showing value as a share of max
+func (t Theme) renderBar(value, max int64, width int) string {
+	filled := 0
+	if max > 0 {
+		filled = int(value * int64(width) / max)
+	}
+	if value > 0 && filled == 0 {
+		filled = 1
+	}
+
+	return t.Running.Render(strings.Repeat("█", filled)) +
+		t.BarEmpty.Render(strings.Repeat("░", width-filled))
+}
+
+// shortImageID strips the digest algorithm and shortens an image ID for display
+func shortImageID(id string) string {
+	id = strings.TrimPrefix(id, "sha256:")
+	if len(id) > 12 {
+		id = id[:12]
+	}
+	return id
+}
+
+// renderInput renders the prompt used to collect free-form input
+func (m Model) renderInput(header string) string {
+	inputPanel := m.theme.ActionMenu.Width(m.viewportWidth / 2).Align(lipgloss.Left).Render(
+		m.input.View() + "\n\n" +
+			m.theme.Instruction.Render("Enter to confirm, Esc to cancel"))
+
+	centeredPanel := lipgloss.Place(
+		m.viewportWidth,
+		m.viewportHeight-2, // Account for header
+		lipgloss.Center,
+		lipgloss.Center,
+		inputPanel)
+
+	return lipgloss.JoinVertical(lipgloss.Left, header, centeredPanel)
+}
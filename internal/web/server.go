@@ -0,0 +1,357 @@
+package web
+
+import (
+	"context"
+	"embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net"
+	"net/http"
+	"os"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/docker/docker/client"
+
+	"pulse/internal/auth"
+	"pulse/internal/config"
+	"pulse/internal/docker"
+)
+
+//go:embed static
+var static embed.FS
+
+// Info tells the browser how the server was started
+type Info struct {
+	Host     string `json:"host"`
+	ReadOnly bool   `json:"readOnly"`
+	// AuthRequired is set when viewing needs a token too
+	AuthRequired bool `json:"authRequired"`
+	// RefreshInterval is in milliseconds; 0 disables automatic refreshes
+	RefreshInterval int64 `json:"refreshInterval"`
+}
+
+// errorResponse is the body returned with every non-2xx status
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+// actionHeader must be set on every action request. Browsers only send custom headers
+// from scripts of the same origin, so a page elsewhere cannot forge an action.
+const actionHeader = "X-Pulse-Action"
+
+// Server serves the web dashboard and the JSON and event-stream endpoints behind it
+type Server struct {
+	cli      *client.Client
+	cfg      config.Config
+	readOnly bool
+	readAuth bool
+	listen   string
+	auth     *auth.Authenticator
+}
+
+// NewServer creates a web server for the dashboard at listen. Actions require a token
+// with the operate scope, and with readAuth the views require one with the read scope;
+// in read-only mode every action is refused.
+func NewServer(cli *client.Client, cfg config.Config, readOnly, readAuth bool, listen string, tokens []auth.Token) *Server {
+	return &Server{cli: cli, cfg: cfg, readOnly: readOnly, readAuth: readAuth, listen: listen, auth: auth.New(tokens, writeError)}
+}
+
+// Handler returns the HTTP handler serving the dashboard
+func (s *Server) Handler() http.Handler {
+	assets, _ := fs.Sub(static, "static")
+
+	mux := http.NewServeMux()
+	mux.Handle("GET /", http.FileServer(http.FS(assets)))
+	mux.HandleFunc("GET /api/info", s.handleInfo)
+	mux.Handle("GET /api/stacks", s.view(s.handleStacks))
+	mux.Handle("GET /api/stacks/{stack}/containers", s.view(s.handleContainers))
+	mux.Handle("GET /api/stacks/{stack}/logs", s.view(s.handleStackLogs))
+	mux.Handle("POST /api/stacks/{stack}/restart", s.action(docker.RestartStack, "Restarted"))
+	mux.Handle("POST /api/stacks/{stack}/kill", s.action(docker.KillStack, "Killed"))
+	mux.Handle("GET /api/containers/{id}/logs", s.view(s.handleContainerLogs))
+	return s.checkHost(mux)
+}
+
+// checkHost refuses requests addressed to another host name than the dashboard's, so
+// a page on a rebound DNS name cannot read the dashboard through the user's browser
+func (s *Server) checkHost(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !s.allowedHost(r.Host) {
+			writeError(w, http.StatusMisdirectedRequest, fmt.Errorf("unexpected host %q", r.Host))
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// allowedHost reports whether host names the listen address. A loopback listener also
+// answers to localhost; one listening on every interface answers to IP addresses and
+// the machine's name, which DNS rebinding cannot forge.
+func (s *Server) allowedHost(host string) bool {
+	if host == s.listen {
+		return true
+	}
+	listenHost, listenPort, err := net.SplitHostPort(s.listen)
+	if err != nil {
+		return false
+	}
+	name, port, err := net.SplitHostPort(host)
+	if err != nil {
+		name, port = host, "80"
+	}
+	if port != listenPort {
+		return false
+	}
+
+	listenIP := net.ParseIP(listenHost)
+	switch {
+	case listenHost == "" || listenIP != nil && listenIP.IsUnspecified():
+		hostname, _ := os.Hostname()
+		return net.ParseIP(name) != nil || name == "localhost" || strings.EqualFold(name, hostname)
+	case listenHost == "localhost" || listenIP != nil && listenIP.IsLoopback():
+		ip := net.ParseIP(name)
+		return name == "localhost" || ip != nil && ip.IsLoopback()
+	default:
+		return strings.EqualFold(name, listenHost)
+	}
+}
+
+// sameOrigin reports whether a request comes from a page of the dashboard itself
+func sameOrigin(r *http.Request) bool {
+	if site := r.Header.Get("Sec-Fetch-Site"); site != "" && site != "same-origin" {
+		return false
+	}
+	if origin := r.Header.Get("Origin"); origin != "" && origin != "http://"+r.Host && origin != "https://"+r.Host {
+		return false
+	}
+	return r.Header.Get(actionHeader) != ""
+}
+
+func (s *Server) handleInfo(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, Info{
+		Host:            s.cli.DaemonHost(),
+		ReadOnly:        s.readOnly,
+		AuthRequired:    s.readAuth,
+		RefreshInterval: s.cfg.RefreshInterval.Milliseconds(),
+	})
+}
+
+func (s *Server) handleStacks(w http.ResponseWriter, r *http.Request) {
+	stacks, err := s.stacks(r.Context())
+	if err != nil {
+		writeError(w, http.StatusBadGateway, err)
+		return
+	}
+
+	items := make([]docker.StackStats, 0, len(stacks))
+	for _, stack := range stacks {
+		stats, err := docker.GetStackStats(r.Context(), s.cli, stack)
+		if err != nil {
+			writeError(w, http.StatusBadGateway, err)
+			return
+		}
+		items = append(items, stats)
+	}
+	writeJSON(w, http.StatusOK, items)
+}
+
+func (s *Server) handleContainers(w http.ResponseWriter, r *http.Request) {
+	stack, ok := s.requireStack(w, r)
+	if !ok {
+		return
+	}
+
+	containers, err := docker.ListContainers(r.Context(), s.cli, stack)
+	if err != nil {
+		writeError(w, http.StatusBadGateway, err)
+		return
+	}
+
+	items := make([]docker.ContainerSummary, 0, len(containers))
+	for _, c := range containers {
+		items = append(items, docker.SummarizeContainer(c))
+	}
+	sort.Slice(items, func(i, j int) bool { return items[i].Name < items[j].Name })
+	writeJSON(w, http.StatusOK, items)
+}
+
+func (s *Server) handleStackLogs(w http.ResponseWriter, r *http.Request) {
+	stack, ok := s.requireStack(w, r)
+	if !ok {
+		return
+	}
+
+	s.streamLogs(w, r, func(ctx context.Context, tail int, lines chan<- string) error {
+		return docker.FollowStackLogs(ctx, s.cli, stack, tail, lines)
+	})
+}
+
+func (s *Server) handleContainerLogs(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	details, err := docker.InspectContainer(r.Context(), s.cli, id)
+	if err != nil {
+		if client.IsErrNotFound(err) {
+			writeError(w, http.StatusNotFound, fmt.Errorf("container %s not found", id))
+		} else {
+			writeError(w, http.StatusBadGateway, err)
+		}
+		return
+	}
+
+	// Only containers of visible stacks are reachable, like in the TUI
+	stack := details.Config.Labels["com.docker.stack.namespace"]
+	if stack == "" || s.cfg.IsHidden(stack) {
+		writeError(w, http.StatusNotFound, fmt.Errorf("container %s is not part of a stack", id))
+		return
+	}
+
+	s.streamLogs(w, r, func(ctx context.Context, tail int, lines chan<- string) error {
+		return docker.FollowContainerLogs(ctx, s.cli, id, tail, lines)
+	})
+}
+
+// streamLogs sends log lines as Server-Sent Events until the client disconnects.
+// Each line is a "message" event; a failure ends the stream with an "error" event.
+func (s *Server) streamLogs(w http.ResponseWriter, r *http.Request, follow func(ctx context.Context, tail int, lines chan<- string) error) {
+	tail := s.cfg.LogTail
+	if value := r.URL.Query().Get("tail"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 0 {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("invalid tail %q", value))
+			return
+		}
+		tail = parsed
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeError(w, http.StatusInternalServerError, errors.New("streaming is not supported"))
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	ctx, cancel := context.WithCancel(r.Context())
+	defer cancel()
+
+	lines := make(chan string, 64)
+	done := make(chan error, 1)
+	go func() {
+		done <- follow(ctx, tail, lines)
+	}()
+
+	// Comments keep proxies from closing idle streams
+	keepAlive := time.NewTicker(30 * time.Second)
+	defer keepAlive.Stop()
+
+	for {
+		select {
+		case line := <-lines:
+			fmt.Fprintf(w, "data: %s\n\n", sanitize(line))
+			flusher.Flush()
+		case <-keepAlive.C:
+			fmt.Fprint(w, ": keep-alive\n\n")
+			flusher.Flush()
+		case err := <-done:
+			// Send what was read before the stream ended
+			for len(lines) > 0 {
+				fmt.Fprintf(w, "data: %s\n\n", sanitize(<-lines))
+			}
+			message := "end of logs"
+			if err != nil {
+				message = err.Error()
+			}
+			fmt.Fprintf(w, "event: end\ndata: %s\n\n", sanitize(message))
+			flusher.Flush()
+			return
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// view serves a read endpoint; callers need the read scope when viewing requires a token
+func (s *Server) view(next http.HandlerFunc) http.Handler {
+	if !s.readAuth {
+		return next
+	}
+	return s.auth.Middleware(s.auth.Require(auth.ScopeRead, next))
+}
+
+// action runs a stack operation for a caller with the operate scope, unless the server
+// is read-only or the request comes from another site
+func (s *Server) action(run func(ctx context.Context, cli *client.Client, stack string) error, verb string) http.Handler {
+	return s.auth.Middleware(s.auth.Require(auth.ScopeOperate, func(w http.ResponseWriter, r *http.Request) {
+		if s.readOnly {
+			writeError(w, http.StatusForbidden, errors.New("pulse web is running in read-only mode"))
+			return
+		}
+		if !sameOrigin(r) {
+			writeError(w, http.StatusForbidden, errors.New("cross-site action refused"))
+			return
+		}
+
+		stack, ok := s.requireStack(w, r)
+		if !ok {
+			return
+		}
+
+		if err := run(r.Context(), s.cli, stack); err != nil {
+			writeError(w, http.StatusBadGateway, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]string{"message": fmt.Sprintf("%s stack %s", verb, stack)})
+	}))
+}
+
+// stacks lists the stacks shown in the dashboard: the same set the TUI shows
+func (s *Server) stacks(ctx context.Context) ([]string, error) {
+	stacks, err := docker.ListStacks(ctx, s.cli)
+	if err != nil {
+		return nil, err
+	}
+	stacks = s.cfg.VisibleStacks(stacks)
+	sort.Strings(stacks)
+	return stacks, nil
+}
+
+// requireStack returns the stack named in the path, answering 404 if it is not shown
+func (s *Server) requireStack(w http.ResponseWriter, r *http.Request) (string, bool) {
+	stack := r.PathValue("stack")
+	stacks, err := s.stacks(r.Context())
+	if err != nil {
+		writeError(w, http.StatusBadGateway, err)
+		return "", false
+	}
+	if !slices.Contains(stacks, stack) {
+		writeError(w, http.StatusNotFound, fmt.Errorf("stack %s not found", stack))
+		return "", false
+	}
+	return stack, true
+}
+
+// lineBreaks would end an event's data field early
+var lineBreaks = strings.NewReplacer("\r", "", "\n", " ")
+
+func sanitize(line string) string {
+	return lineBreaks.Replace(line)
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, errorResponse{Error: err.Error()})
+}
@@ -0,0 +1,135 @@
+package web
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/docker/docker/client"
+
+	"pulse/internal/auth"
+	"pulse/internal/config"
+)
+
+func TestAllowedHost(t *testing.T) {
+	hostname, _ := os.Hostname()
+
+	tests := []struct {
+		name   string
+		listen string
+		host   string
+		want   bool
+	}{
+		{name: "listen address", listen: "127.0.0.1:9325", host: "127.0.0.1:9325", want: true},
+		{name: "loopback answers to localhost", listen: "127.0.0.1:9325", host: "localhost:9325", want: true},
+		{name: "loopback answers to IPv6 loopback", listen: "127.0.0.1:9325", host: "[::1]:9325", want: true},
+		{name: "loopback refuses other names", listen: "127.0.0.1:9325", host: "rebind.example:9325", want: false},
+		{name: "loopback refuses other addresses", listen: "127.0.0.1:9325", host: "10.0.0.5:9325", want: false},
+		{name: "other port", listen: "127.0.0.1:9325", host: "127.0.0.1:8080", want: false},
+		{name: "missing port means 80", listen: "127.0.0.1:9325", host: "127.0.0.1", want: false},
+		{name: "missing port on port 80", listen: "127.0.0.1:80", host: "localhost", want: true},
+		{name: "every interface answers to addresses", listen: "0.0.0.0:9325", host: "10.0.0.5:9325", want: true},
+		{name: "every interface answers to the machine's name", listen: "0.0.0.0:9325", host: hostname + ":9325", want: true},
+		{name: "every interface refuses other names", listen: "0.0.0.0:9325", host: "rebind.example:9325", want: false},
+		{name: "empty host listens everywhere", listen: ":9325", host: "192.168.1.2:9325", want: true},
+		{name: "IPv6 unspecified listens everywhere", listen: "[::]:9325", host: "[fd00::1]:9325", want: true},
+		{name: "named listen address", listen: "dash.internal:9325", host: "dash.internal:9325", want: true},
+		{name: "named listen address ignores case", listen: "dash.internal:9325", host: "DASH.internal:9325", want: true},
+		{name: "named listen address refuses others", listen: "dash.internal:9325", host: "127.0.0.1:9325", want: false},
+		{name: "invalid listen address", listen: "dash.internal", host: "dash.internal:9325", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &Server{listen: tt.listen}
+			if got := s.allowedHost(tt.host); got != tt.want {
+				t.Errorf("allowedHost(%q) with listen %q = %v, want %v", tt.host, tt.listen, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSameOrigin(t *testing.T) {
+	tests := []struct {
+		name    string
+		headers map[string]string
+		want    bool
+	}{
+		{name: "script of the dashboard", headers: map[string]string{actionHeader: "restart"}, want: true},
+		{name: "missing action header", headers: map[string]string{}, want: false},
+		{name: "same-origin fetch", headers: map[string]string{actionHeader: "kill", "Sec-Fetch-Site": "same-origin"}, want: true},
+		{name: "cross-site fetch", headers: map[string]string{actionHeader: "kill", "Sec-Fetch-Site": "cross-site"}, want: false},
+		{name: "same-site fetch", headers: map[string]string{actionHeader: "kill", "Sec-Fetch-Site": "same-site"}, want: false},
+		{name: "typed into the address bar", headers: map[string]string{actionHeader: "kill", "Sec-Fetch-Site": "none"}, want: false},
+		{name: "own origin", headers: map[string]string{actionHeader: "kill", "Origin": "http://127.0.0.1:9325"}, want: true},
+		{name: "own origin over TLS", headers: map[string]string{actionHeader: "kill", "Origin": "https://127.0.0.1:9325"}, want: true},
+		{name: "other origin", headers: map[string]string{actionHeader: "kill", "Origin": "http://evil.example"}, want: false},
+		{name: "other port", headers: map[string]string{actionHeader: "kill", "Origin": "http://127.0.0.1:8080"}, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPost, "http://127.0.0.1:9325/api/stacks/shop/restart", nil)
+			for key, value := range tt.headers {
+				r.Header.Set(key, value)
+			}
+			if got := sameOrigin(r); got != tt.want {
+				t.Errorf("sameOrigin() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestViewAuth(t *testing.T) {
+	// The daemon is never reached; requests that get past authentication fail with 502
+	cli, err := client.NewClientWithOpts(client.WithHost("tcp://127.0.0.1:1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cli.Close()
+	tokens := []auth.Token{
+		{Name: "viewer", Value: "read-token", Scope: auth.ScopeRead},
+		{Name: "admin", Value: "operate-token", Scope: auth.ScopeOperate},
+	}
+
+	tests := []struct {
+		name     string
+		readAuth bool
+		method   string
+		path     string
+		token    string
+		want     int
+	}{
+		{name: "open views", readAuth: false, path: "/api/stacks", want: http.StatusBadGateway},
+		{name: "view without token", readAuth: true, path: "/api/stacks", want: http.StatusUnauthorized},
+		{name: "view with wrong token", readAuth: true, path: "/api/stacks", token: "guess", want: http.StatusUnauthorized},
+		{name: "view with read token", readAuth: true, path: "/api/stacks", token: "read-token", want: http.StatusBadGateway},
+		{name: "view with operate token", readAuth: true, path: "/api/stacks", token: "operate-token", want: http.StatusBadGateway},
+		{name: "containers without token", readAuth: true, path: "/api/stacks/shop/containers", want: http.StatusUnauthorized},
+		{name: "stack logs without token", readAuth: true, path: "/api/stacks/shop/logs", want: http.StatusUnauthorized},
+		{name: "container logs without token", readAuth: true, path: "/api/containers/abc/logs", want: http.StatusUnauthorized},
+		{name: "info stays open", readAuth: true, path: "/api/info", want: http.StatusOK},
+		{name: "action without token", readAuth: false, method: http.MethodPost, path: "/api/stacks/shop/restart", want: http.StatusUnauthorized},
+		{name: "action with read token", readAuth: true, method: http.MethodPost, path: "/api/stacks/shop/restart", token: "read-token", want: http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := NewServer(cli, config.Config{}, false, tt.readAuth, "127.0.0.1:9325", tokens).Handler()
+			method := tt.method
+			if method == "" {
+				method = http.MethodGet
+			}
+			r := httptest.NewRequest(method, "http://127.0.0.1:9325"+tt.path, nil)
+			if tt.token != "" {
+				r.Header.Set("Authorization", "Bearer "+tt.token)
+			}
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, r)
+			if w.Code != tt.want {
+				t.Errorf("%s %s = %d, want %d: %s", method, tt.path, w.Code, tt.want, w.Body.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,240 @@
+"use strict";
+
+const state = {
+  readOnly: true,
+  authRequired: false,
+  refreshInterval: 0,
+  stack: null,
+  logs: null,
+};
+
+const $ = (id) => document.getElementById(id);
+
+// The token arrives in the URL fragment of the link pulse web prints; keep it for the
+// session and drop it from the address bar
+function takeToken() {
+  const params = new URLSearchParams(window.location.hash.slice(1));
+  if (params.has("token")) {
+    sessionStorage.setItem("pulse-token", params.get("token"));
+    history.replaceState(null, "", window.location.pathname);
+  }
+  return sessionStorage.getItem("pulse-token");
+}
+
+// askToken prompts for a token and keeps it for the session
+function askToken(text) {
+  const token = window.prompt(text);
+  if (token) {
+    sessionStorage.setItem("pulse-token", token);
+  }
+  return token;
+}
+
+// authHeaders sends the session's token with views when the server requires one
+function authHeaders() {
+  const token = takeToken();
+  return state.authRequired && token ? { Authorization: `Bearer ${token}` } : {};
+}
+
+async function api(path, options = {}) {
+  const response = await fetch(path, { ...options, headers: { ...authHeaders(), ...options.headers } });
+  const body = await response.json();
+  if (!response.ok) {
+    // A rejected token is dropped, so the next page load asks for another one
+    if (response.status === 401) {
+      sessionStorage.removeItem("pulse-token");
+    }
+    const error = new Error(body.error || response.statusText);
+    error.status = response.status;
+    throw error;
+  }
+  return body;
+}
+
+function showMessage(text) {
+  $("message").textContent = text;
+}
+
+function cell(row, text, className) {
+  const td = row.insertCell();
+  td.textContent = text;
+  if (className) {
+    td.className = className;
+  }
+}
+
+async function loadStacks() {
+  try {
+    const stacks = await api("/api/stacks");
+    const tbody = $("stacks");
+    tbody.replaceChildren();
+    for (const stats of stacks) {
+      const row = tbody.insertRow();
+      row.classList.toggle("selected", stats.stack === state.stack);
+      cell(row, stats.stack);
+      cell(row, stats.running, "running");
+      cell(row, stats.stopped, "stopped");
+      cell(row, stats.other, "other");
+      row.onclick = () => selectStack(stats.stack);
+    }
+  } catch (err) {
+    showMessage(`Error loading stacks: ${err.message}`);
+  }
+}
+
+async function selectStack(stack) {
+  state.stack = stack;
+  $("stack-title").textContent = `Stack: ${stack}`;
+  $("stack-panel").hidden = false;
+  await Promise.all([loadStacks(), loadContainers()]);
+}
+
+async function loadContainers() {
+  if (!state.stack) {
+    return;
+  }
+  try {
+    const containers = await api(`/api/stacks/${encodeURIComponent(state.stack)}/containers`);
+    const tbody = $("containers");
+    tbody.replaceChildren();
+    for (const c of containers) {
+      const row = tbody.insertRow();
+      cell(row, c.name);
+      cell(row, c.service);
+      cell(row, c.image);
+      cell(row, c.state, c.state === "running" ? "running" : c.state === "exited" ? "stopped" : "other");
+      cell(row, c.status);
+      row.onclick = () => followLogs(`/api/containers/${encodeURIComponent(c.id)}/logs`, `Logs: ${c.name}`);
+    }
+  } catch (err) {
+    showMessage(`Error loading containers: ${err.message}`);
+  }
+}
+
+// parseEvent reads one Server-Sent Event; comments such as keep-alives have no data
+function parseEvent(block) {
+  let type = "message";
+  const data = [];
+  for (const line of block.split("\n")) {
+    if (line.startsWith("event: ")) {
+      type = line.slice(7);
+    } else if (line.startsWith("data: ")) {
+      data.push(line.slice(6));
+    }
+  }
+  return data.length > 0 ? { type, data: data.join("\n") } : null;
+}
+
+// followLogs streams log lines sent as Server-Sent Events into the log panel. They are
+// read with fetch since EventSource cannot send the token.
+async function followLogs(url, title) {
+  if (state.logs) {
+    state.logs.abort();
+  }
+  const controller = new AbortController();
+  state.logs = controller;
+
+  const pre = $("logs");
+  pre.textContent = "";
+  $("logs-title").textContent = title;
+  $("logs-panel").hidden = false;
+
+  const append = (text) => {
+    const atBottom = pre.scrollTop + pre.clientHeight >= pre.scrollHeight - 4;
+    pre.append(text + "\n");
+    if (atBottom) {
+      pre.scrollTop = pre.scrollHeight;
+    }
+  };
+
+  try {
+    const response = await fetch(url, { headers: authHeaders(), signal: controller.signal });
+    if (!response.ok) {
+      const body = await response.json().catch(() => ({}));
+      showMessage(`Error: ${body.error || response.statusText}`);
+      return;
+    }
+
+    const reader = response.body.pipeThrough(new TextDecoderStream()).getReader();
+    let buffered = "";
+    for (;;) {
+      const { value, done } = await reader.read();
+      if (done) {
+        break;
+      }
+      buffered += value;
+      let end;
+      while ((end = buffered.indexOf("\n\n")) >= 0) {
+        const event = parseEvent(buffered.slice(0, end));
+        buffered = buffered.slice(end + 2);
+        if (event?.type === "message") {
+          append(event.data);
+        } else if (event?.type === "end") {
+          append(`--- ${event.data} ---`);
+          return;
+        }
+      }
+    }
+    showMessage("Log stream closed");
+  } catch (err) {
+    if (err.name !== "AbortError") {
+      showMessage(`Log stream closed: ${err.message}`);
+    }
+  }
+}
+
+async function runAction(action, confirmText) {
+  if (!state.stack || !window.confirm(confirmText)) {
+    return;
+  }
+  const token = takeToken() || askToken("Token to restart and kill stacks:");
+  if (!token) {
+    return;
+  }
+  try {
+    const result = await api(`/api/stacks/${encodeURIComponent(state.stack)}/${action}`, {
+      method: "POST",
+      headers: { Authorization: `Bearer ${token}`, "X-Pulse-Action": action },
+    });
+    showMessage(result.message);
+  } catch (err) {
+    showMessage(`Error: ${err.message}`);
+  }
+  await Promise.all([loadStacks(), loadContainers()]);
+}
+
+async function init() {
+  takeToken();
+  try {
+    const info = await api("/api/info");
+    state.readOnly = info.readOnly;
+    state.authRequired = info.authRequired;
+    state.refreshInterval = info.refreshInterval;
+    $("host").textContent = info.host;
+    $("mode").hidden = !info.readOnly;
+  } catch (err) {
+    showMessage(`Error: ${err.message}`);
+  }
+
+  if (state.authRequired && !takeToken()) {
+    askToken("Token to view the dashboard:");
+  }
+
+  for (const button of document.querySelectorAll(".operate")) {
+    button.hidden = state.readOnly;
+  }
+  $("stack-logs").onclick = () =>
+    followLogs(`/api/stacks/${encodeURIComponent(state.stack)}/logs`, `Logs: ${state.stack}`);
+  $("restart").onclick = () => runAction("restart", `Restart every service of ${state.stack}?`);
+  $("kill").onclick = () => runAction("kill", `Remove every service of ${state.stack}? This cannot be undone.`);
+
+  await loadStacks();
+  if (state.refreshInterval > 0) {
+    setInterval(() => {
+      loadStacks();
+      loadContainers();
+    }, state.refreshInterval);
+  }
+}
+
+init();